@@ -4,7 +4,7 @@ import graphql from 'graphQLAPI'
 
 const { PACT_BROKER_TOKEN, PACT_BROKER_URL } = process.env;
 
-const { like } = Matchers
+const { like, term } = Matchers
 
 const user = {
   id: like(123).contents,
@@ -20,7 +20,11 @@ describe('GraphQL', () => {
         .uponReceiving('a list of users')
         .withRequest({
           path: '/graphql',
-          method: 'POST'
+          method: 'POST',
+          // reading feedback needs an analyst token, the provider verification signs a real one
+          headers: {
+            Authorization: term({ generate: 'Bearer analyst-token', matcher: '^Bearer .+$' })
+          }
         })
         .withOperation('GetUsers')
           .withQuery(`
@@ -1,14 +1,18 @@
-import { useState } from 'react';
+import { useMemo, useState } from 'react';
 import './App.css';
 import { useQuery,useMutation, gql } from '@apollo/client';
 
 const GET_USER_FEEDBACK = gql`
   {
     GetUserFeedback(filter: {}){
-		id
-		firstName
-		lastName
-		email
+		edges {
+			node {
+				id
+				firstName
+				lastName
+				email
+			}
+		}
 	}
 }
 `;
@@ -30,9 +34,9 @@ function DisplayUsers() {
 
   if (loading) return <p>Loading...</p>;
   if (error) return <p>Error : {error.message}</p>;
-  if (data.GetUserFeedback.length === 0)  return <p>No Users</p>; 
+  if (data.GetUserFeedback.edges.length === 0)  return <p>No Users</p>; 
 
-  return data.GetUserFeedback.map(({ id, firstName, lastName, email }) => (
+  return data.GetUserFeedback.edges.map(({ node: { id, firstName, lastName, email } }) => (
       <div class="App" key={id}>
         <h3>{firstName}</h3>
         <h3>{lastName}</h3>
@@ -51,6 +55,12 @@ function DisplayForm() {
   const [email, setEmail] = useState("");
   const [jobTitle, setJobTitle] = useState("");
   const [feedback, setFeedBack] = useState("");
+  // a double click or a retry sends the same key so the feedback is only saved once,
+  // editing the form starts a new submission
+  const idempotencyKey = useMemo(
+    () => crypto.randomUUID(),
+    [firstName, lastName, email, jobTitle, feedback],
+  );
 
   const [createUser] = useMutation(CREATE_USER, {
     variables: {
@@ -59,7 +69,8 @@ function DisplayForm() {
           lastName: lastName,
           email: email,
           jobTitle: jobTitle,
-          feedback: feedback
+          feedback: feedback,
+          idempotencyKey: idempotencyKey
         }
       }
   });
@@ -0,0 +1,41 @@
+// Command backfill-sentiment scores the feedback saved before sentiment analysis was added.
+// Run it once after the service has applied the feedback_sentiment migration, it is safe to
+// run again or while the service is up since only unscored rows are touched. Like the service
+// it has to be built with the sqlite_fts5 tag, the search triggers fire on every update.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	"github.com/alexflint/go-arg"
+	"github.com/riyadennis/sigist/graphql-service/graph"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type args struct {
+	DBFile    string `arg:"env:DB_FILE" default:"../environment/db/user-feedback.sqlite"`
+	BatchSize int    `arg:"--batch-size,env:BACKFILL_BATCH_SIZE" default:"500" help:"rows scored per transaction"`
+}
+
+func main() {
+	var a args
+	p := arg.MustParse(&a)
+	if a.BatchSize < 1 {
+		p.Fail("--batch-size must be at least 1")
+	}
+
+	db, err := sql.Open("sqlite3", a.DBFile)
+	if err != nil {
+		log.Fatalf("failed to open db: %s", err)
+	}
+	defer db.Close()
+
+	scored, err := graph.BackfillSentiment(context.Background(), db, a.BatchSize)
+	if err != nil {
+		log.Fatalf("failed to backfill sentiment after scoring %d feedback: %s", scored, err)
+	}
+	log.Printf("scored %d feedback", scored)
+}
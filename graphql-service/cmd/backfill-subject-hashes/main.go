@@ -0,0 +1,42 @@
+// Command backfill-subject-hashes hashes the email of the feedback saved before the subject hash was stored.
+// Run it once after the service has applied the feedback_subject_hash migration and before any erasure
+// is requested, erasures only find hashed feedback. It is safe to run again or while the service is up
+// since only unhashed rows are touched. Like the service
+// it has to be built with the sqlite_fts5 tag, the search triggers fire on every update.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	"github.com/alexflint/go-arg"
+	"github.com/riyadennis/sigist/graphql-service/graph"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type args struct {
+	DBFile    string `arg:"env:DB_FILE" default:"../environment/db/user-feedback.sqlite"`
+	BatchSize int    `arg:"--batch-size,env:BACKFILL_BATCH_SIZE" default:"500" help:"rows hashed per transaction"`
+}
+
+func main() {
+	var a args
+	p := arg.MustParse(&a)
+	if a.BatchSize < 1 {
+		p.Fail("--batch-size must be at least 1")
+	}
+
+	db, err := sql.Open("sqlite3", a.DBFile)
+	if err != nil {
+		log.Fatalf("failed to open db: %s", err)
+	}
+	defer db.Close()
+
+	hashed, err := graph.BackfillSubjectHashes(context.Background(), db, a.BatchSize)
+	if err != nil {
+		log.Fatalf("failed to backfill subject hashes after hashing %d feedback: %s", hashed, err)
+	}
+	log.Printf("hashed %d feedback", hashed)
+}
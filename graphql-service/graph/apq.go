@@ -0,0 +1,114 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/uptrace/opentelemetry-go-extra/otelzap"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+var (
+	queryGetPersistedQuery   = `SELECT query FROM persisted_queries WHERE hash = ?`
+	queryTouchPersistedQuery = `UPDATE persisted_queries SET last_used = ? WHERE hash = ?`
+	querySavePersistedQuery  = `INSERT OR IGNORE INTO persisted_queries (hash, query, last_used) VALUES (?, ?, ?)`
+	queryEvictPersistedQuery = `DELETE FROM persisted_queries WHERE hash NOT IN (SELECT hash FROM persisted_queries ORDER BY last_used DESC LIMIT ?)`
+)
+
+// persistedQueryLookups counts cache lookups of automatic persisted queries by result,
+// the hit ratio is hits over all lookups.
+var persistedQueryLookups, _ = meter.Int64Counter(
+	"graphql.apq.lookups",
+	metric.WithDescription("number of persisted query lookups by cache and result"),
+)
+
+// SQLPersistedQueryCache keeps automatic persisted queries in the persisted_queries table
+// so that clients don't have to resend them after a restart. Like the memory cache it holds
+// at most size queries and evicts the least recently used ones.
+type SQLPersistedQueryCache struct {
+	logger *otelzap.Logger
+	db     *sql.DB
+	size   int
+}
+
+// NewSQLPersistedQueryCache creates a persisted query cache of size queries stored in db
+func NewSQLPersistedQueryCache(logger *otelzap.Logger, db *sql.DB, size int) *SQLPersistedQueryCache {
+	return &SQLPersistedQueryCache{
+		logger: logger,
+		db:     db,
+		size:   size,
+	}
+}
+
+// Get looks up the query text of a sha256 hash, failures are treated as a miss
+// so the client falls back to sending the full query.
+func (c *SQLPersistedQueryCache) Get(ctx context.Context, hash string) (interface{}, bool) {
+	var query string
+	err := c.db.QueryRowContext(ctx, queryGetPersistedQuery, hash).Scan(&query)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			c.logger.Error("failed to fetch persisted query", zap.Error(err))
+		}
+		return nil, false
+	}
+
+	if _, err := c.db.ExecContext(ctx, queryTouchPersistedQuery, timeNow().UnixNano(), hash); err != nil {
+		c.logger.Error("failed to touch persisted query", zap.Error(err))
+	}
+
+	return query, true
+}
+
+// Add stores the query text under its sha256 hash and evicts the least recently used queries
+// beyond the size of the cache, the hash has already been verified by the caller.
+func (c *SQLPersistedQueryCache) Add(ctx context.Context, hash string, value interface{}) {
+	query, ok := value.(string)
+	if !ok {
+		return
+	}
+
+	result, err := c.db.ExecContext(ctx, querySavePersistedQuery, hash, query, timeNow().UnixNano())
+	if err != nil {
+		c.logger.Error("failed to save persisted query", zap.Error(err))
+		return
+	}
+	// the cache only grows when the query wasn't there yet
+	if added, err := result.RowsAffected(); err != nil || added == 0 {
+		return
+	}
+
+	if _, err := c.db.ExecContext(ctx, queryEvictPersistedQuery, c.size); err != nil {
+		c.logger.Error("failed to evict persisted queries", zap.Error(err))
+	}
+}
+
+// meteredCache records whether lookups in the wrapped cache were hits or misses
+type meteredCache struct {
+	graphql.Cache
+	name string
+}
+
+// NewMeteredCache wraps a persisted query cache with hit and miss metrics
+func NewMeteredCache(name string, cache graphql.Cache) graphql.Cache {
+	return &meteredCache{Cache: cache, name: name}
+}
+
+// Get looks up the key in the wrapped cache
+func (c *meteredCache) Get(ctx context.Context, key string) (interface{}, bool) {
+	value, ok := c.Cache.Get(ctx, key)
+
+	result := "miss"
+	if ok {
+		result = "hit"
+	}
+	persistedQueryLookups.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("cache", c.name),
+		attribute.String("result", result),
+	))
+
+	return value, ok
+}
@@ -0,0 +1,39 @@
+//go:build sqlite_fts5
+
+package graph
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLPersistedQueryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	now := createdAt
+	timeNow = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	defer func() { timeNow = time.Now }()
+
+	ctx := context.Background()
+	cache := NewSQLPersistedQueryCache(logger, migratedDB(t), 2)
+	cache.Add(ctx, "a", "{ a }")
+	cache.Add(ctx, "b", "{ b }")
+	// a is used after b was added, so b is the least recently used
+	_, found := cache.Get(ctx, "a")
+	assert.True(t, found)
+	cache.Add(ctx, "c", "{ c }")
+
+	for hash, expected := range map[string]bool{"a": true, "b": false, "c": true} {
+		_, found := cache.Get(ctx, hash)
+		assert.Equal(t, expected, found, hash)
+	}
+
+	// adding a known query doesn't evict anything
+	cache.Add(ctx, "c", "{ c }")
+	_, found = cache.Get(ctx, "a")
+	assert.True(t, found)
+}
@@ -0,0 +1,109 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	persistedHash  = "7f56e67dd21ab3f30d1ff8b7bed08893f0a0db86449836189b361dd1e56ddb4b"
+	persistedQuery = "{ __typename }"
+)
+
+func TestSQLPersistedQueryCacheGet(t *testing.T) {
+	scenarios := []struct {
+		name     string
+		mock     func(mock sqlmock.Sqlmock)
+		expected interface{}
+		found    bool
+	}{
+		{
+			name: "hit",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(queryGetPersistedQuery)).WithArgs(persistedHash).
+					WillReturnRows(sqlmock.NewRows([]string{"query"}).AddRow(persistedQuery))
+				mock.ExpectExec(regexp.QuoteMeta(queryTouchPersistedQuery)).WithArgs(sqlmock.AnyArg(), persistedHash).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expected: persistedQuery,
+			found:    true,
+		},
+		{
+			name: "miss",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(queryGetPersistedQuery)).WithArgs(persistedHash).
+					WillReturnError(sql.ErrNoRows)
+			},
+		},
+		{
+			name: "db error is a miss",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(queryGetPersistedQuery)).WithArgs(persistedHash).
+					WillReturnError(errFailedDBOperation)
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			scenario.mock(mock)
+
+			cache := NewMeteredCache("sqlite", NewSQLPersistedQueryCache(logger, db, 10))
+			value, found := cache.Get(context.Background(), persistedHash)
+			assert.Equal(t, scenario.found, found)
+			assert.Equal(t, scenario.expected, value)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestSQLPersistedQueryCacheAdd(t *testing.T) {
+	scenarios := []struct {
+		name string
+		mock func(mock sqlmock.Sqlmock)
+	}{
+		{
+			name: "new query evicts beyond the size",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(querySavePersistedQuery)).WithArgs(persistedHash, persistedQuery, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(regexp.QuoteMeta(queryEvictPersistedQuery)).WithArgs(10).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "known query",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(querySavePersistedQuery)).WithArgs(persistedHash, persistedQuery, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+		},
+		{
+			name: "db error",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(querySavePersistedQuery)).WithArgs(persistedHash, persistedQuery, sqlmock.AnyArg()).
+					WillReturnError(errFailedDBOperation)
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			scenario.mock(mock)
+
+			var cache graphql.Cache = NewSQLPersistedQueryCache(logger, db, 10)
+			cache.Add(context.Background(), persistedHash, persistedQuery)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
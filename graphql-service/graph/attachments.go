@@ -0,0 +1,376 @@
+package graph
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"github.com/riyadennis/sigist/graphql-service/internal/storage"
+	"github.com/uptrace/opentelemetry-go-extra/otelzap"
+	"go.uber.org/zap"
+)
+
+const (
+	attachmentType = "Attachment"
+	// maxAttachmentNameLength is how many characters of an uploaded file name are kept
+	maxAttachmentNameLength = 255
+)
+
+var (
+	querySaveAttachment    = `INSERT INTO attachments (id, feedback_id, filename, content_type, size, storage_key, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	queryGetAttachmentByID = `SELECT id, feedback_id, filename, content_type, size, storage_key, created_at FROM attachments WHERE id = ?`
+	queryGetAttachmentsIn  = `SELECT id, feedback_id, filename, content_type, size, storage_key, created_at FROM attachments WHERE feedback_id IN `
+	queryAttachmentKeys    = `SELECT storage_key FROM attachments WHERE feedback_id = ?`
+	orderAttachmentsOldest = ` ORDER BY created_at, id`
+)
+
+var (
+	// ErrTooManyAttachments means that more files were uploaded with one feedback than allowed
+	ErrTooManyAttachments = errors.New("too many attachments")
+
+	// ErrAttachmentTooLarge means that an uploaded file is bigger than allowed
+	ErrAttachmentTooLarge = errors.New("attachment is too large")
+
+	// ErrAttachmentType means that the content of an uploaded file is not one of the allowed types
+	ErrAttachmentType = errors.New("attachment type is not allowed")
+
+	// ErrAttachmentsDisabled means that the service has no storage, attachments saved while it had one can't be downloaded
+	ErrAttachmentsDisabled = errors.New("attachments are not enabled on this service")
+
+	// DefaultAttachmentTypes are accepted when no other types are configured
+	DefaultAttachmentTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp", "application/pdf"}
+)
+
+// Attachments stores the files uploaded with feedback and signs the urls they are downloaded from,
+// a nil Attachments accepts no files.
+type Attachments struct {
+	Storage storage.Storage
+	// MaxSize is the size limit of one file in bytes
+	MaxSize int64
+	// MaxFiles is how many files can be uploaded with one feedback
+	MaxFiles int
+	// Types are the allowed content types, they are detected from the content of the file
+	Types []string
+	// SigningKey signs download urls, urls signed with another key are rejected
+	SigningKey []byte
+	// URLTTL is how long a download url works for
+	URLTTL time.Duration
+}
+
+// store checks every upload before it writes any of them, files stored before a failure are removed again.
+// The returned attachments still have to be saved to the db.
+func (a *Attachments) store(ctx context.Context, feedbackID string, uploads []*graphql.Upload, createdAt time.Time) ([]*model.Attachment, error) {
+	if len(uploads) == 0 {
+		return nil, nil
+	}
+	if a == nil || len(uploads) > a.MaxFiles {
+		return nil, fmt.Errorf("%w: at most %d files can be uploaded", ErrTooManyAttachments, a.maxFiles())
+	}
+
+	attachments := make([]*model.Attachment, 0, len(uploads))
+	for _, upload := range uploads {
+		attachment, err := a.check(upload)
+		if err != nil {
+			return nil, err
+		}
+		attachment.FeedbackID = feedbackID
+		attachment.CreateAt = createdAt
+		attachments = append(attachments, attachment)
+	}
+
+	for i, attachment := range attachments {
+		if err := a.Storage.Put(ctx, attachment.StorageKey, uploads[i].File); err != nil {
+			return nil, errors.Join(err, a.remove(ctx, storageKeys(attachments[:i])))
+		}
+	}
+
+	return attachments, nil
+}
+
+func (a *Attachments) maxFiles() int {
+	if a == nil {
+		return 0
+	}
+
+	return a.MaxFiles
+}
+
+// check enforces the size and type limits, the type is sniffed from the first bytes of the file
+// so a renamed executable is not served as an image.
+func (a *Attachments) check(upload *graphql.Upload) (*model.Attachment, error) {
+	name := attachmentName(upload.Filename)
+	if upload.Size > a.MaxSize {
+		return nil, fmt.Errorf("%w: %s is bigger than %d bytes", ErrAttachmentTooLarge, name, a.MaxSize)
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(upload.File, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	if _, err := upload.File.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if err != nil || !a.allowed(contentType) {
+		return nil, fmt.Errorf("%w: %s is not one of %s", ErrAttachmentType, name, strings.Join(a.Types, ", "))
+	}
+
+	id := uuid.New().String()
+	return &model.Attachment{
+		ID:          id,
+		Filename:    name,
+		ContentType: contentType,
+		Size:        int(upload.Size),
+		StorageKey:  id,
+	}, nil
+}
+
+func (a *Attachments) allowed(contentType string) bool {
+	for _, allowed := range a.Types {
+		if strings.EqualFold(allowed, contentType) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// remove deletes the files stored under the keys, it carries on after a failure
+// and returns every error it ran into.
+func (a *Attachments) remove(ctx context.Context, keys []string) error {
+	if a == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, key := range keys {
+		if err := a.Storage.Delete(ctx, key); err != nil {
+			errs = append(errs, fmt.Errorf("deleting %s: %w", key, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func storageKeys(attachments []*model.Attachment) []string {
+	keys := make([]string, 0, len(attachments))
+	for _, attachment := range attachments {
+		keys = append(keys, attachment.StorageKey)
+	}
+
+	return keys
+}
+
+// URL returns the download path of the attachment, it works until the url ttl has passed
+func (a *Attachments) URL(id string, now time.Time) (string, error) {
+	if a == nil {
+		return "", ErrAttachmentsDisabled
+	}
+
+	expires := strconv.FormatInt(now.Add(a.URLTTL).Unix(), 10)
+	query := url.Values{
+		"expires":   {expires},
+		"signature": {a.sign(id, expires)},
+	}
+
+	return "/attachments/" + url.PathEscape(id) + "?" + query.Encode(), nil
+}
+
+func (a *Attachments) sign(id, expires string) string {
+	mac := hmac.New(sha256.New, a.SigningKey)
+	mac.Write([]byte(id + "\n" + expires))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks that the url was signed for this attachment and has not expired yet
+func (a *Attachments) verify(id, expires, signature string, now time.Time) bool {
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || now.Unix() > unix {
+		return false
+	}
+
+	return hmac.Equal([]byte(signature), []byte(a.sign(id, expires)))
+}
+
+// DownloadHandler serves the file of an attachment to anyone holding a signed url for it,
+// files are always sent as downloads so that browsers never render them inline.
+func DownloadHandler(logger *otelzap.Logger, db *sql.DB, attachments *Attachments) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		query := r.URL.Query()
+		if attachments == nil || !attachments.verify(id, query.Get("expires"), query.Get("signature"), time.Now()) {
+			http.Error(w, "invalid or expired url", http.StatusForbidden)
+			return
+		}
+
+		attachment, err := getAttachmentByID(db, id)
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			logger.Error("failed to fetch attachment", zap.String("id", id), zap.Error(err))
+			http.Error(w, errInternal.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		file, err := attachments.Storage.Open(r.Context(), attachment.StorageKey)
+		if errors.Is(err, storage.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			logger.Error("failed to open attachment", zap.String("id", id), zap.Error(err))
+			http.Error(w, errInternal.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer file.Close()
+
+		w.Header().Set("Content-Type", attachment.ContentType)
+		w.Header().Set("Content-Length", strconv.Itoa(attachment.Size))
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Cache-Control", "private, no-store")
+		if _, err := io.Copy(w, file); err != nil {
+			logger.Warn("failed to send attachment", zap.String("id", id), zap.Error(err))
+		}
+	}
+}
+
+// attachmentName drops the directories some browsers send with the file name
+func attachmentName(filename string) string {
+	name := strings.TrimSpace(filename[strings.LastIndexAny(filename, `/\`)+1:])
+	if !utf8.ValidString(name) {
+		name = strings.ToValidUTF8(name, "")
+	}
+	if utf8.RuneCountInString(name) > maxAttachmentNameLength {
+		name = string([]rune(name)[:maxAttachmentNameLength])
+	}
+	if name == "" || name == "." || name == ".." {
+		return "attachment"
+	}
+
+	return name
+}
+
+// saveAttachments stores the metadata of files that are already in storage
+func saveAttachments(tx *sql.Tx, attachments []*model.Attachment) error {
+	for _, attachment := range attachments {
+		_, err := tx.Exec(querySaveAttachment,
+			attachment.ID,
+			attachment.FeedbackID,
+			attachment.Filename,
+			attachment.ContentType,
+			attachment.Size,
+			attachment.StorageKey,
+			formatDateTime(attachment.CreateAt),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getAttachmentKeys returns where the files of the feedback are stored
+func getAttachmentKeys(q querier, feedbackID string) ([]string, error) {
+	rows, err := q.Query(queryAttachmentKeys, feedbackID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// getAttachmentByID returns sql.ErrNoRows when there is no attachment with the given id
+func getAttachmentByID(db *sql.DB, id string) (*model.Attachment, error) {
+	rows, err := db.Query(queryGetAttachmentByID, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments, err := scanAttachments(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(attachments) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return attachments[0], nil
+}
+
+// getAttachmentsByFeedbackIDs fetches the attachments of every feedback in a single query, oldest first,
+// feedback without attachments is left out of the result.
+func getAttachmentsByFeedbackIDs(db *sql.DB, ids []string) (map[string][]*model.Attachment, error) {
+	rows, err := db.Query(queryGetAttachmentsIn+placeholders(len(ids))+orderAttachmentsOldest, stringArgs(ids)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments, err := scanAttachments(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	byFeedback := make(map[string][]*model.Attachment, len(ids))
+	for _, attachment := range attachments {
+		byFeedback[attachment.FeedbackID] = append(byFeedback[attachment.FeedbackID], attachment)
+	}
+
+	return byFeedback, nil
+}
+
+func scanAttachments(rows *sql.Rows) ([]*model.Attachment, error) {
+	var attachments []*model.Attachment
+	for rows.Next() {
+		attachment := &model.Attachment{}
+		err := rows.Scan(
+			&attachment.ID,
+			&attachment.FeedbackID,
+			&attachment.Filename,
+			&attachment.ContentType,
+			&attachment.Size,
+			&attachment.StorageKey,
+			&attachment.CreateAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, rows.Err()
+}
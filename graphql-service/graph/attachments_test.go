@@ -0,0 +1,264 @@
+package graph
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi"
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"github.com/riyadennis/sigist/graphql-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	pdfHeader = []byte("%PDF-1.7\n")
+)
+
+func upload(name string, content []byte) *graphql.Upload {
+	return &graphql.Upload{
+		File:     bytes.NewReader(content),
+		Filename: name,
+		Size:     int64(len(content)),
+	}
+}
+
+func testAttachments(t *testing.T) *Attachments {
+	return testAttachmentsIn(t, t.TempDir())
+}
+
+func testAttachmentsIn(t *testing.T, dir string) *Attachments {
+	local, err := storage.NewLocal(dir)
+	assert.NoError(t, err)
+
+	return &Attachments{
+		Storage:    local,
+		MaxSize:    64,
+		MaxFiles:   2,
+		Types:      DefaultAttachmentTypes,
+		SigningKey: []byte("secret"),
+		URLTTL:     time.Minute,
+	}
+}
+
+func TestAttachmentsStore(t *testing.T) {
+	scenarios := []struct {
+		name          string
+		uploads       []*graphql.Upload
+		expectedTypes []string
+		expectedErr   error
+	}{
+		{
+			name:    "nothing uploaded",
+			uploads: nil,
+		},
+		{
+			name:          "image and pdf",
+			uploads:       []*graphql.Upload{upload("C:\\Users\\john\\screen.png", pngHeader), upload("invoice.pdf", pdfHeader)},
+			expectedTypes: []string{"image/png", "application/pdf"},
+		},
+		{
+			name:        "too many files",
+			uploads:     []*graphql.Upload{upload("a.png", pngHeader), upload("b.png", pngHeader), upload("c.png", pngHeader)},
+			expectedErr: ErrTooManyAttachments,
+		},
+		{
+			name:        "too large",
+			uploads:     []*graphql.Upload{upload("big.png", append(pngHeader, make([]byte, 64)...))},
+			expectedErr: ErrAttachmentTooLarge,
+		},
+		{
+			name:        "type is sniffed rather than taken from the name",
+			uploads:     []*graphql.Upload{upload("script.png", []byte("<html><script>alert(1)</script>"))},
+			expectedErr: ErrAttachmentType,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			attachments := testAttachments(t)
+			stored, err := attachments.store(context.Background(), id, scenario.uploads, createdAt)
+			assert.True(t, errors.Is(err, scenario.expectedErr), err)
+			assert.Len(t, stored, len(scenario.expectedTypes))
+
+			for i, attachment := range stored {
+				assert.Equal(t, id, attachment.FeedbackID)
+				assert.Equal(t, scenario.expectedTypes[i], attachment.ContentType)
+				assert.Equal(t, int(scenario.uploads[i].Size), attachment.Size)
+
+				// the whole file is stored, not only what was read to detect the type
+				r, err := attachments.Storage.Open(context.Background(), attachment.StorageKey)
+				assert.NoError(t, err)
+				data, err := io.ReadAll(r)
+				assert.NoError(t, err)
+				assert.NoError(t, r.Close())
+				assert.Equal(t, int(scenario.uploads[i].Size), len(data))
+			}
+			if len(stored) > 0 {
+				assert.Equal(t, "screen.png", stored[0].Filename)
+			}
+		})
+	}
+}
+
+func TestNilAttachmentsRejectUploads(t *testing.T) {
+	var attachments *Attachments
+	_, err := attachments.store(context.Background(), id, []*graphql.Upload{upload("a.png", pngHeader)}, createdAt)
+	assert.True(t, errors.Is(err, ErrTooManyAttachments))
+	assert.Equal(t, ErrCodeValidationFailed, errorCode(err))
+	assert.NoError(t, attachments.remove(context.Background(), []string{"abc"}))
+
+	// attachments saved while the service had storage can't be downloaded
+	resolver := &attachmentResolver{&Resolver{logger: logger}}
+	_, err = resolver.URL(context.Background(), &model.Attachment{ID: id})
+	assert.ErrorIs(t, err, ErrAttachmentsDisabled)
+	assert.Equal(t, ErrCodeUnavailable, errorCode(err))
+}
+
+func TestAttachmentsURL(t *testing.T) {
+	attachments := testAttachments(t)
+	now := time.Now()
+
+	download, err := attachments.URL(id, now)
+	assert.NoError(t, err)
+	u, err := http.NewRequest(http.MethodGet, download, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "/attachments/"+id, u.URL.Path)
+	expires, signature := u.URL.Query().Get("expires"), u.URL.Query().Get("signature")
+
+	assert.True(t, attachments.verify(id, expires, signature, now))
+	assert.True(t, attachments.verify(id, expires, signature, now.Add(time.Minute)))
+	assert.False(t, attachments.verify(id, expires, signature, now.Add(time.Minute+time.Second)))
+	assert.False(t, attachments.verify("456", expires, signature, now))
+	assert.False(t, attachments.verify(id, expires+"0", signature, now))
+	assert.False(t, attachments.verify(id, "soon", signature, now))
+
+	other := testAttachments(t)
+	other.SigningKey = []byte("other")
+	assert.False(t, other.verify(id, expires, signature, now))
+}
+
+func TestDownloadHandler(t *testing.T) {
+	attachments := testAttachments(t)
+	assert.NoError(t, attachments.Storage.Put(context.Background(), "key-1", bytes.NewReader(pngHeader)))
+
+	signedURL := func(now time.Time) string {
+		u, err := attachments.URL(id, now)
+		assert.NoError(t, err)
+		return u
+	}
+
+	scenarios := []struct {
+		name           string
+		url            string
+		mockDB         func(mock sqlmock.Sqlmock)
+		expectedStatus int
+	}{
+		{
+			name: "signed url",
+			url:  signedURL(time.Now()),
+			mockDB: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(queryGetAttachmentByID)).
+					WithArgs(id).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "feedback_id", "filename", "content_type", "size", "storage_key", "created_at"}).
+						AddRow(id, "fb-1", `screen "1".png`, "image/png", len(pngHeader), "key-1", createdAt))
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "expired url",
+			url:            signedURL(time.Now().Add(-2 * time.Minute)),
+			mockDB:         func(mock sqlmock.Sqlmock) {},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "unsigned url",
+			url:            "/attachments/" + id,
+			mockDB:         func(mock sqlmock.Sqlmock) {},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name: "deleted attachment",
+			url:  signedURL(time.Now()),
+			mockDB: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(queryGetAttachmentByID)).
+					WithArgs(id).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			scenario.mockDB(mock)
+
+			router := chi.NewRouter()
+			router.Get("/attachments/{id}", DownloadHandler(logger, db, attachments))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, scenario.url, nil))
+
+			assert.Equal(t, scenario.expectedStatus, rec.Code)
+			if rec.Code == http.StatusOK {
+				assert.Equal(t, pngHeader, rec.Body.Bytes())
+				assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+				assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+				assert.Equal(t, `attachment; filename="screen \"1\".png"`, rec.Header().Get("Content-Disposition"))
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMutationResolverSaveUserFeedbackWithAttachments(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	mock.ExpectBegin()
+	expectCandidates(mock, sqlmock.NewRows(candidateColumns))
+	mock.ExpectPrepare(regexp.QuoteMeta(querySaveUser)).WillBeClosed()
+	mock.ExpectExec(regexp.QuoteMeta(querySaveUser)).WillReturnResult(sqlmock.NewResult(1, 1))
+	expectSaveSignature(mock, sqlmock.AnyArg())
+	mock.ExpectExec(regexp.QuoteMeta(querySaveAttachment)).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "screen.png", "image/png", len(pngHeader), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnError(errFailedDBOperation)
+	mock.ExpectRollback()
+
+	dir := t.TempDir()
+	attachments := testAttachmentsIn(t, dir)
+	resolver := &mutationResolver{Resolver: &Resolver{logger: logger, db: db, attachments: attachments}}
+	_, err = resolver.SaveUserFeedback(context.Background(), model.UserFeedbackInput{
+		FirstName:   firstName,
+		LastName:    lastName,
+		Email:       email,
+		Feedback:    feedback,
+		Attachments: []*graphql.Upload{upload("screen.png", pngHeader)},
+	})
+	assert.Equal(t, errFailedDBOperation, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// the file of a feedback that couldn't be saved is removed again
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestAttachmentName(t *testing.T) {
+	assert.Equal(t, "screen.png", attachmentName("/home/john/screen.png"))
+	assert.Equal(t, "screen.png", attachmentName(`C:\fakepath\screen.png`))
+	assert.Equal(t, "attachment", attachmentName("dir/"))
+	assert.Equal(t, "attachment", attachmentName(".."))
+	assert.Equal(t, maxAttachmentNameLength, len([]rune(attachmentName(strings.Repeat("ä", 300)))))
+}
@@ -0,0 +1,119 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"github.com/riyadennis/sigist/graphql-service/internal/auth"
+)
+
+var (
+	// ErrUnauthenticated means that the field needs a role and the request has no bearer token
+	ErrUnauthenticated = errors.New("a bearer token is needed to use this field")
+
+	// ErrForbidden means that the bearer token doesn't grant the role the field needs
+	ErrForbidden = errors.New("the bearer token doesn't grant the role needed to use this field")
+
+	// ErrMalformedAuthorization means that the Authorization header is not a bearer token
+	ErrMalformedAuthorization = errors.New("authorization must be a bearer token")
+)
+
+type claimsKey struct{}
+
+// AuthMiddleware verifies the bearer token of the request and makes its claims available to the resolvers,
+// requests without a token go through anonymously and requests with an invalid one are rejected.
+// The verifier is nil when no keys are configured, then every token is rejected.
+func AuthMiddleware(verifier *auth.Verifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization := r.Header.Get("Authorization")
+			if authorization == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			claims, err := authenticate(verifier, authorization)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithClaims(r.Context(), claims)))
+		})
+	}
+}
+
+// WebsocketInit verifies the token sent in the authorization of the connection_init payload,
+// browsers can't set headers on websockets. Connections without one stay anonymous.
+func WebsocketInit(verifier *auth.Verifier) transport.WebsocketInitFunc {
+	return func(ctx context.Context, payload transport.InitPayload) (context.Context, error) {
+		authorization := payload.Authorization()
+		if authorization == "" {
+			return ctx, nil
+		}
+
+		claims, err := authenticate(verifier, authorization)
+		if err != nil {
+			return ctx, err
+		}
+		// the tenant was resolved from the upgrade request before the token was known
+		if err := grantedTenant(claims, tenantFrom(ctx)); err != nil {
+			return ctx, err
+		}
+
+		return WithClaims(ctx, claims), nil
+	}
+}
+
+// authenticate verifies the token of a "Bearer <token>" authorization
+func authenticate(verifier *auth.Verifier, authorization string) (*auth.Claims, error) {
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, ErrMalformedAuthorization
+	}
+	if verifier == nil {
+		return nil, auth.ErrNoKeys
+	}
+
+	return verifier.Verify(token)
+}
+
+// WithClaims returns a context for work done on behalf of the holder of a verified token
+func WithClaims(ctx context.Context, claims *auth.Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// claimsFrom returns the claims of the bearer token, nil for anonymous requests
+func claimsFrom(ctx context.Context) *auth.Claims {
+	claims, _ := ctx.Value(claimsKey{}).(*auth.Claims)
+	return claims
+}
+
+// HasRole implements the @hasRole directive, the field fails without running its resolver
+// unless the bearer token grants the role.
+func HasRole(ctx context.Context, _ interface{}, next graphql.Resolver, role model.Role) (interface{}, error) {
+	if err := requireRole(ctx, role); err != nil {
+		return nil, err
+	}
+
+	return next(ctx)
+}
+
+// requireRole fails unless the bearer token grants the role, admins have every role.
+// Resolvers that can't be guarded with @hasRole call it themselves.
+func requireRole(ctx context.Context, role model.Role) error {
+	claims := claimsFrom(ctx)
+	if claims == nil {
+		return ErrUnauthenticated
+	}
+	if !claims.HasRole(role.String()) && !claims.HasRole(model.RoleAdmin.String()) {
+		return ErrForbidden
+	}
+
+	return nil
+}
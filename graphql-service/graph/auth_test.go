@@ -0,0 +1,233 @@
+package graph
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/riyadennis/sigist/graphql-service/graph/generated"
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"github.com/riyadennis/sigist/graphql-service/internal/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+// asAdmin makes a test client request on behalf of an admin
+func asAdmin(r *client.Request) {
+	r.HTTP = r.HTTP.WithContext(WithClaims(r.HTTP.Context(), &auth.Claims{Roles: []string{"admin"}}))
+}
+
+// testVerifier returns a verifier that trusts the returned key
+func testVerifier(t *testing.T) (*auth.Verifier, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "key.pem")
+	assert.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+
+	verifier, err := auth.NewVerifier(auth.Config{KeyFile: path})
+	assert.NoError(t, err)
+
+	return verifier, key
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, claims *auth.Claims) string {
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(key)
+	assert.NoError(t, err)
+
+	return token
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	verifier, key := testVerifier(t)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	scenarios := []struct {
+		name           string
+		verifier       *auth.Verifier
+		authorization  string
+		expectedStatus int
+		expectedRoles  []string
+	}{
+		{
+			name:           "anonymous",
+			verifier:       verifier,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "valid token",
+			verifier:       verifier,
+			authorization:  "Bearer " + signToken(t, key, &auth.Claims{Roles: []string{"admin"}}),
+			expectedStatus: http.StatusOK,
+			expectedRoles:  []string{"admin"},
+		},
+		{
+			name:           "token signed with another key",
+			verifier:       verifier,
+			authorization:  "Bearer " + signToken(t, otherKey, &auth.Claims{Roles: []string{"admin"}}),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "not a bearer token",
+			verifier:       verifier,
+			authorization:  "Basic YWRtaW46YWRtaW4=",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "no keys configured",
+			authorization:  "Bearer " + signToken(t, key, &auth.Claims{Roles: []string{"admin"}}),
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			var claims *auth.Claims
+			handler := AuthMiddleware(scenario.verifier)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				claims = claimsFrom(r.Context())
+			}))
+			req := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+			if scenario.authorization != "" {
+				req.Header.Set("Authorization", scenario.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, scenario.expectedStatus, rec.Code)
+			if scenario.expectedRoles == nil {
+				assert.Nil(t, claims)
+				return
+			}
+			assert.Equal(t, scenario.expectedRoles, claims.Roles)
+		})
+	}
+}
+
+func TestWebsocketInit(t *testing.T) {
+	verifier, key := testVerifier(t)
+	init := WebsocketInit(verifier)
+	ctx := WithTenant(context.Background(), "team-a")
+
+	anonymous, err := init(ctx, transport.InitPayload{})
+	assert.NoError(t, err)
+	assert.Nil(t, claimsFrom(anonymous))
+
+	authorized, err := init(ctx, transport.InitPayload{
+		"Authorization": "Bearer " + signToken(t, key, &auth.Claims{Roles: []string{"admin"}, Tenant: "team-a"}),
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, requireRole(authorized, model.RoleAdmin))
+
+	_, err = init(ctx, transport.InitPayload{
+		"Authorization": "Bearer " + signToken(t, key, &auth.Claims{Roles: []string{"admin"}, Tenant: "team-b"}),
+	})
+	assert.Equal(t, ErrTenantNotGranted, err)
+
+	// tokens without a tenant are refused like on http requests, unless they are super admin tokens
+	_, err = init(ctx, transport.InitPayload{
+		"Authorization": "Bearer " + signToken(t, key, &auth.Claims{Roles: []string{"admin"}}),
+	})
+	assert.Equal(t, ErrTokenWithoutTenant, err)
+
+	superAdmin, err := init(ctx, transport.InitPayload{
+		"Authorization": "Bearer " + signToken(t, key, &auth.Claims{Roles: []string{"admin", auth.SuperAdminRole}}),
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, requireRole(superAdmin, model.RoleAdmin))
+}
+
+func TestWebsocketSubscriptionRejectsTokenWithoutTenant(t *testing.T) {
+	verifier, key := testVerifier(t)
+	broadcaster := NewBroadcaster(logger, 1)
+	defer broadcaster.Close()
+	srv := handler.New(generated.NewExecutableSchema(generated.Config{
+		Resolvers:  &Resolver{logger: logger, broadcaster: broadcaster},
+		Directives: generated.DirectiveRoot{Constraint: Constraint, HasRole: HasRole},
+	}))
+	srv.AddTransport(transport.Websocket{InitFunc: WebsocketInit(verifier)})
+
+	query := `subscription { feedbackSubmitted { id } }`
+	var resp map[string]interface{}
+	sub := client.New(srv).WebsocketWithPayload(query, map[string]interface{}{
+		"Authorization": "Bearer " + signToken(t, key, &auth.Claims{Roles: []string{"admin"}}),
+	})
+	defer sub.Close()
+	// the connection is refused with a connection_error before the subscription starts
+	assert.ErrorContains(t, sub.Next(&resp), "connection_error")
+	assert.Empty(t, broadcaster.subscribers)
+}
+
+func TestHasRole(t *testing.T) {
+	scenarios := []struct {
+		name         string
+		claims       *auth.Claims
+		expectedErr  error
+		expectedCode string
+	}{
+		{
+			name:         "anonymous",
+			expectedErr:  ErrUnauthenticated,
+			expectedCode: ErrCodeUnauthenticated,
+		},
+		{
+			name:         "without the role",
+			claims:       &auth.Claims{Roles: []string{"analyst"}},
+			expectedErr:  ErrForbidden,
+			expectedCode: ErrCodeForbidden,
+		},
+		{
+			name:   "with the role",
+			claims: &auth.Claims{Roles: []string{"analyst", "ADMIN"}},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			ctx := context.Background()
+			if scenario.claims != nil {
+				ctx = WithClaims(ctx, scenario.claims)
+			}
+
+			var resolved bool
+			_, err := HasRole(ctx, nil, func(context.Context) (interface{}, error) {
+				resolved = true
+				return nil, nil
+			}, model.RoleAdmin)
+			assert.Equal(t, scenario.expectedErr, err)
+			assert.Equal(t, scenario.expectedErr == nil, resolved)
+			if err != nil {
+				assert.Equal(t, scenario.expectedCode, errorCode(err))
+			}
+		})
+	}
+}
+
+func TestHasRoleGuardsReads(t *testing.T) {
+	srv := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{
+		Resolvers:  &Resolver{logger: logger},
+		Directives: generated.DirectiveRoot{Constraint: Constraint, HasRole: HasRole},
+	}))
+	srv.SetErrorPresenter(ErrorPresenter(logger))
+
+	var resp map[string]interface{}
+	err := client.New(srv).Post(`{ GetUserFeedback(filter: {}) { totalCount } }`, &resp)
+	assert.ErrorContains(t, err, `"code":"`+ErrCodeUnauthenticated+`"`)
+
+	// the federation sdl stays public
+	err = client.New(srv).Post(`{ _service { sdl } }`, &resp)
+	assert.NoError(t, err)
+}
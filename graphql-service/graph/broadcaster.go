@@ -0,0 +1,112 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"github.com/uptrace/opentelemetry-go-extra/otelzap"
+	"go.uber.org/zap"
+)
+
+// ErrBroadcasterClosed means that the service is shutting down and no longer accepts subscriptions
+var ErrBroadcasterClosed = errors.New("service is shutting down")
+
+// Broadcaster fans saved feedback out to subscriptions in this process.
+// Every subscriber has a bounded buffer, a subscriber that lets it fill up is
+// disconnected instead of slowing down the publisher or the other subscribers.
+type Broadcaster struct {
+	logger     *otelzap.Logger
+	bufferSize int
+
+	mu          sync.Mutex
+	closed      bool
+	subscribers map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	tenant string
+	filter *model.FilterInput
+	ch     chan *model.UserFeedback
+}
+
+// NewBroadcaster creates a broadcaster that buffers up to bufferSize feedback per subscriber
+func NewBroadcaster(logger *otelzap.Logger, bufferSize int) *Broadcaster {
+	return &Broadcaster{
+		logger:      logger,
+		bufferSize:  bufferSize,
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+// Subscribe returns a channel of the tenant's feedback matching the filter, it is closed when
+// ctx is done, when the subscriber falls behind or when the broadcaster is closed.
+func (b *Broadcaster) Subscribe(ctx context.Context, tenant string, filter *model.FilterInput) (<-chan *model.UserFeedback, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrBroadcasterClosed
+	}
+
+	sub := &subscriber{
+		tenant: tenant,
+		filter: filter,
+		ch:     make(chan *model.UserFeedback, b.bufferSize),
+	}
+	b.subscribers[sub] = struct{}{}
+
+	go func() {
+		<-ctx.Done()
+		b.unsubscribe(sub)
+	}()
+
+	return sub.ch, nil
+}
+
+// Publish hands the feedback of the tenant to every matching subscriber without blocking
+func (b *Broadcaster) Publish(tenant string, feedback *model.UserFeedback) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := timeNow()
+	for sub := range b.subscribers {
+		if sub.tenant != tenant || !matchFilter(sub.filter, feedback, now) {
+			continue
+		}
+
+		select {
+		case sub.ch <- feedback:
+		default:
+			b.logger.Warn("disconnecting slow subscriber", zap.Int("buffer", b.bufferSize))
+			delete(b.subscribers, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// Close ends every subscription and rejects new ones
+func (b *Broadcaster) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subscribers {
+		delete(b.subscribers, sub)
+		close(sub.ch)
+	}
+}
+
+func (b *Broadcaster) unsubscribe(sub *subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.ch)
+	}
+}
@@ -0,0 +1,68 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBroadcasterPublish(t *testing.T) {
+	dev, qa := "Developer", "QA"
+	b := NewBroadcaster(logger, 1)
+
+	devs, err := b.Subscribe(context.Background(), DefaultTenant, &model.FilterInput{
+		JobTitle: &model.StringFilter{Eq: &dev},
+	})
+	assert.NoError(t, err)
+	all, err := b.Subscribe(context.Background(), DefaultTenant, nil)
+	assert.NoError(t, err)
+
+	b.Publish(DefaultTenant, &model.UserFeedback{ID: "1", JobTitle: &qa})
+	assert.Equal(t, "1", (<-all).ID)
+	assert.Empty(t, devs)
+
+	b.Publish(DefaultTenant, &model.UserFeedback{ID: "2", JobTitle: &dev})
+	assert.Equal(t, "2", (<-devs).ID)
+
+	// all still holds feedback 2 so feedback 3 overflows its buffer
+	b.Publish(DefaultTenant, &model.UserFeedback{ID: "3", JobTitle: &dev})
+	assert.Equal(t, "2", (<-all).ID)
+	_, open := <-all
+	assert.False(t, open)
+	assert.Equal(t, "3", (<-devs).ID)
+
+	b.Close()
+	_, open = <-devs
+	assert.False(t, open)
+
+	_, err = b.Subscribe(context.Background(), DefaultTenant, nil)
+	assert.Equal(t, ErrBroadcasterClosed, err)
+}
+
+func TestBroadcasterUnsubscribe(t *testing.T) {
+	b := NewBroadcaster(logger, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ch, err := b.Subscribe(ctx, DefaultTenant, nil)
+	assert.NoError(t, err)
+
+	cancel()
+	_, open := <-ch
+	assert.False(t, open)
+	assert.Empty(t, b.subscribers)
+}
+
+func TestBroadcasterTenants(t *testing.T) {
+	b := NewBroadcaster(logger, 1)
+
+	teamA, err := b.Subscribe(context.Background(), "team-a", nil)
+	assert.NoError(t, err)
+	teamB, err := b.Subscribe(context.Background(), "team-b", nil)
+	assert.NoError(t, err)
+
+	b.Publish("team-b", &model.UserFeedback{ID: "1"})
+	assert.Equal(t, "1", (<-teamB).ID)
+	assert.Empty(t, teamA)
+}
@@ -0,0 +1,258 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"github.com/riyadennis/sigist/graphql-service/graph/similarity"
+)
+
+const (
+	// clusterThreshold is how alike a new feedback has to be to an existing one to join its cluster
+	clusterThreshold = 0.5
+	// defaultSimilarFeedback is how many similar feedback are returned when first is not given
+	defaultSimilarFeedback = 10
+	// defaultClusterItems is how many feedback of a cluster are returned when first is not given
+	defaultClusterItems = 5
+)
+
+var (
+	queryCandidates = `SELECT s.feedback_id, s.signature, COALESCE(u.cluster_id, u.id) FROM feedback_signatures s JOIN user_feedback u ON u.id = s.feedback_id
+		WHERE u.tenant = ? AND s.feedback_id != ? AND s.feedback_id IN (SELECT feedback_id FROM feedback_bands WHERE (band, hash) IN (VALUES `
+	querySaveSignature    = `INSERT OR REPLACE INTO feedback_signatures (feedback_id, signature) VALUES (?, ?)`
+	queryDeleteBands      = `DELETE FROM feedback_bands WHERE feedback_id = ?`
+	querySaveBands        = `INSERT OR IGNORE INTO feedback_bands (band, hash, feedback_id) VALUES `
+	queryClusters         = `SELECT cluster_id, COUNT(*) FROM user_feedback WHERE tenant = ? AND cluster_id IS NOT NULL GROUP BY cluster_id HAVING COUNT(*) >= ? ORDER BY COUNT(*) DESC, MIN(created_at), cluster_id LIMIT ?`
+	queryClusterItems     = queryGetUsers + ` WHERE tenant = ? AND cluster_id = ? ORDER BY id != cluster_id, created_at, id LIMIT ?`
+	queryUnclusteredUsers = `SELECT id, tenant, feedback FROM user_feedback WHERE cluster_id IS NULL ORDER BY created_at, id LIMIT ?`
+	querySetCluster       = `UPDATE user_feedback SET cluster_id = ? WHERE id = ? AND cluster_id IS NULL`
+)
+
+var (
+	// ErrInvalidThreshold means that a similarity threshold is not above 0 and at most 1
+	ErrInvalidThreshold = errors.New("threshold must be above 0 and at most 1")
+
+	// ErrInvalidClusterSize means that the minimum cluster size is below 1
+	ErrInvalidClusterSize = errors.New("minSize must be at least 1")
+)
+
+// similarMatch is a stored feedback that is worded like the one being compared
+type similarMatch struct {
+	feedbackID string
+	clusterID  string
+	similarity float64
+}
+
+// signFeedback computes the signature of a feedback text
+func signFeedback(text *string) similarity.Signature {
+	if text == nil {
+		return similarity.Sign("")
+	}
+
+	return similarity.Sign(*text)
+}
+
+// findSimilar returns the tenant's other feedback at least threshold alike, most similar first.
+// Only feedback sharing a band with the signature is compared.
+func findSimilar(q querier, tenant, id string, sig similarity.Signature, threshold float64) ([]similarMatch, error) {
+	if sig.Empty() {
+		return nil, nil
+	}
+
+	bands := sig.Bands()
+	args := make([]interface{}, 0, 2+2*len(bands))
+	args = append(args, tenant, id)
+	for band, hash := range bands {
+		args = append(args, band, hash)
+	}
+	values := strings.TrimSuffix(strings.Repeat("(?, ?), ", len(bands)), ", ")
+
+	rows, err := q.Query(queryCandidates+values+"))", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []similarMatch
+	for rows.Next() {
+		var match similarMatch
+		var data []byte
+		if err := rows.Scan(&match.feedbackID, &data, &match.clusterID); err != nil {
+			return nil, err
+		}
+		other, err := similarity.Parse(data)
+		if err != nil {
+			return nil, err
+		}
+		match.similarity = sig.Similarity(other)
+		if match.similarity >= threshold {
+			matches = append(matches, match)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].similarity != matches[j].similarity {
+			return matches[i].similarity > matches[j].similarity
+		}
+		return matches[i].feedbackID < matches[j].feedbackID
+	})
+
+	return matches, nil
+}
+
+// clusterOf picks the cluster of the tenant's most similar feedback, feedback that is not
+// like any other starts a cluster named after its own id.
+func clusterOf(q querier, tenant, id string, sig similarity.Signature) (string, error) {
+	matches, err := findSimilar(q, tenant, id, sig, clusterThreshold)
+	if err != nil || len(matches) == 0 {
+		return id, err
+	}
+
+	return matches[0].clusterID, nil
+}
+
+// saveSignature replaces the stored signature and bands of the feedback
+func saveSignature(tx *sql.Tx, id string, sig similarity.Signature) error {
+	if _, err := tx.Exec(querySaveSignature, id, sig.Bytes()); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(queryDeleteBands, id); err != nil {
+		return err
+	}
+	if sig.Empty() {
+		return nil
+	}
+
+	bands := sig.Bands()
+	args := make([]interface{}, 0, 3*len(bands))
+	for band, hash := range bands {
+		args = append(args, band, hash, id)
+	}
+	values := strings.TrimSuffix(strings.Repeat("(?, ?, ?), ", len(bands)), ", ")
+	_, err := tx.Exec(querySaveBands+values, args...)
+
+	return err
+}
+
+// getClusters returns the tenant's clusters with at least minSize feedback, largest first
+func getClusters(db *sql.DB, tenant string, minSize, limit int) ([]*model.FeedbackCluster, error) {
+	rows, err := db.Query(queryClusters, tenant, minSize, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clusters := []*model.FeedbackCluster{}
+	for rows.Next() {
+		cluster := &model.FeedbackCluster{}
+		if err := rows.Scan(&cluster.ID, &cluster.Size); err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, cluster)
+	}
+
+	return clusters, rows.Err()
+}
+
+// getClusterItems returns the feedback that started the cluster first, then the oldest of the others
+func getClusterItems(db *sql.DB, tenant, clusterID string, limit int) ([]*model.UserFeedback, error) {
+	rows, err := db.Query(queryClusterItems, tenant, clusterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	feedbacks, err := scanUsers(rows)
+	if feedbacks == nil && err == nil {
+		feedbacks = []*model.UserFeedback{}
+	}
+
+	return feedbacks, err
+}
+
+// BackfillClusters signs and clusters the feedback saved before clustering existed, oldest first
+// so that the oldest feedback of a cluster is the one the others join. Feedback only joins clusters
+// of its own tenant. It works in batches of
+// batchSize rows per transaction and returns how many rows it clustered. Versions are left alone
+// and no events are published since the feedback itself did not change.
+func BackfillClusters(ctx context.Context, db *sql.DB, batchSize int) (int, error) {
+	clustered := 0
+	for {
+		n, done, err := backfillClustersBatch(ctx, db, batchSize)
+		clustered += n
+		if err != nil || done {
+			return clustered, err
+		}
+	}
+}
+
+// backfillClustersBatch clusters the next batch of unclustered rows, clustered rows drop out
+// of the next batch so no cursor is needed. It reports done once there is nothing left.
+func backfillClustersBatch(ctx context.Context, db *sql.DB, batchSize int) (int, bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, queryUnclusteredUsers, batchSize)
+	if err != nil {
+		return 0, false, err
+	}
+
+	type unclustered struct {
+		id       string
+		tenant   string
+		feedback sql.NullString
+	}
+	var batch []unclustered
+	for rows.Next() {
+		var u unclustered
+		if err := rows.Scan(&u.id, &u.tenant, &u.feedback); err != nil {
+			rows.Close()
+			return 0, false, err
+		}
+		batch = append(batch, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, false, err
+	}
+	if len(batch) == 0 {
+		return 0, true, nil
+	}
+
+	clustered := 0
+	for _, u := range batch {
+		sig := similarity.Sign(u.feedback.String)
+		clusterID, err := clusterOf(tx, u.tenant, u.id, sig)
+		if err != nil {
+			return 0, false, err
+		}
+		res, err := tx.ExecContext(ctx, querySetCluster, clusterID, u.id)
+		if err != nil {
+			return 0, false, err
+		}
+		// rows clustered concurrently by an update are skipped
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, false, err
+		}
+		if n == 0 {
+			continue
+		}
+		if err := saveSignature(tx, u.id, sig); err != nil {
+			return 0, false, err
+		}
+		clustered++
+	}
+
+	return clustered, false, tx.Commit()
+}
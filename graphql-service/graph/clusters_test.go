@@ -0,0 +1,129 @@
+package graph
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/riyadennis/sigist/graphql-service/graph/similarity"
+	"github.com/stretchr/testify/assert"
+)
+
+var candidateColumns = []string{"feedback_id", "signature", "cluster_id"}
+
+// expectCandidates mocks the lookup of feedback sharing a band with a signature
+func expectCandidates(mock sqlmock.Sqlmock, rows *sqlmock.Rows) {
+	mock.ExpectQuery(regexp.QuoteMeta(queryCandidates)).WillReturnRows(rows)
+}
+
+// expectSaveSignature mocks storing the signature and bands of a feedback
+func expectSaveSignature(mock sqlmock.Sqlmock, id interface{}) {
+	mock.ExpectExec(regexp.QuoteMeta(querySaveSignature)).
+		WithArgs(id, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(queryDeleteBands)).
+		WithArgs(id).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(querySaveBands)).
+		WillReturnResult(sqlmock.NewResult(0, similarity.Bands))
+}
+
+func TestClusterOf(t *testing.T) {
+	sig := similarity.Sign(feedback)
+	reworded := similarity.Sign(feedback + " really")
+	unrelated := similarity.Sign("The checkout page keeps timing out on mobile")
+
+	scenarios := []struct {
+		name            string
+		rows            *sqlmock.Rows
+		expectedCluster string
+	}{
+		{
+			name:            "nothing alike starts a cluster",
+			rows:            sqlmock.NewRows(candidateColumns),
+			expectedCluster: id,
+		},
+		{
+			name:            "candidate below the threshold",
+			rows:            sqlmock.NewRows(candidateColumns).AddRow("2", unrelated.Bytes(), "2"),
+			expectedCluster: id,
+		},
+		{
+			name: "joins the cluster of the most similar feedback",
+			rows: sqlmock.NewRows(candidateColumns).
+				AddRow("2", reworded.Bytes(), "cluster-2").
+				AddRow("3", sig.Bytes(), "cluster-3"),
+			expectedCluster: "cluster-3",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			expectCandidates(mock, scenario.rows)
+
+			clusterID, err := clusterOf(db, DefaultTenant, id, sig)
+			assert.NoError(t, err)
+			assert.Equal(t, scenario.expectedCluster, clusterID)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestFindSimilarCorruptSignature(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	expectCandidates(mock, sqlmock.NewRows(candidateColumns).AddRow("2", []byte("short"), "2"))
+
+	_, err = findSimilar(db, DefaultTenant, id, similarity.Sign(feedback), clusterThreshold)
+	assert.Equal(t, similarity.ErrInvalidSignature, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFindSimilarEmptyFeedback(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+
+	// feedback too short to sign is never compared
+	matches, err := findSimilar(db, DefaultTenant, id, similarity.Sign(""), clusterThreshold)
+	assert.NoError(t, err)
+	assert.Empty(t, matches)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestQueryResolverSimilarFeedbackValidation(t *testing.T) {
+	tooMany := maxPageSize + 1
+	resolver := &queryResolver{Resolver: &Resolver{logger: logger}}
+
+	_, err := resolver.SimilarFeedback(context.Background(), toGlobalID(userFeedbackType, id), 0, nil)
+	assert.Equal(t, ErrInvalidThreshold, err)
+	_, err = resolver.SimilarFeedback(context.Background(), toGlobalID(userFeedbackType, id), 1.5, nil)
+	assert.Equal(t, ErrInvalidThreshold, err)
+	_, err = resolver.SimilarFeedback(context.Background(), toGlobalID(userFeedbackType, id), 0.5, &tooMany)
+	assert.Equal(t, ErrInvalidPageSize, err)
+	_, err = resolver.SimilarFeedback(context.Background(), "not-a-global-id", 0.5, nil)
+	assert.Equal(t, ErrInvalidGlobalID, err)
+	assert.Equal(t, ErrCodeValidationFailed, errorCode(ErrInvalidThreshold))
+}
+
+func TestQueryResolverClusters(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	mock.ExpectQuery(regexp.QuoteMeta(queryClusters)).
+		WithArgs("team-a", 2, defaultPageSize).
+		WillReturnRows(sqlmock.NewRows([]string{"cluster_id", "count"}).AddRow("1", 3).AddRow("4", 2))
+
+	ctx := WithTenant(context.Background(), "team-a")
+	resolver := &queryResolver{Resolver: &Resolver{logger: logger, db: db}}
+	clusters, err := resolver.Clusters(ctx, nil, 2)
+	assert.NoError(t, err)
+	assert.Len(t, clusters, 2)
+	assert.Equal(t, "1", clusters[0].ID)
+	assert.Equal(t, 3, clusters[0].Size)
+
+	_, err = resolver.Clusters(ctx, nil, 0)
+	assert.Equal(t, ErrInvalidClusterSize, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
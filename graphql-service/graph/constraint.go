@@ -0,0 +1,174 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/go-playground/validator/v10"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+var (
+	// ErrInvalidInput means that an input field breaks the constraint declared on it in the schema
+	ErrInvalidInput = errors.New("invalid input")
+
+	validate = validator.New()
+
+	// formats are the validator tags that can be used as @constraint(format:)
+	formats = map[string]string{
+		"email": "email",
+		"url":   "url",
+		"uuid":  "uuid",
+	}
+
+	// patterns caches the compiled @constraint(pattern:) expressions
+	patterns sync.Map
+)
+
+// Constraint implements the @constraint directive, a failing field is reported as an error on
+// the path of the field instead of failing the argument so that every invalid field is reported at once.
+// RejectInvalidInput stops the resolver from running with the invalid input.
+func Constraint(ctx context.Context, _ interface{}, next graphql.Resolver, minLength, maxLength *int, format, pattern *string, min, max *int) (interface{}, error) {
+	value, err := next(ctx)
+	if err != nil {
+		return value, err
+	}
+
+	var msg string
+	switch v := value.(type) {
+	case string:
+		msg, err = checkConstraint(v, minLength, maxLength, format, pattern)
+	case *string:
+		if v == nil {
+			return value, nil
+		}
+		msg, err = checkConstraint(*v, minLength, maxLength, format, pattern)
+	case int:
+		msg = checkRange(v, min, max)
+	case *int:
+		if v == nil {
+			return value, nil
+		}
+		msg = checkRange(*v, min, max)
+	default:
+		return value, fmt.Errorf("@constraint can not be used on %T", value)
+	}
+	if err != nil {
+		return value, err
+	}
+	if msg != "" {
+		path := graphql.GetPath(ctx)
+		gqlErr := gqlerror.WrapPath(path, fmt.Errorf("%w: %s %s", ErrInvalidInput, fieldName(path), msg))
+		errcode.Set(gqlErr, ErrCodeValidationFailed)
+		graphql.AddError(ctx, gqlErr)
+	}
+
+	return value, nil
+}
+
+// checkRange returns why the number is out of range, or an empty string when it isn't
+func checkRange(value int, min, max *int) string {
+	if min != nil && value < *min {
+		return fmt.Sprintf("must be at least %d", *min)
+	}
+	if max != nil && value > *max {
+		return fmt.Sprintf("must be at most %d", *max)
+	}
+
+	return ""
+}
+
+// checkConstraint returns why the value breaks the constraint, or an empty string when it doesn't
+func checkConstraint(value string, minLength, maxLength *int, format, pattern *string) (string, error) {
+	if minLength != nil {
+		if err := validate.Var(value, "min="+strconv.Itoa(*minLength)); err != nil {
+			return fmt.Sprintf("must be at least %d characters long", *minLength), nil
+		}
+	}
+	if maxLength != nil {
+		if err := validate.Var(value, "max="+strconv.Itoa(*maxLength)); err != nil {
+			return fmt.Sprintf("must be at most %d characters long", *maxLength), nil
+		}
+	}
+	if format != nil {
+		tag, ok := formats[*format]
+		if !ok {
+			return "", fmt.Errorf("unknown @constraint format %q", *format)
+		}
+		if err := validate.Var(value, tag); err != nil {
+			return "must be a valid " + *format, nil
+		}
+	}
+	if pattern != nil {
+		re, err := compilePattern(*pattern)
+		if err != nil {
+			return "", err
+		}
+		if !re.MatchString(value) {
+			return "must match " + *pattern, nil
+		}
+	}
+
+	return "", nil
+}
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid @constraint pattern: %w", err)
+	}
+	patterns.Store(pattern, re)
+
+	return re, nil
+}
+
+// RejectInvalidInput skips resolvers whose arguments broke a @constraint, the field fails
+// with ErrInvalidInput next to the per-field errors already added by Constraint.
+func RejectInvalidInput(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || len(fc.Args) == 0 {
+		return next(ctx)
+	}
+
+	path := fc.Path()
+	for _, err := range graphql.GetErrors(ctx) {
+		if hasPathPrefix(err.Path, path) && errors.Is(err, ErrInvalidInput) {
+			return nil, ErrInvalidInput
+		}
+	}
+
+	return next(ctx)
+}
+
+func hasPathPrefix(path, prefix ast.Path) bool {
+	if len(path) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if path[i] != prefix[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// fieldName is the name of the input field at the end of the path
+func fieldName(path ast.Path) string {
+	if len(path) == 0 {
+		return "value"
+	}
+
+	return fmt.Sprint(path[len(path)-1])
+}
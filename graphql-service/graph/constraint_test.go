@@ -0,0 +1,97 @@
+package graph
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/riyadennis/sigist/graphql-service/graph/generated"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckConstraint(t *testing.T) {
+	one, three := 1, 3
+	email, unknown := "email", "phone"
+	pattern := "^[a-z]+$"
+
+	scenarios := []struct {
+		name        string
+		value       string
+		minLength   *int
+		maxLength   *int
+		format      *string
+		pattern     *string
+		expectedMsg string
+		expectedErr bool
+	}{
+		{name: "valid", value: "abc", minLength: &one, maxLength: &three, pattern: &pattern},
+		{name: "too short", value: "", minLength: &one, expectedMsg: "must be at least 1 characters long"},
+		{name: "too long", value: "abcd", maxLength: &three, expectedMsg: "must be at most 3 characters long"},
+		{name: "length counts characters", value: "äöü", maxLength: &three},
+		{name: "email", value: "john@test.com", format: &email},
+		{name: "not an email", value: "john", format: &email, expectedMsg: "must be a valid email"},
+		{name: "pattern", value: "ABC", pattern: &pattern, expectedMsg: "must match ^[a-z]+$"},
+		{name: "unknown format", value: "john", format: &unknown, expectedErr: true},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			msg, err := checkConstraint(scenario.value, scenario.minLength, scenario.maxLength, scenario.format, scenario.pattern)
+			assert.Equal(t, scenario.expectedErr, err != nil)
+			assert.Equal(t, scenario.expectedMsg, msg)
+		})
+	}
+}
+
+func TestCheckRange(t *testing.T) {
+	zero, ten := 0, 10
+
+	assert.Equal(t, "", checkRange(0, &zero, &ten))
+	assert.Equal(t, "", checkRange(10, &zero, &ten))
+	assert.Equal(t, "must be at least 0", checkRange(-1, &zero, &ten))
+	assert.Equal(t, "must be at most 10", checkRange(11, &zero, &ten))
+	assert.Equal(t, "", checkRange(11, nil, nil))
+}
+
+func TestConstraintReportsEveryField(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+
+	srv := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{
+		Resolvers:  &Resolver{logger: logger, db: db},
+		Directives: generated.DirectiveRoot{Constraint: Constraint},
+	}))
+	srv.SetErrorPresenter(ErrorPresenter(logger))
+	srv.AroundFields(RejectInvalidInput)
+
+	var resp struct {
+		SaveUserFeedback *struct{ ID string }
+	}
+	err = client.New(srv).Post(`mutation {
+		SaveUserFeedback(input: {firstName: "", lastName: "Doe", email: "john", feedback: "This is a feedback", score: 11}) { id }
+	}`, &resp)
+
+	var errs []struct {
+		Message    string
+		Path       []string
+		Extensions map[string]interface{}
+	}
+	assert.NoError(t, json.Unmarshal([]byte(err.Error()), &errs))
+	assert.Len(t, errs, 4)
+	assert.Equal(t, []string{"SaveUserFeedback", "input", "firstName"}, errs[0].Path)
+	assert.Equal(t, "invalid input: firstName must be at least 1 characters long", errs[0].Message)
+	assert.Equal(t, []string{"SaveUserFeedback", "input", "email"}, errs[1].Path)
+	assert.Equal(t, "invalid input: email must be a valid email", errs[1].Message)
+	assert.Equal(t, []string{"SaveUserFeedback", "input", "score"}, errs[2].Path)
+	assert.Equal(t, "invalid input: score must be at most 10", errs[2].Message)
+	assert.Equal(t, []string{"SaveUserFeedback"}, errs[3].Path)
+	for _, e := range errs {
+		assert.Equal(t, ErrCodeValidationFailed, e.Extensions["code"])
+	}
+
+	// the resolver never ran so nothing was written
+	assert.Nil(t, resp.SaveUserFeedback)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
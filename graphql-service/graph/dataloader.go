@@ -0,0 +1,249 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/global"
+)
+
+const (
+	// loaderWait is how long a loader collects keys before it fetches them
+	loaderWait = 2 * time.Millisecond
+	// maxBatchSize caps the number of keys sent in one IN (...) query
+	maxBatchSize = 100
+)
+
+type loadersKey struct{}
+
+// meter creates the instruments of this package, it records into the global meter provider
+var meter = global.Meter("github.com/riyadennis/sigist/graphql-service/graph")
+
+// batchSizes records how many keys every batched query fetched
+var batchSizes, _ = meter.Int64Histogram(
+	"graphql.dataloader.batch_size",
+	metric.WithDescription("number of keys fetched by one dataloader query"),
+)
+
+// Loaders batch and cache lookups for the lifetime of one response
+type Loaders struct {
+	FeedbackByID            *loader[*model.UserFeedback]
+	SubmitterByEmail        *loader[bool]
+	TagsByFeedbackID        *loader[[]string]
+	RepliesByFeedbackID     *loader[[]*model.FeedbackReply]
+	AttachmentsByFeedbackID *loader[[]*model.Attachment]
+}
+
+// NewLoaders creates loaders with an empty cache that read the tenant's feedback from db,
+// everything else is looked up by the id of a feedback that was loaded for the tenant.
+func NewLoaders(db *sql.DB, tenant string) *Loaders {
+	return &Loaders{
+		FeedbackByID: newLoader("feedback_by_id", func(ids []string) (map[string]*model.UserFeedback, error) {
+			return getUsersByIDs(db, tenant, ids)
+		}),
+		SubmitterByEmail: newLoader("submitter_by_email", func(emails []string) (map[string]bool, error) {
+			return getSubmitterEmails(db, tenant, emails)
+		}),
+		TagsByFeedbackID: newLoader("tags_by_feedback_id", func(ids []string) (map[string][]string, error) {
+			return getTagsByFeedbackIDs(db, ids)
+		}),
+		RepliesByFeedbackID: newLoader("replies_by_feedback_id", func(ids []string) (map[string][]*model.FeedbackReply, error) {
+			return getRepliesByFeedbackIDs(db, ids)
+		}),
+		AttachmentsByFeedbackID: newLoader("attachments_by_feedback_id", func(ids []string) (map[string][]*model.Attachment, error) {
+			return getAttachmentsByFeedbackIDs(db, ids)
+		}),
+	}
+}
+
+// Clear drops everything cached so far, it is called after writes
+func (l *Loaders) Clear() {
+	l.FeedbackByID.clear()
+	l.SubmitterByEmail.clear()
+	l.TagsByFeedbackID.clear()
+	l.RepliesByFeedbackID.clear()
+	l.AttachmentsByFeedbackID.clear()
+}
+
+// LoaderExtension gives every response its own loaders so nothing is cached across operations,
+// the events of a subscription are responses of their own although they share one websocket request.
+type LoaderExtension struct {
+	db *sql.DB
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+} = &LoaderExtension{}
+
+// NewLoaderExtension creates the extension, the tenant is read from the context of the request
+func NewLoaderExtension(db *sql.DB) *LoaderExtension {
+	return &LoaderExtension{db: db}
+}
+
+// ExtensionName returns the name of the extension
+func (e *LoaderExtension) ExtensionName() string {
+	return "Loaders"
+}
+
+// Validate is called when adding an extension to the server
+func (e *LoaderExtension) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// InterceptResponse resolves the response with loaders that have an empty cache
+func (e *LoaderExtension) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	return next(context.WithValue(ctx, loadersKey{}, NewLoaders(e.db, tenantFrom(ctx))))
+}
+
+// loaders returns the loaders of the response, resolvers called outside
+// of the extension get loaders that only live for the call.
+func (r *Resolver) loaders(ctx context.Context) *Loaders {
+	if l, ok := ctx.Value(loadersKey{}).(*Loaders); ok {
+		return l
+	}
+
+	return NewLoaders(r.db, tenantFrom(ctx))
+}
+
+// loader collects the keys asked for by concurrent resolvers and fetches them with one call,
+// keys missing from the fetched map resolve to the zero value.
+type loader[V any] struct {
+	name  string
+	fetch func(keys []string) (map[string]V, error)
+
+	mu    sync.Mutex
+	cache map[string]*loadResult[V]
+	batch []string
+}
+
+type loadResult[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+func newLoader[V any](name string, fetch func(keys []string) (map[string]V, error)) *loader[V] {
+	return &loader[V]{
+		name:  name,
+		fetch: fetch,
+		cache: make(map[string]*loadResult[V]),
+	}
+}
+
+// Load returns the value of one key, waiting for the batch it ends up in
+func (l *loader[V]) Load(ctx context.Context, key string) (V, error) {
+	return l.wait(ctx, l.enqueue(key))
+}
+
+// LoadMany returns the values of the keys in the same order
+func (l *loader[V]) LoadMany(ctx context.Context, keys []string) ([]V, error) {
+	results := make([]*loadResult[V], 0, len(keys))
+	for _, key := range keys {
+		results = append(results, l.enqueue(key))
+	}
+
+	values := make([]V, 0, len(keys))
+	for _, res := range results {
+		v, err := l.wait(ctx, res)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+
+	return values, nil
+}
+
+func (l *loader[V]) enqueue(key string) *loadResult[V] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if res, ok := l.cache[key]; ok {
+		return res
+	}
+
+	res := &loadResult[V]{done: make(chan struct{})}
+	l.cache[key] = res
+	l.batch = append(l.batch, key)
+
+	switch len(l.batch) {
+	case 1:
+		go func() {
+			time.Sleep(loaderWait)
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.dispatch()
+		}()
+	case maxBatchSize:
+		l.dispatch()
+	}
+
+	return res
+}
+
+// dispatch fetches the pending keys in the background, l.mu must be held
+func (l *loader[V]) dispatch() {
+	keys := l.batch
+	if len(keys) == 0 {
+		return
+	}
+	l.batch = nil
+
+	results := make([]*loadResult[V], 0, len(keys))
+	for _, key := range keys {
+		results = append(results, l.cache[key])
+	}
+
+	go func() {
+		batchSizes.Record(context.Background(), int64(len(keys)),
+			metric.WithAttributes(attribute.String("loader", l.name)))
+
+		values, err := l.fetch(keys)
+		for i, res := range results {
+			res.value, res.err = values[keys[i]], err
+			close(res.done)
+		}
+
+		// failed lookups are not cached so that a later load can retry them
+		if err != nil {
+			l.mu.Lock()
+			for i, key := range keys {
+				if l.cache[key] == results[i] {
+					delete(l.cache, key)
+				}
+			}
+			l.mu.Unlock()
+		}
+	}()
+}
+
+func (l *loader[V]) wait(ctx context.Context, res *loadResult[V]) (V, error) {
+	select {
+	case <-res.done:
+		return res.value, res.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// clear forgets cached values, keys that are still being fetched are not affected
+func (l *loader[V]) clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, res := range l.cache {
+		select {
+		case <-res.done:
+			delete(l.cache, key)
+		default:
+		}
+	}
+}
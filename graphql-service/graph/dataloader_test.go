@@ -0,0 +1,153 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/riyadennis/sigist/graphql-service/graph/generated"
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"github.com/riyadennis/sigist/graphql-service/internal/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingFetch returns every key upper cased and remembers the batches it was asked for
+type recordingFetch struct {
+	mu      sync.Mutex
+	batches [][]string
+	err     error
+}
+
+func (f *recordingFetch) fetch(keys []string) (map[string]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches = append(f.batches, keys)
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	values := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if key != "missing" {
+			values[key] = "value-" + key
+		}
+	}
+
+	return values, nil
+}
+
+func TestLoaderBatchesConcurrentLoads(t *testing.T) {
+	f := &recordingFetch{}
+	l := newLoader("test", f.fetch)
+
+	keys := []string{"a", "b", "a", "missing", "c"}
+	values := make([]string, len(keys))
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func(i int, key string) {
+			defer wg.Done()
+			v, err := l.Load(context.Background(), key)
+			assert.NoError(t, err)
+			values[i] = v
+		}(i, key)
+	}
+	wg.Wait()
+
+	assert.Equal(t, []string{"value-a", "value-b", "value-a", "", "value-c"}, values)
+	assert.Len(t, f.batches, 1)
+	assert.ElementsMatch(t, []string{"a", "b", "missing", "c"}, f.batches[0])
+
+	// cached keys are not fetched again
+	v, err := l.Load(context.Background(), "b")
+	assert.NoError(t, err)
+	assert.Equal(t, "value-b", v)
+	assert.Len(t, f.batches, 1)
+
+	l.clear()
+	_, err = l.Load(context.Background(), "b")
+	assert.NoError(t, err)
+	assert.Len(t, f.batches, 2)
+}
+
+func TestLoaderLoadManySplitsLargeBatches(t *testing.T) {
+	f := &recordingFetch{}
+	l := newLoader("test", f.fetch)
+
+	keys := make([]string, maxBatchSize+1)
+	for i := range keys {
+		keys[i] = fmt.Sprint(i)
+	}
+
+	values, err := l.LoadMany(context.Background(), keys)
+	assert.NoError(t, err)
+	assert.Len(t, values, len(keys))
+	assert.Equal(t, "value-100", values[100])
+	assert.Len(t, f.batches, 2)
+	assert.Len(t, f.batches[0], maxBatchSize)
+}
+
+func TestLoaderDoesNotCacheErrors(t *testing.T) {
+	f := &recordingFetch{err: errFailedDBOperation}
+	l := newLoader("test", f.fetch)
+
+	_, err := l.Load(context.Background(), "a")
+	assert.Equal(t, errFailedDBOperation, err)
+
+	f.err = nil
+	v, err := l.Load(context.Background(), "a")
+	assert.NoError(t, err)
+	assert.Equal(t, "value-a", v)
+	assert.Len(t, f.batches, 2)
+}
+
+func TestLoaderExtensionRefetchesPerSubscriptionEvent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	mock.ExpectQuery(regexp.QuoteMeta(queryTagsIn + "(?)" + orderTagsByName)).WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"feedback_id", "name"}).AddRow(id, "billing"))
+	mock.ExpectQuery(regexp.QuoteMeta(queryTagsIn + "(?)" + orderTagsByName)).WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"feedback_id", "name"}).AddRow(id, "billing").AddRow(id, "ux"))
+
+	broadcaster := NewBroadcaster(logger, 1)
+	defer broadcaster.Close()
+	srv := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{
+		Resolvers:  &Resolver{logger: logger, db: db, broadcaster: broadcaster},
+		Directives: generated.DirectiveRoot{Constraint: Constraint, HasRole: HasRole},
+	}))
+	srv.Use(NewLoaderExtension(db))
+	// the websocket client dials a server of its own, so the claims are added by a wrapping handler
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		srv.ServeHTTP(w, r.WithContext(WithClaims(r.Context(), &auth.Claims{Roles: []string{"admin"}})))
+	})
+
+	sub := client.New(h).Websocket(`subscription { feedbackSubmitted { tags } }`)
+	defer sub.Close()
+	assert.Eventually(t, func() bool {
+		broadcaster.mu.Lock()
+		defer broadcaster.mu.Unlock()
+		return len(broadcaster.subscribers) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	var resp struct {
+		FeedbackSubmitted struct {
+			Tags []string
+		}
+	}
+	broadcaster.Publish(DefaultTenant, &model.UserFeedback{ID: id})
+	assert.NoError(t, sub.Next(&resp))
+	assert.Equal(t, []string{"billing"}, resp.FeedbackSubmitted.Tags)
+
+	// the feedback was tagged in between, the event doesn't get the tags cached for the first one
+	broadcaster.Publish(DefaultTenant, &model.UserFeedback{ID: id})
+	assert.NoError(t, sub.Next(&resp))
+	assert.Equal(t, []string{"billing", "ux"}, resp.FeedbackSubmitted.Tags)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -2,25 +2,57 @@ package graph
 
 import (
 	"database/sql"
+	"strings"
+	"time"
+
 	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"github.com/riyadennis/sigist/graphql-service/graph/similarity"
 )
 
 var (
-	querySaveUser           = `INSERT INTO user_feedback (id, first_name, last_name, email, job_title, feedback,created_at) VALUES (?, ?, ?, ?, ?, ?,?)`
-	queryGetUserByID        = `SELECT id, first_name, last_name, email, job_title, feedback, created_at FROM user_feedback WHERE id = ?`
-	queryGetUserByEmail     = `SELECT id, first_name, last_name, email, job_title, feedback, created_at FROM user_feedback WHERE email = ?`
-	queryGetUserByFirstName = `SELECT id, first_name, last_name, email, job_title, feedback, created_at FROM user_feedback WHERE first_name = ?`
-	queryGetAllUsers        = `SELECT id, first_name, last_name, email, job_title, feedback, created_at FROM user_feedback`
+	querySaveUser    = `INSERT INTO user_feedback (id, first_name, last_name, email, job_title, feedback,created_at, sentiment_score, sentiment_label, score, cluster_id, tenant, subject_hash) VALUES (?, ?, ?, ?, ?, ?,?, ?, ?, ?, ?, ?, ?)`
+	queryGetUserByID = `SELECT id, first_name, last_name, email, job_title, feedback, created_at, version, sentiment_score, sentiment_label, score, cluster_id FROM user_feedback WHERE tenant = ? AND id = ?`
+	queryGetUsers    = `SELECT id, first_name, last_name, email, job_title, feedback, created_at, version, sentiment_score, sentiment_label, score, cluster_id FROM user_feedback`
+	queryCountUsers  = `SELECT COUNT(*) FROM user_feedback`
+	queryUpdateUser  = `UPDATE user_feedback SET first_name = COALESCE(?, first_name), last_name = COALESCE(?, last_name), email = COALESCE(?, email), subject_hash = COALESCE(?, subject_hash), job_title = COALESCE(?, job_title), feedback = COALESCE(?, feedback), sentiment_score = COALESCE(?, sentiment_score), sentiment_label = COALESCE(?, sentiment_label), score = COALESCE(?, score), cluster_id = COALESCE(?, cluster_id), version = version + 1 WHERE tenant = ? AND id = ? AND version = ?`
+	queryDeleteUser  = `DELETE FROM user_feedback WHERE tenant = ? AND id = ? AND version = ?`
+	queryUserExists  = `SELECT COUNT(*) FROM user_feedback WHERE tenant = ? AND id = ?`
+	queryGetUsersIn  = `SELECT id, first_name, last_name, email, job_title, feedback, created_at, version, sentiment_score, sentiment_label, score, cluster_id FROM user_feedback WHERE tenant = ? AND id IN `
+	queryEmailsIn    = `SELECT DISTINCT email FROM user_feedback WHERE tenant = ? AND email IN `
 )
 
-func saveUserFeedback(db *sql.DB, input model.UserFeedbackInput, uuid, createdAt string) (sql.Result, error) {
-	stmt, err := db.Prepare(querySaveUser)
+// formatDateTime is the layout created_at is stored in, UTC with a fixed width
+// so that comparing the text column orders rows by time.
+func formatDateTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// saveUserFeedback inserts the feedback of the tenant together with the metadata of its attachments and its
+// idempotency key, the files have to be in storage already. It returns the cluster the feedback joined.
+func saveUserFeedback(db *sql.DB, tenant string, input model.UserFeedbackInput, uuid, createdAt string, sentiment *model.Sentiment, attachments []*model.Attachment, claim *idempotencyClaim) (sql.Result, string, error) {
+	tx, err := db.Begin()
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+	defer tx.Rollback()
+
+	if err := saveIdempotencyKey(tx, claim, uuid); err != nil {
+		return nil, "", err
+	}
+
+	sig := signFeedback(&input.Feedback)
+	clusterID, err := clusterOf(tx, tenant, uuid, sig)
+	if err != nil {
+		return nil, "", err
+	}
+
+	stmt, err := tx.Prepare(querySaveUser)
+	if err != nil {
+		return nil, "", err
 	}
 	defer stmt.Close()
 
-	return stmt.Exec(
+	res, err := stmt.Exec(
 		uuid,
 		input.FirstName,
 		input.LastName,
@@ -28,18 +60,289 @@ func saveUserFeedback(db *sql.DB, input model.UserFeedbackInput, uuid, createdAt
 		input.JobTitle,
 		input.Feedback,
 		createdAt,
+		sentiment.Score,
+		sentiment.Label,
+		input.Score,
+		clusterID,
+		tenant,
+		subjectHash(normalizeSubject(input.Email)),
 	)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := saveSignature(tx, uuid, sig); err != nil {
+		return nil, "", err
+	}
+	if err := saveAttachments(tx, attachments); err != nil {
+		return nil, "", err
+	}
+
+	return res, clusterID, tx.Commit()
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// updateUserFeedback applies the non nil fields of the input if the stored version still matches,
+// a new feedback text is scored again. It returns the feedback as it is after the update.
+func updateUserFeedback(db *sql.DB, tenant, id string, input model.UpdateUserFeedbackInput) (*model.UserFeedback, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var score, label, clusterID, hash interface{}
+	var sig similarity.Signature
+	if input.Email != nil {
+		hash = subjectHash(normalizeSubject(*input.Email))
+	}
+	if input.Feedback != nil {
+		sentiment := analyzeSentiment(*input.Feedback)
+		score, label = sentiment.Score, sentiment.Label
+
+		// reworded feedback can move to another cluster, the cluster it leaves keeps its id
+		sig = signFeedback(input.Feedback)
+		cluster, err := clusterOf(tx, tenant, id, sig)
+		if err != nil {
+			return nil, err
+		}
+		clusterID = cluster
+	}
+
+	res, err := tx.Exec(queryUpdateUser,
+		input.FirstName,
+		input.LastName,
+		input.Email,
+		hash,
+		input.JobTitle,
+		input.Feedback,
+		score,
+		label,
+		input.Score,
+		clusterID,
+		tenant,
+		id,
+		input.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersionedWrite(tx, res, tenant, id); err != nil {
+		return nil, err
+	}
+	if input.Feedback != nil {
+		if err := saveSignature(tx, id, sig); err != nil {
+			return nil, err
+		}
+	}
+
+	feedback, err := getUserByID(tx, tenant, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return feedback, tx.Commit()
+}
+
+// deleteUserFeedback removes the feedback if the stored version still matches,
+// it returns the storage keys of the attachments that were removed with it.
+func deleteUserFeedback(db *sql.DB, tenant, id string, version int) ([]string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	keys, err := getAttachmentKeys(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := tx.Exec(queryDeleteUser, tenant, id, version)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersionedWrite(tx, res, tenant, id); err != nil {
+		return nil, err
+	}
+
+	return keys, tx.Commit()
+}
+
+// checkVersionedWrite tells apart a missing feedback from a stale version when nothing was written,
+// feedback of another tenant is missing.
+func checkVersionedWrite(q querier, res sql.Result, tenant, id string) error {
+	rows, err := res.RowsAffected()
+	if err != nil || rows > 0 {
+		return err
+	}
+
+	var count int
+	if err := q.QueryRow(queryUserExists, tenant, id).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrFeedbackNotFound
+	}
+
+	return ErrVersionConflict
 }
 
-func getUserRows(db *sql.DB, filter model.FilterInput) (*sql.Rows, error) {
-	switch {
-	case filter.ID != nil:
-		return db.Query(queryGetUserByID, *filter.ID)
-	case filter.Email != nil:
-		return db.Query(queryGetUserByEmail, filter.Email)
-	case filter.FirstName != nil:
-		return db.Query(queryGetUserByFirstName, filter.FirstName)
-	default:
-		return db.Query(queryGetAllUsers)
+// getUserRows fetches one keyset page of the tenant's feedback matching the filter in the order of the page,
+// it asks for one row more than the page size so that callers can tell if there are more pages.
+func getUserRows(db *sql.DB, tenant string, filter model.FilterInput, page pageQuery) (*sql.Rows, error) {
+	conditions, args, err := filterConditions(tenant, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if page.cursor != nil {
+		keyset, err := page.order.keyset(page.cursor, page.backward)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, keyset.expr)
+		args = append(args, keyset.args...)
 	}
+	args = append(args, page.limit+1)
+
+	return db.Query(queryGetUsers+where(conditions)+page.order.orderBy(page.backward), args...)
+}
+
+// countUsers returns the number of the tenant's feedback rows matching the filter regardless of paging
+func countUsers(db *sql.DB, tenant string, filter model.FilterInput) (int, error) {
+	conditions, args, err := filterConditions(tenant, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = db.QueryRow(queryCountUsers+where(conditions), args...).Scan(&count)
+	return count, err
+}
+
+// getUserByID returns sql.ErrNoRows when the tenant has no feedback with the given id
+func getUserByID(q querier, tenant, id string) (*model.UserFeedback, error) {
+	rows, err := q.Query(queryGetUserByID, tenant, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	feedbacks, err := scanUsers(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(feedbacks) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return feedbacks[0], nil
+}
+
+// getUsersByIDs fetches every feedback of the tenant with one of the ids in a single query,
+// ids without feedback are left out of the result.
+func getUsersByIDs(db *sql.DB, tenant string, ids []string) (map[string]*model.UserFeedback, error) {
+	rows, err := db.Query(queryGetUsersIn+placeholders(len(ids)), tenantArgs(tenant, ids)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	feedbacks, err := scanUsers(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*model.UserFeedback, len(feedbacks))
+	for _, fb := range feedbacks {
+		byID[fb.ID] = fb
+	}
+
+	return byID, nil
+}
+
+// getSubmitterEmails returns which of the emails submitted at least one feedback to the tenant
+func getSubmitterEmails(db *sql.DB, tenant string, emails []string) (map[string]bool, error) {
+	rows, err := db.Query(queryEmailsIn+placeholders(len(emails)), tenantArgs(tenant, emails)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := make(map[string]bool, len(emails))
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		found[email] = true
+	}
+
+	return found, rows.Err()
+}
+
+func scanUsers(rows *sql.Rows) ([]*model.UserFeedback, error) {
+	var userFeedbacks []*model.UserFeedback
+	for rows.Next() {
+		user := model.UserFeedback{}
+		var sentiment nullSentiment
+		err := rows.Scan(
+			&user.ID, &user.FirstName,
+			&user.LastName, &user.Email,
+			&user.JobTitle, &user.Feedback,
+			&user.CreateAt, &user.Version,
+			&sentiment.score, &sentiment.label,
+			&user.Score, &user.ClusterID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		user.Sentiment = sentiment.value()
+		userFeedbacks = append(userFeedbacks, &user)
+	}
+
+	return userFeedbacks, rows.Err()
+}
+
+// filterConditions limits the rows to the tenant's feedback matching the filter
+func filterConditions(tenant string, filter model.FilterInput) ([]string, []interface{}, error) {
+	conditions, args := []string{"tenant = ?"}, []interface{}{tenant}
+	compiled, err := compileFilter(&filter)
+	if err != nil || compiled.expr == "" {
+		return conditions, args, err
+	}
+
+	return append(conditions, compiled.expr), append(args, compiled.args...), nil
+}
+
+func where(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+// placeholders returns a parenthesised list of n bind parameters for an IN clause
+func placeholders(n int) string {
+	return "(" + strings.TrimSuffix(strings.Repeat("?, ", n), ", ") + ")"
+}
+
+func stringArgs(values []string) []interface{} {
+	args := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		args = append(args, v)
+	}
+
+	return args
+}
+
+// tenantArgs puts the tenant in front of the values of an IN clause
+func tenantArgs(tenant string, values []string) []interface{} {
+	return append([]interface{}{tenant}, stringArgs(values)...)
 }
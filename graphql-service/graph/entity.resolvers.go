@@ -0,0 +1,72 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.32
+
+import (
+	"context"
+
+	"github.com/riyadennis/sigist/graphql-service/graph/generated"
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"go.uber.org/zap"
+)
+
+// FindManySubmitterByEmails is the resolver for the findManySubmitterByEmails field.
+func (r *entityResolver) FindManySubmitterByEmails(ctx context.Context, reps []*model.SubmitterByEmailsInput) ([]*model.Submitter, error) {
+	// _entities can't carry a directive, the gateway has to forward an admin token
+	if err := requireRole(ctx, model.RoleAdmin); err != nil {
+		return nil, err
+	}
+
+	emails := make([]string, 0, len(reps))
+	for _, rep := range reps {
+		emails = append(emails, rep.Email)
+	}
+
+	found, err := r.loaders(ctx).SubmitterByEmail.LoadMany(ctx, emails)
+	if err != nil {
+		r.logger.Error("failed to fetch submitters", zap.Error(err))
+		return nil, err
+	}
+
+	// the gateway matches entities to representations by position, unknown submitters stay nil
+	submitters := make([]*model.Submitter, len(reps))
+	for i, rep := range reps {
+		if found[i] {
+			submitters[i] = &model.Submitter{Email: rep.Email}
+		}
+	}
+
+	return submitters, nil
+}
+
+// FindManyUserFeedbackByIDs is the resolver for the findManyUserFeedbackByIDs field.
+func (r *entityResolver) FindManyUserFeedbackByIDs(ctx context.Context, reps []*model.UserFeedbackByIDsInput) ([]*model.UserFeedback, error) {
+	if err := requireRole(ctx, model.RoleAdmin); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(reps))
+	for _, rep := range reps {
+		id, err := feedbackID(rep.ID)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	// the gateway matches entities to representations by position, deleted feedback stays nil
+	feedbacks, err := r.loaders(ctx).FeedbackByID.LoadMany(ctx, ids)
+	if err != nil {
+		r.logger.Error("failed to fetch feedback", zap.Error(err))
+		return nil, err
+	}
+
+	return feedbacks, nil
+}
+
+// Entity returns generated.EntityResolver implementation.
+func (r *Resolver) Entity() generated.EntityResolver { return &entityResolver{r} }
+
+type entityResolver struct{ *Resolver }
@@ -0,0 +1,355 @@
+package graph
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"github.com/uptrace/opentelemetry-go-extra/otelzap"
+	"go.uber.org/zap"
+)
+
+// erasureService is the name this service reports the status of its own erasures under
+const erasureService = "graphql-service"
+
+var (
+	// ErrInvalidSubject means that the subject to erase is not an email address
+	ErrInvalidSubject = errors.New("the subject to erase must be an email address")
+
+	// ErrTopicNotCompacted means that a topic keeps the events of erased feedback, tombstones only remove them
+	// from topics with cleanup.policy=compact
+	ErrTopicNotCompacted = errors.New("topic is not compacted")
+
+	// feedback is matched on the hash of its normalised email, saved with the feedback and indexed
+	subjectCondition = `tenant = ? AND subject_hash = ?`
+
+	queryErasedFeedback       = `SELECT id FROM user_feedback WHERE ` + subjectCondition
+	queryErasedAttachmentKeys = `SELECT storage_key FROM attachments WHERE feedback_id IN (SELECT id FROM user_feedback WHERE ` + subjectCondition + `)`
+	queryEraseIdempotencyKeys = `DELETE FROM idempotency_keys WHERE tenant = ? AND feedback_id IN (SELECT id FROM user_feedback WHERE ` + subjectCondition + `)`
+	queryEraseFeedback        = `DELETE FROM user_feedback WHERE ` + subjectCondition
+	queryCountUnkeyedFeedback = `SELECT COUNT(*) FROM user_feedback WHERE ` + subjectCondition + ` AND created_at < ?`
+	querySaveErasure          = `INSERT INTO erasures (id, tenant, subject_hash, requested_by, requested_at, feedback_erased, limitations) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	queryGetErasure           = `SELECT id, subject_hash, requested_by, requested_at, feedback_erased, limitations FROM erasures WHERE tenant = ? AND id = ?`
+	queryUnhashedUsers        = `SELECT id, email FROM user_feedback WHERE subject_hash IS NULL AND email IS NOT NULL AND id > ? ORDER BY id LIMIT ?`
+	queryHashUser             = `UPDATE user_feedback SET subject_hash = ? WHERE id = ? AND subject_hash IS NULL`
+)
+
+// Erasures asks the other services that keep personal data how far they got with an erasure,
+// each of them serves the status of its erasures on GET /erasures/{id}.
+type Erasures struct {
+	Logger *otelzap.Logger
+	Client *http.Client
+	// Services are the base urls of the services by name
+	Services map[string]string
+}
+
+// erasureReport is what the other services answer on GET /erasures/{id}
+type erasureReport struct {
+	ErasedCount int       `json:"erased_count"`
+	ErasedAt    time.Time `json:"erased_at"`
+}
+
+// statuses asks every service about the erasure, a service that can't be asked is reported as UNKNOWN
+func (e *Erasures) statuses(ctx context.Context, tenant, id string) []*model.ErasureServiceStatus {
+	if e == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(e.Services))
+	for name := range e.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	statuses := make([]*model.ErasureServiceStatus, 0, len(names))
+	for _, name := range names {
+		status, err := e.status(ctx, tenant, e.Services[name], id)
+		if err != nil {
+			e.Logger.Warn("failed to fetch erasure status", zap.String("service", name), zap.String("id", id), zap.Error(err))
+			status = &model.ErasureServiceStatus{Status: model.ErasureStatusUnknown}
+		}
+		status.Service = name
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
+
+func (e *Erasures) status(ctx context.Context, tenant, baseURL, id string) (*model.ErasureServiceStatus, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+"/erasures/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(TenantHeader, tenant)
+
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return &model.ErasureServiceStatus{Status: model.ErasureStatusPending}, nil
+	default:
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var report erasureReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return nil, err
+	}
+	completedAt := report.ErasedAt.UTC()
+
+	return &model.ErasureServiceStatus{
+		Status:      model.ErasureStatusCompleted,
+		ErasedCount: &report.ErasedCount,
+		CompletedAt: &completedAt,
+	}, nil
+}
+
+// normalizeSubject is the form of an email that feedback is matched and the subject hash is computed on
+func normalizeSubject(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// subjectHash identifies the subject in the erasure log and events without keeping its email
+func subjectHash(subject string) string {
+	sum := sha256.Sum256([]byte(subject))
+	return hex.EncodeToString(sum[:])
+}
+
+// eraseSubject deletes the tenant's feedback submitted with the email of the erasure's subject hash and records
+// the erasure, it returns the ids of the deleted feedback and the storage keys of their attachments.
+// Replies, tags, attachments and signatures are deleted with the feedback by triggers.
+// Feedback created before keyedSince, or any feedback when it is zero, may have unkeyed events
+// that tombstones can't remove, the erasure reports them as a limitation.
+func eraseSubject(db *sql.DB, tenant string, erasure *model.Erasure, keyedSince time.Time) ([]string, []string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	hash := erasure.SubjectHash
+	ids, err := queryStrings(tx, queryErasedFeedback, tenant, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys, err := queryStrings(tx, queryErasedAttachmentKeys, tenant, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	unkeyed := len(ids) > 0
+	if unkeyed && !keyedSince.IsZero() {
+		var count int
+		if err := tx.QueryRow(queryCountUnkeyedFeedback, tenant, hash, formatDateTime(keyedSince)).Scan(&count); err != nil {
+			return nil, nil, err
+		}
+		unkeyed = count > 0
+	}
+	erasure.Limitations = []model.ErasureLimitation{}
+	if unkeyed {
+		erasure.Limitations = append(erasure.Limitations, model.ErasureLimitationUnkeyedEvents)
+	}
+
+	// the request hash of an idempotency key is derived from the personal data
+	if _, err := tx.Exec(queryEraseIdempotencyKeys, tenant, tenant, hash); err != nil {
+		return nil, nil, err
+	}
+	if _, err := tx.Exec(queryEraseFeedback, tenant, hash); err != nil {
+		return nil, nil, err
+	}
+
+	erasure.FeedbackErased = len(ids)
+	_, err = tx.Exec(querySaveErasure, erasure.ID, tenant, erasure.SubjectHash, erasure.RequestedBy,
+		formatDateTime(erasure.RequestedAt), erasure.FeedbackErased, formatLimitations(erasure.Limitations))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ids, keys, tx.Commit()
+}
+
+// getErasure returns sql.ErrNoRows when the tenant has no erasure with the given id
+func getErasure(db *sql.DB, tenant, id string) (*model.Erasure, error) {
+	erasure := &model.Erasure{}
+	var limitations string
+	err := db.QueryRow(queryGetErasure, tenant, id).Scan(&erasure.ID, &erasure.SubjectHash, &erasure.RequestedBy,
+		&erasure.RequestedAt, &erasure.FeedbackErased, &limitations)
+	if err != nil {
+		return nil, err
+	}
+	erasure.Limitations = parseLimitations(limitations)
+
+	return erasure, nil
+}
+
+// formatLimitations is how the limitations of an erasure are stored, separated by commas
+func formatLimitations(limitations []model.ErasureLimitation) string {
+	values := make([]string, len(limitations))
+	for i, limitation := range limitations {
+		values[i] = limitation.String()
+	}
+
+	return strings.Join(values, ",")
+}
+
+func parseLimitations(stored string) []model.ErasureLimitation {
+	limitations := []model.ErasureLimitation{}
+	if stored == "" {
+		return limitations
+	}
+	for _, value := range strings.Split(stored, ",") {
+		limitations = append(limitations, model.ErasureLimitation(value))
+	}
+
+	return limitations
+}
+
+// BackfillSubjectHashes hashes the email of the feedback that was saved before the subject hash was stored,
+// batchSize rows per transaction, and returns how many rows it hashed. Erasures don't find unhashed feedback,
+// so it has to run before erasures are requested.
+func BackfillSubjectHashes(ctx context.Context, db *sql.DB, batchSize int) (int, error) {
+	hashed, lastID := 0, ""
+	for {
+		n, last, err := backfillSubjectHashBatch(ctx, db, lastID, batchSize)
+		hashed += n
+		if err != nil || last == "" {
+			return hashed, err
+		}
+		lastID = last
+	}
+}
+
+// backfillSubjectHashBatch hashes the next batch of unhashed rows after lastID,
+// it returns an empty id once there is nothing left to hash.
+func backfillSubjectHashBatch(ctx context.Context, db *sql.DB, lastID string, batchSize int) (int, string, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, queryUnhashedUsers, lastID, batchSize)
+	if err != nil {
+		return 0, "", err
+	}
+
+	type unhashed struct {
+		id    string
+		email string
+	}
+	var batch []unhashed
+	for rows.Next() {
+		var u unhashed
+		if err := rows.Scan(&u.id, &u.email); err != nil {
+			rows.Close()
+			return 0, "", err
+		}
+		batch = append(batch, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, "", err
+	}
+	if len(batch) == 0 {
+		return 0, "", nil
+	}
+
+	hashed := 0
+	for _, u := range batch {
+		res, err := tx.ExecContext(ctx, queryHashUser, subjectHash(normalizeSubject(u.email)), u.id)
+		if err != nil {
+			return 0, "", err
+		}
+		// rows hashed concurrently by an update are skipped
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, "", err
+		}
+		hashed += int(n)
+	}
+
+	return hashed, batch[len(batch)-1].id, tx.Commit()
+}
+
+func queryStrings(q querier, query string, args ...interface{}) ([]string, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	return values, rows.Err()
+}
+
+// TopicConfigs describes the config of topics, it is implemented by the kafka admin client
+type TopicConfigs interface {
+	DescribeConfigs(ctx context.Context, resources []kafka.ConfigResource, options ...kafka.DescribeConfigsAdminOption) ([]kafka.ConfigResourceResult, error)
+}
+
+// CheckCompaction makes sure that every topic events are sent to is compacted,
+// otherwise the personal data of erased feedback would stay in the topic until it expires.
+func (c *KafkaConfig) CheckCompaction(ctx context.Context, admin TopicConfigs) error {
+	topics := map[string]struct{}{c.Topic: {}}
+	for _, topic := range c.TenantTopics {
+		topics[topic] = struct{}{}
+	}
+	resources := make([]kafka.ConfigResource, 0, len(topics))
+	for topic := range topics {
+		resources = append(resources, kafka.ConfigResource{Type: kafka.ResourceTopic, Name: topic})
+	}
+	sort.Slice(resources, func(i, j int) bool { return resources[i].Name < resources[j].Name })
+
+	results, err := admin.DescribeConfigs(ctx, resources)
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		if result.Error.Code() != kafka.ErrNoError {
+			return fmt.Errorf("describing topic %s: %w", result.Name, result.Error)
+		}
+		policy := result.Config["cleanup.policy"].Value
+		if !strings.Contains(policy, "compact") {
+			return fmt.Errorf("%w: %s has cleanup.policy=%s", ErrTopicNotCompacted, result.Name, policy)
+		}
+	}
+
+	return nil
+}
+
+// publishTombstone sends a message without a value under the key of the feedback, compaction of the topic
+// then drops the earlier events of the feedback and the personal data they carry. Events published before
+// they were keyed have no key to compact on, erasures report them as ErasureLimitationUnkeyedEvents.
+func (r *Resolver) publishTombstone(tenant, id string) error {
+	return r.KafkaConfig.Producer.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: r.KafkaConfig.topic(tenant), Partition: kafka.PartitionAny},
+		Key:            []byte(id),
+		Headers: []kafka.Header{
+			{Key: "event", Value: []byte(EventFeedbackErased)},
+			{Key: "tenant", Value: []byte(tenant)},
+		},
+	}, nil)
+}
@@ -0,0 +1,59 @@
+//go:build sqlite_fts5
+
+package graph
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEraseSubjectSQLite(t *testing.T) {
+	db := migratedDB(t)
+	save := func(id, tenant, email string) {
+		input := model.UserFeedbackInput{FirstName: firstName, LastName: lastName, Email: email, Feedback: "great, thanks"}
+		_, _, err := saveUserFeedback(db, tenant, input, id, formatDateTime(createdAt), &model.Sentiment{}, nil, nil)
+		assert.NoError(t, err)
+	}
+	save("1", DefaultTenant, " John@Test.com")
+	save("2", DefaultTenant, "jane@test.com")
+	save("3", "team-b", "john@test.com")
+
+	// moved to the subject by an update
+	changed := "JOHN@test.com"
+	_, err := updateUserFeedback(db, DefaultTenant, "2", model.UpdateUserFeedbackInput{Email: &changed, Version: 1})
+	assert.NoError(t, err)
+
+	// saved before the subject hash was stored
+	_, err = db.Exec(`INSERT INTO user_feedback (id, first_name, last_name, email, feedback, created_at, tenant) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		"4", firstName, lastName, "ÉMILE@test.com", "it crashes", formatDateTime(createdAt), DefaultTenant)
+	assert.NoError(t, err)
+	hashed, err := BackfillSubjectHashes(context.Background(), db, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, hashed)
+
+	erasure := &model.Erasure{ID: "erasure-1", SubjectHash: subjectHash("john@test.com"), RequestedAt: createdAt}
+	ids, _, err := eraseSubject(db, DefaultTenant, erasure, createdAt)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1", "2"}, ids)
+	assert.Equal(t, 2, erasure.FeedbackErased)
+	assert.Empty(t, erasure.Limitations)
+
+	// lower case is not limited to ASCII
+	erasure = &model.Erasure{ID: "erasure-2", SubjectHash: subjectHash("émile@test.com"), RequestedAt: createdAt}
+	ids, _, err = eraseSubject(db, DefaultTenant, erasure, createdAt.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"4"}, ids)
+
+	// its events were published before they were keyed
+	stored, err := getErasure(db, DefaultTenant, "erasure-2")
+	assert.NoError(t, err)
+	assert.Equal(t, []model.ErasureLimitation{model.ErasureLimitationUnkeyedEvents}, stored.Limitations)
+
+	kept, err := queryStrings(db, `SELECT id FROM user_feedback`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"3"}, kept)
+}
@@ -0,0 +1,322 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"github.com/riyadennis/sigist/graphql-service/internal/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMutationResolverEraseSubject(t *testing.T) {
+	subject := "john@test.com"
+	hash := subjectHash(subject)
+
+	scenarios := []struct {
+		name                string
+		email               string
+		keyedSince          time.Time
+		mock                func(mock sqlmock.Sqlmock)
+		producerErr         error
+		expectedErr         error
+		expectedErased      int
+		expectedLimitations []model.ErasureLimitation
+		expectedMessages    []string
+	}{
+		{
+			name:        "not an email",
+			email:       "john",
+			mock:        func(sqlmock.Sqlmock) {},
+			expectedErr: ErrInvalidSubject,
+		},
+		{
+			name:  "db error",
+			email: subject,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryErasedFeedback)).
+					WithArgs(DefaultTenant, hash).
+					WillReturnError(errFailedDBOperation)
+				mock.ExpectRollback()
+			},
+			expectedErr: errFailedDBOperation,
+		},
+		{
+			name:  "erased",
+			email: "  John@Test.com ",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryErasedFeedback)).
+					WithArgs(DefaultTenant, hash).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id).AddRow("456"))
+				mock.ExpectQuery(regexp.QuoteMeta(queryErasedAttachmentKeys)).
+					WithArgs(DefaultTenant, hash).
+					WillReturnRows(sqlmock.NewRows([]string{"storage_key"}))
+				mock.ExpectExec(regexp.QuoteMeta(queryEraseIdempotencyKeys)).
+					WithArgs(DefaultTenant, DefaultTenant, hash).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(queryEraseFeedback)).
+					WithArgs(DefaultTenant, hash).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec(regexp.QuoteMeta(querySaveErasure)).
+					WithArgs(sqlmock.AnyArg(), DefaultTenant, hash, "admin-1", sqlmock.AnyArg(), 2, "UNKEYED_EVENTS").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expectedErased: 2,
+			// it is not known when events started to be keyed
+			expectedLimitations: []model.ErasureLimitation{model.ErasureLimitationUnkeyedEvents},
+			expectedMessages:    []string{EventFeedbackErased, EventFeedbackErased, EventSubjectErased},
+		},
+		{
+			name:       "feedback created after events were keyed",
+			email:      subject,
+			keyedSince: time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC),
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryErasedFeedback)).
+					WithArgs(DefaultTenant, hash).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+				mock.ExpectQuery(regexp.QuoteMeta(queryErasedAttachmentKeys)).
+					WithArgs(DefaultTenant, hash).
+					WillReturnRows(sqlmock.NewRows([]string{"storage_key"}))
+				mock.ExpectQuery(regexp.QuoteMeta(queryCountUnkeyedFeedback)).
+					WithArgs(DefaultTenant, hash, "2023-05-01T00:00:00Z").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				mock.ExpectExec(regexp.QuoteMeta(queryEraseIdempotencyKeys)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta(queryEraseFeedback)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(querySaveErasure)).
+					WithArgs(sqlmock.AnyArg(), DefaultTenant, hash, "admin-1", sqlmock.AnyArg(), 1, "").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expectedErased:      1,
+			expectedLimitations: []model.ErasureLimitation{},
+			expectedMessages:    []string{EventFeedbackErased, EventSubjectErased},
+		},
+		{
+			name:  "kafka error",
+			email: subject,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryErasedFeedback)).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectQuery(regexp.QuoteMeta(queryErasedAttachmentKeys)).
+					WillReturnRows(sqlmock.NewRows([]string{"storage_key"}))
+				mock.ExpectExec(regexp.QuoteMeta(queryEraseIdempotencyKeys)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta(queryEraseFeedback)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta(querySaveErasure)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			producerErr: errFailedDBOperation,
+			expectedErr: errFailedDBOperation,
+		},
+		{
+			name:  "nothing to erase",
+			email: subject,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryErasedFeedback)).
+					WithArgs(DefaultTenant, hash).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectQuery(regexp.QuoteMeta(queryErasedAttachmentKeys)).
+					WithArgs(DefaultTenant, hash).
+					WillReturnRows(sqlmock.NewRows([]string{"storage_key"}))
+				mock.ExpectExec(regexp.QuoteMeta(queryEraseIdempotencyKeys)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta(queryEraseFeedback)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta(querySaveErasure)).
+					WithArgs(sqlmock.AnyArg(), DefaultTenant, hash, "admin-1", sqlmock.AnyArg(), 0, "").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expectedLimitations: []model.ErasureLimitation{},
+			// the other services are told anyway, they may hold the email without any feedback left here
+			expectedMessages: []string{EventSubjectErased},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			scenario.mock(mock)
+
+			producer := &mockProducer{err: scenario.producerErr}
+			resolver := &mutationResolver{
+				Resolver: &Resolver{
+					logger:      logger,
+					db:          db,
+					KafkaConfig: &KafkaConfig{Topic: "test", Producer: producer, KeyedSince: scenario.keyedSince},
+				},
+			}
+			claims := &auth.Claims{Roles: []string{"admin"}}
+			claims.Subject = "admin-1"
+			ctx := WithClaims(context.Background(), claims)
+
+			erasure, err := resolver.EraseSubject(ctx, scenario.email)
+			assert.ErrorIs(t, err, scenario.expectedErr)
+			assert.NoError(t, mock.ExpectationsWereMet())
+			if scenario.expectedErr != nil {
+				return
+			}
+
+			assert.Equal(t, hash, erasure.SubjectHash)
+			assert.Equal(t, scenario.expectedErased, erasure.FeedbackErased)
+			assert.Equal(t, scenario.expectedLimitations, erasure.Limitations)
+			var events []string
+			for _, msg := range producer.messages {
+				events = append(events, string(msg.Headers[0].Value))
+			}
+			assert.Equal(t, scenario.expectedMessages, events)
+
+			last := producer.messages[len(producer.messages)-1]
+			assert.Equal(t, []byte(erasure.ID), last.Key)
+			var payload SubjectErased
+			assert.NoError(t, json.Unmarshal(last.Value, &payload))
+			assert.Equal(t, erasure.ID, payload.ID)
+			assert.Equal(t, hash, payload.SubjectHash)
+			assert.NotContains(t, string(last.Value), subject)
+			for _, msg := range producer.messages[:len(producer.messages)-1] {
+				assert.Nil(t, msg.Value)
+			}
+		})
+	}
+}
+
+func TestErasureResolverServices(t *testing.T) {
+	erasedAt := time.Date(2023, 5, 2, 10, 0, 0, 0, time.UTC)
+	rest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "team-a", r.Header.Get(TenantHeader))
+		switch r.URL.Path {
+		case "/erasures/done":
+			_, _ = w.Write([]byte(`{"id": "done", "erased_count": 3, "erased_at": "2023-05-02T10:00:00Z"}`))
+		case "/erasures/waiting":
+			http.Error(w, "erasure not found", http.StatusNotFound)
+		default:
+			http.Error(w, "boom", http.StatusInternalServerError)
+		}
+	}))
+	defer rest.Close()
+
+	resolver := &erasureResolver{&Resolver{
+		logger: logger,
+		erasures: &Erasures{
+			Logger:   logger,
+			Client:   rest.Client(),
+			Services: map[string]string{"rest-service": rest.URL},
+		},
+	}}
+	ctx := WithTenant(context.Background(), "team-a")
+	three := 3
+
+	scenarios := []struct {
+		id       string
+		expected *model.ErasureServiceStatus
+	}{
+		{
+			id:       "done",
+			expected: &model.ErasureServiceStatus{Service: "rest-service", Status: model.ErasureStatusCompleted, ErasedCount: &three, CompletedAt: &erasedAt},
+		},
+		{
+			id:       "waiting",
+			expected: &model.ErasureServiceStatus{Service: "rest-service", Status: model.ErasureStatusPending},
+		},
+		{
+			id:       "failing",
+			expected: &model.ErasureServiceStatus{Service: "rest-service", Status: model.ErasureStatusUnknown},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.id, func(t *testing.T) {
+			statuses, err := resolver.Services(ctx, &model.Erasure{ID: scenario.id, RequestedAt: erasedAt, FeedbackErased: 2})
+			assert.NoError(t, err)
+			assert.Len(t, statuses, 2)
+			assert.Equal(t, erasureService, statuses[0].Service)
+			assert.Equal(t, model.ErasureStatusCompleted, statuses[0].Status)
+			assert.Equal(t, 2, *statuses[0].ErasedCount)
+			assert.Equal(t, scenario.expected, statuses[1])
+		})
+	}
+}
+
+// mockTopicConfigs answers with the cleanup policy of every topic, topics it doesn't know are reported as unknown
+type mockTopicConfigs struct {
+	policies map[string]string
+	err      error
+}
+
+func (m *mockTopicConfigs) DescribeConfigs(_ context.Context, resources []kafka.ConfigResource, _ ...kafka.DescribeConfigsAdminOption) ([]kafka.ConfigResourceResult, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	results := make([]kafka.ConfigResourceResult, 0, len(resources))
+	for _, resource := range resources {
+		result := kafka.ConfigResourceResult{Type: resource.Type, Name: resource.Name}
+		policy, ok := m.policies[resource.Name]
+		if !ok {
+			result.Error = kafka.NewError(kafka.ErrUnknownTopicOrPart, "unknown topic", false)
+		}
+		result.Config = map[string]kafka.ConfigEntryResult{"cleanup.policy": {Name: "cleanup.policy", Value: policy}}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func TestKafkaConfigCheckCompaction(t *testing.T) {
+	conf := &KafkaConfig{Topic: "feedback", TenantTopics: map[string]string{"team-a": "team-a-feedback", "team-b": "feedback"}}
+
+	scenarios := []struct {
+		name        string
+		admin       *mockTopicConfigs
+		expectedErr string
+	}{
+		{
+			name:  "compacted",
+			admin: &mockTopicConfigs{policies: map[string]string{"feedback": "compact", "team-a-feedback": "compact,delete"}},
+		},
+		{
+			name:        "tenant topic deletes",
+			admin:       &mockTopicConfigs{policies: map[string]string{"feedback": "compact", "team-a-feedback": "delete"}},
+			expectedErr: "topic is not compacted: team-a-feedback has cleanup.policy=delete",
+		},
+		{
+			name:        "missing topic",
+			admin:       &mockTopicConfigs{policies: map[string]string{"feedback": "compact"}},
+			expectedErr: "describing topic team-a-feedback",
+		},
+		{
+			name:        "broker error",
+			admin:       &mockTopicConfigs{err: errFailedDBOperation},
+			expectedErr: errFailedDBOperation.Error(),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			err := conf.CheckCompaction(context.Background(), scenario.admin)
+			if scenario.expectedErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, scenario.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package graph
+
+import (
+	"context"
+	"errors"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/go-chi/chi/middleware"
+	"github.com/mattn/go-sqlite3"
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"github.com/uptrace/opentelemetry-go-extra/otelzap"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"go.uber.org/zap"
+)
+
+// error codes set in the extensions of errors returned by resolvers,
+// clients should branch on these rather than on messages.
+const (
+	ErrCodeValidationFailed = "VALIDATION_FAILED"
+	ErrCodeUnauthenticated  = "UNAUTHENTICATED"
+	ErrCodeForbidden        = "FORBIDDEN"
+	ErrCodeNotFound         = "NOT_FOUND"
+	ErrCodeConflict         = "CONFLICT"
+	ErrCodeUnavailable      = "UNAVAILABLE"
+	ErrCodeInternal         = "INTERNAL"
+)
+
+var (
+	// errInternal replaces the message of errors that would leak implementation details
+	errInternal = errors.New("internal server error")
+
+	// errUnavailable replaces the message of errors caused by a dependency that is down or busy
+	errUnavailable = errors.New("service is temporarily unavailable, try again later")
+
+	validationErrors = []error{
+		ErrInvalidCursor,
+		ErrInvalidPageArguments,
+		ErrInvalidPageSize,
+		ErrInvalidOrder,
+		ErrInvalidGroupBy,
+		ErrInvalidThreshold,
+		ErrInvalidClusterSize,
+		ErrInvalidTag,
+		ErrTooManyAttachments,
+		ErrAttachmentTooLarge,
+		ErrAttachmentType,
+		ErrInvalidIdempotencyKey,
+		ErrInvalidGlobalID,
+		ErrFilterTooDeep,
+		ErrInvalidTimeWindow,
+		ErrInvalidSearchQuery,
+		ErrMaskedField,
+		ErrInvalidSubject,
+		model.ErrInvalidDateTime,
+		ErrInvalidInput,
+	}
+)
+
+// ErrorPresenter gives every error a code and the id of the request it failed in,
+// the cause of internal and unavailable errors is logged instead of sent to the client.
+func ErrorPresenter(logger *otelzap.Logger) graphql.ErrorPresenterFunc {
+	return func(ctx context.Context, err error) *gqlerror.Error {
+		gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+		requestID := middleware.GetReqID(ctx)
+
+		// errors raised by gqlgen and its extensions, like parse errors, already have a code
+		if _, ok := gqlErr.Extensions["code"]; !ok {
+			code := errorCode(err)
+			switch code {
+			case ErrCodeInternal:
+				// panics have been logged with their stack by RecoverFunc
+				if !errors.Is(err, errInternal) {
+					logger.Error("request failed", zap.Error(err),
+						zap.String("path", gqlErr.Path.String()), zap.String("request_id", requestID))
+				}
+				gqlErr.Message = errInternal.Error()
+			case ErrCodeUnavailable:
+				logger.Warn("dependency unavailable", zap.Error(err),
+					zap.String("path", gqlErr.Path.String()), zap.String("request_id", requestID))
+				gqlErr.Message = errUnavailable.Error()
+			}
+			errcode.Set(gqlErr, code)
+		}
+
+		if requestID != "" {
+			if gqlErr.Extensions == nil {
+				gqlErr.Extensions = map[string]interface{}{}
+			}
+			gqlErr.Extensions["requestId"] = requestID
+		}
+
+		return gqlErr
+	}
+}
+
+// RecoverFunc logs a panicking resolver and reports it to the client as an internal error
+func RecoverFunc(logger *otelzap.Logger) graphql.RecoverFunc {
+	return func(ctx context.Context, err interface{}) error {
+		logger.Error("resolver panicked",
+			zap.Any("panic", err),
+			zap.String("request_id", middleware.GetReqID(ctx)),
+			zap.Stack("stack"),
+		)
+
+		return errInternal
+	}
+}
+
+// errorCode classifies an error returned by a resolver
+func errorCode(err error) string {
+	for _, validationErr := range validationErrors {
+		if errors.Is(err, validationErr) {
+			return ErrCodeValidationFailed
+		}
+	}
+
+	var kafkaErr kafka.Error
+	var sqliteErr sqlite3.Error
+	switch {
+	case errors.Is(err, ErrUnauthenticated):
+		return ErrCodeUnauthenticated
+	case errors.Is(err, ErrForbidden):
+		return ErrCodeForbidden
+	case errors.Is(err, ErrFeedbackNotFound):
+		return ErrCodeNotFound
+	case errors.Is(err, ErrVersionConflict), errors.Is(err, ErrIdempotencyKeyReused):
+		return ErrCodeConflict
+	case errors.Is(err, ErrBroadcasterClosed),
+		errors.Is(err, ErrAttachmentsDisabled),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &kafkaErr):
+		return ErrCodeUnavailable
+	case errors.As(err, &sqliteErr) && (sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked):
+		return ErrCodeUnavailable
+	default:
+		return ErrCodeInternal
+	}
+}
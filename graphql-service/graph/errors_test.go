@@ -0,0 +1,98 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/go-chi/chi/middleware"
+	"github.com/mattn/go-sqlite3"
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+func TestErrorPresenter(t *testing.T) {
+	scenarios := []struct {
+		name            string
+		err             error
+		expectedCode    string
+		expectedMessage string
+	}{
+		{
+			name:            "validation",
+			err:             ErrInvalidCursor,
+			expectedCode:    ErrCodeValidationFailed,
+			expectedMessage: ErrInvalidCursor.Error(),
+		},
+		{
+			name:            "invalid scalar on an argument",
+			err:             gqlerror.WrapPath(nil, model.ErrInvalidDateTime),
+			expectedCode:    ErrCodeValidationFailed,
+			expectedMessage: model.ErrInvalidDateTime.Error(),
+		},
+		{
+			name:            "not found",
+			err:             ErrFeedbackNotFound,
+			expectedCode:    ErrCodeNotFound,
+			expectedMessage: ErrFeedbackNotFound.Error(),
+		},
+		{
+			name:            "conflict",
+			err:             fmt.Errorf("updating: %w", ErrVersionConflict),
+			expectedCode:    ErrCodeConflict,
+			expectedMessage: "updating: " + ErrVersionConflict.Error(),
+		},
+		{
+			name:            "kafka",
+			err:             kafka.NewError(kafka.ErrQueueFull, "Local: Queue full", false),
+			expectedCode:    ErrCodeUnavailable,
+			expectedMessage: errUnavailable.Error(),
+		},
+		{
+			name:            "busy db",
+			err:             sqlite3.Error{Code: sqlite3.ErrBusy},
+			expectedCode:    ErrCodeUnavailable,
+			expectedMessage: errUnavailable.Error(),
+		},
+		{
+			name:            "internal",
+			err:             errFailedDBOperation,
+			expectedCode:    ErrCodeInternal,
+			expectedMessage: errInternal.Error(),
+		},
+		{
+			name: "code set by gqlgen is kept",
+			err: func() error {
+				err := gqlerror.Errorf("PersistedQueryNotFound")
+				errcode.Set(err, "PERSISTED_QUERY_NOT_FOUND")
+				return err
+			}(),
+			expectedCode:    "PERSISTED_QUERY_NOT_FOUND",
+			expectedMessage: "PersistedQueryNotFound",
+		},
+	}
+
+	presenter := ErrorPresenter(logger)
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "host/request-1")
+
+			gqlErr := presenter(ctx, scenario.err)
+			assert.Equal(t, scenario.expectedCode, gqlErr.Extensions["code"])
+			assert.Equal(t, scenario.expectedMessage, gqlErr.Message)
+			assert.Equal(t, "host/request-1", gqlErr.Extensions["requestId"])
+		})
+	}
+}
+
+func TestRecoverFunc(t *testing.T) {
+	err := RecoverFunc(logger)(context.Background(), "boom")
+
+	gqlErr := ErrorPresenter(logger)(context.Background(), err)
+	assert.Equal(t, ErrCodeInternal, gqlErr.Extensions["code"])
+	assert.Equal(t, errInternal.Error(), gqlErr.Message)
+	assert.NotContains(t, gqlErr.Extensions, "requestId")
+}
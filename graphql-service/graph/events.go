@@ -0,0 +1,76 @@
+package graph
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// event types are sent in the "event" header of every kafka message, the tenant in the "tenant" header
+const (
+	EventFeedbackCreated  = "feedback.created"
+	EventFeedbackUpdated  = "feedback.updated"
+	EventFeedbackDeleted  = "feedback.deleted"
+	EventFeedbackTagged   = "feedback.tagged"
+	EventFeedbackUntagged = "feedback.untagged"
+	EventFeedbackReplied  = "feedback.replied"
+	// EventFeedbackErased is a tombstone, a message without a value keyed by the id of an erased feedback
+	EventFeedbackErased = "feedback.erased"
+	EventSubjectErased  = "subject.erased"
+)
+
+// DeletedFeedback is the payload of feedback.deleted events
+type DeletedFeedback struct {
+	ID      string `json:"id"`
+	Version int    `json:"version"`
+}
+
+// TagsChanged is the payload of feedback.tagged and feedback.untagged events,
+// tags only lists the tags that were actually added or removed.
+type TagsChanged struct {
+	ID   string   `json:"id"`
+	Tags []string `json:"tags"`
+}
+
+// RepliedFeedback is the payload of feedback.replied events, it carries the submitter's
+// email and name so that they can be notified without reading the feedback back.
+type RepliedFeedback struct {
+	ID         string    `json:"id"`
+	ReplyID    string    `json:"replyId"`
+	Email      *string   `json:"email"`
+	FirstName  *string   `json:"firstName"`
+	Author     string    `json:"author"`
+	Body       string    `json:"body"`
+	CreateAt   time.Time `json:"createAt"`
+	FirstReply bool      `json:"firstReply"`
+}
+
+// SubjectErased is the payload of subject.erased events, the services that keep personal data
+// delete what they hold on the email whose hash is given and report it under the erasure id.
+type SubjectErased struct {
+	ID          string    `json:"id"`
+	SubjectHash string    `json:"subjectHash"`
+	RequestedAt time.Time `json:"requestedAt"`
+}
+
+// publish sends the payload to the feedback topic of the tenant, messages are keyed by feedback id
+// so that consumers see the events of one feedback in order.
+func (r *Resolver) publish(tenant, eventType, id string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	message := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: r.KafkaConfig.topic(tenant), Partition: kafka.PartitionAny},
+		Key:            []byte(id),
+		Value:          data,
+		Headers: []kafka.Header{
+			{Key: "event", Value: []byte(eventType)},
+			{Key: "tenant", Value: []byte(tenant)},
+		},
+	}
+
+	return r.KafkaConfig.Producer.Produce(message, nil)
+}
@@ -0,0 +1,101 @@
+package graph
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/riyadennis/sigist/graphql-service/graph/generated"
+	"github.com/stretchr/testify/assert"
+)
+
+// gatewayClient stands in for the federation gateway, it talks to the subgraph
+// over http the same way the gateway does.
+func gatewayClient(resolver *Resolver) *client.Client {
+	srv := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{Resolvers: resolver}))
+	return client.New(srv)
+}
+
+func TestFederationServiceSDL(t *testing.T) {
+	c := gatewayClient(&Resolver{logger: logger})
+
+	var resp struct {
+		Service struct {
+			SDL string
+		} `json:"_service"`
+	}
+	c.MustPost(`{ _service { sdl } }`, &resp)
+
+	assert.Contains(t, resp.Service.SDL, `type UserFeedback implements Node @key(fields: "id")`)
+	assert.Contains(t, resp.Service.SDL, `type Submitter @key(fields: "email")`)
+}
+
+func TestFederationEntities(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+
+	// entity types are resolved concurrently, one batched query per type
+	mock.MatchExpectationsInOrder(false)
+
+	missingID := "456"
+	rows := sqlmock.NewRows([]string{"id", "first_name",
+		"last_name", "email",
+		"job_title", "feedback", "created_at", "version", "sentiment_score", "sentiment_label", "score", "cluster_id"}).AddRow(
+		id, firstName, lastName, email, jobTitle, feedback, createdAt, 1, nil, nil, nil, nil)
+	mock.ExpectQuery(regexp.QuoteMeta(queryGetUsersIn+"(?, ?)")).
+		WithArgs(DefaultTenant, missingID, id).WillReturnRows(rows)
+	mock.ExpectQuery(regexp.QuoteMeta(queryEmailsIn+"(?, ?)")).
+		WithArgs(DefaultTenant, email, "nobody@test.com").
+		WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow(email))
+
+	c := gatewayClient(&Resolver{logger: logger, db: db})
+
+	var resp struct {
+		Entities []map[string]interface{} `json:"_entities"`
+	}
+	c.MustPost(`query($representations: [_Any!]!) {
+		_entities(representations: $representations) {
+			... on UserFeedback { id firstName }
+			... on Submitter { email }
+		}
+	}`, &resp, client.Var("representations", []map[string]interface{}{
+		{"__typename": "UserFeedback", "id": toGlobalID(userFeedbackType, missingID)},
+		{"__typename": "Submitter", "email": email},
+		{"__typename": "UserFeedback", "id": toGlobalID(userFeedbackType, id)},
+		{"__typename": "Submitter", "email": "nobody@test.com"},
+	}), asAdmin)
+
+	assert.Equal(t, []map[string]interface{}{
+		nil,
+		{"email": email},
+		{"id": toGlobalID(userFeedbackType, id), "firstName": firstName},
+		nil,
+	}, resp.Entities)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFederationEntitiesInvalidID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+
+	c := gatewayClient(&Resolver{logger: logger, db: db})
+
+	var resp struct {
+		Entities []map[string]interface{} `json:"_entities"`
+	}
+	err = c.Post(`{ _entities(representations: [{__typename: "UserFeedback", id: "not-a-global-id"}]) { ... on UserFeedback { id } } }`, &resp, asAdmin)
+	assert.ErrorContains(t, err, ErrInvalidGlobalID.Error())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFederationEntitiesNeedAdmin(t *testing.T) {
+	c := gatewayClient(&Resolver{logger: logger})
+
+	var resp struct {
+		Entities []map[string]interface{} `json:"_entities"`
+	}
+	err := c.Post(`{ _entities(representations: [{__typename: "Submitter", email: "john.doe@gmail.com"}]) { ... on Submitter { email } } }`, &resp)
+	assert.ErrorContains(t, err, ErrUnauthenticated.Error())
+}
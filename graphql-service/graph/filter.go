@@ -0,0 +1,413 @@
+package graph
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+)
+
+// maxFilterDepth limits how deep and/or/not can be nested in one filter
+const maxFilterDepth = 10
+
+var (
+	// ErrFilterTooDeep means that the filter has more nested and/or/not levels than allowed
+	ErrFilterTooDeep = errors.New("filter is nested too deeply")
+
+	// ErrInvalidTimeWindow means that a relative time window is not a positive amount of a known unit
+	ErrInvalidTimeWindow = errors.New("time window must be a positive amount of hours, days, weeks or months")
+
+	// timeNow is the reference point for relative time windows
+	timeNow = time.Now
+
+	likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+)
+
+// sqlFilter is a parameterised boolean expression,
+// values are never written into the expression itself, only into args.
+type sqlFilter struct {
+	expr string
+	args []interface{}
+}
+
+// compileFilter turns a FilterInput into a where condition for user_feedback,
+// an empty expression means that the filter matches every row.
+func compileFilter(filter *model.FilterInput) (sqlFilter, error) {
+	return compileFilterAt(filter, 0)
+}
+
+func compileFilterAt(filter *model.FilterInput, depth int) (sqlFilter, error) {
+	if filter == nil {
+		return sqlFilter{}, nil
+	}
+	if depth > maxFilterDepth {
+		return sqlFilter{}, ErrFilterTooDeep
+	}
+
+	fields := []struct {
+		column string
+		filter *model.StringFilter
+	}{
+		{column: "id", filter: filter.ID},
+		{column: "first_name", filter: filter.FirstName},
+		{column: "last_name", filter: filter.LastName},
+		{column: "email", filter: filter.Email},
+		{column: "job_title", filter: filter.JobTitle},
+	}
+
+	var parts []sqlFilter
+	for _, field := range fields {
+		if field.filter == nil {
+			continue
+		}
+		part, err := compileStringFilter(field.column, field.filter)
+		if err != nil {
+			return sqlFilter{}, err
+		}
+		parts = append(parts, part)
+	}
+
+	if filter.CreatedAfter != nil {
+		parts = append(parts, sqlFilter{expr: "created_at >= ?", args: []interface{}{formatDateTime(*filter.CreatedAfter)}})
+	}
+	if filter.CreatedBefore != nil {
+		parts = append(parts, sqlFilter{expr: "created_at < ?", args: []interface{}{formatDateTime(*filter.CreatedBefore)}})
+	}
+	if filter.CreatedWithin != nil {
+		since, err := windowStart(filter.CreatedWithin, timeNow())
+		if err != nil {
+			return sqlFilter{}, err
+		}
+		parts = append(parts, sqlFilter{expr: "created_at >= ?", args: []interface{}{formatDateTime(since)}})
+	}
+	if filter.Sentiment != nil {
+		parts = append(parts, compileSentimentFilter(filter.Sentiment))
+	}
+	if filter.Tags != nil {
+		part, err := compileTagFilter(filter.Tags)
+		if err != nil {
+			return sqlFilter{}, err
+		}
+		parts = append(parts, part)
+	}
+
+	for _, and := range filter.And {
+		part, err := compileFilterAt(and, depth+1)
+		if err != nil {
+			return sqlFilter{}, err
+		}
+		parts = append(parts, part)
+	}
+
+	if len(filter.Or) > 0 {
+		var branches []sqlFilter
+		for _, or := range filter.Or {
+			branch, err := compileFilterAt(or, depth+1)
+			if err != nil {
+				return sqlFilter{}, err
+			}
+			branches = append(branches, branch)
+		}
+		parts = append(parts, join(" OR ", branches))
+	}
+
+	if filter.Not != nil {
+		part, err := compileFilterAt(filter.Not, depth+1)
+		if err != nil {
+			return sqlFilter{}, err
+		}
+		if part.expr == "" {
+			part.expr = "1 = 1"
+		}
+		parts = append(parts, sqlFilter{expr: "NOT (" + part.expr + ")", args: part.args})
+	}
+
+	return join(" AND ", parts), nil
+}
+
+func compileStringFilter(column string, filter *model.StringFilter) (sqlFilter, error) {
+	value := func(v string) (interface{}, error) {
+		// ids are exposed as relay global ids, everything else is stored as is
+		if column != "id" {
+			return v, nil
+		}
+		_, id, err := fromGlobalID(v)
+		return id, err
+	}
+
+	var parts []sqlFilter
+	if filter.Eq != nil {
+		v, err := value(*filter.Eq)
+		if err != nil {
+			return sqlFilter{}, err
+		}
+		parts = append(parts, sqlFilter{expr: column + " = ?", args: []interface{}{v}})
+	}
+	if filter.Neq != nil {
+		v, err := value(*filter.Neq)
+		if err != nil {
+			return sqlFilter{}, err
+		}
+		parts = append(parts, sqlFilter{expr: column + " IS NOT ?", args: []interface{}{v}})
+	}
+	if filter.In != nil {
+		if len(filter.In) == 0 {
+			parts = append(parts, sqlFilter{expr: "1 = 0"})
+		} else {
+			args := make([]interface{}, 0, len(filter.In))
+			for _, in := range filter.In {
+				v, err := value(in)
+				if err != nil {
+					return sqlFilter{}, err
+				}
+				args = append(args, v)
+			}
+			placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")
+			parts = append(parts, sqlFilter{expr: column + " IN (" + placeholders + ")", args: args})
+		}
+	}
+	if filter.Contains != nil {
+		parts = append(parts, like(column, "%"+likeEscaper.Replace(*filter.Contains)+"%"))
+	}
+	if filter.StartsWith != nil {
+		parts = append(parts, like(column, likeEscaper.Replace(*filter.StartsWith)+"%"))
+	}
+
+	return join(" AND ", parts), nil
+}
+
+// compileSentimentFilter never matches unscored feedback since comparing with NULL is never true
+func compileSentimentFilter(filter *model.SentimentFilter) sqlFilter {
+	parts := []sqlFilter{{expr: "sentiment_score IS NOT NULL"}}
+	if filter.In != nil {
+		if len(filter.In) == 0 {
+			parts = append(parts, sqlFilter{expr: "1 = 0"})
+		} else {
+			args := make([]interface{}, 0, len(filter.In))
+			for _, in := range filter.In {
+				args = append(args, in.String())
+			}
+			parts = append(parts, sqlFilter{expr: "sentiment_label IN " + placeholders(len(args)), args: args})
+		}
+	}
+	if filter.MinScore != nil {
+		parts = append(parts, sqlFilter{expr: "sentiment_score >= ?", args: []interface{}{*filter.MinScore}})
+	}
+	if filter.MaxScore != nil {
+		parts = append(parts, sqlFilter{expr: "sentiment_score <= ?", args: []interface{}{*filter.MaxScore}})
+	}
+
+	return join(" AND ", parts)
+}
+
+func compileTagFilter(filter *model.TagFilter) (sqlFilter, error) {
+	var parts []sqlFilter
+	if filter.Any != nil {
+		tags, err := normalizeTags(filter.Any)
+		if err != nil {
+			return sqlFilter{}, err
+		}
+		if len(tags) == 0 {
+			parts = append(parts, sqlFilter{expr: "1 = 0"})
+		} else {
+			parts = append(parts, sqlFilter{expr: queryTaggedIn + placeholders(len(tags)) + ")", args: stringArgs(tags)})
+		}
+	}
+	if len(filter.All) > 0 {
+		tags, err := normalizeTags(filter.All)
+		if err != nil {
+			return sqlFilter{}, err
+		}
+		parts = append(parts, sqlFilter{
+			expr: queryTaggedIn + placeholders(len(tags)) + havingEveryTag + ")",
+			args: append(stringArgs(tags), len(tags)),
+		})
+	}
+
+	return join(" AND ", parts), nil
+}
+
+// matchFilter evaluates a filter against one feedback in memory, it follows the
+// semantics of the sql built by compileFilter so it expects a filter that compiles.
+func matchFilter(filter *model.FilterInput, fb *model.UserFeedback, now time.Time) bool {
+	if filter == nil {
+		return true
+	}
+
+	fields := []struct {
+		value  *string
+		filter *model.StringFilter
+		isID   bool
+	}{
+		{value: &fb.ID, filter: filter.ID, isID: true},
+		{value: fb.FirstName, filter: filter.FirstName},
+		{value: fb.LastName, filter: filter.LastName},
+		{value: fb.Email, filter: filter.Email},
+		{value: fb.JobTitle, filter: filter.JobTitle},
+	}
+	for _, field := range fields {
+		if field.filter != nil && !matchString(field.filter, field.value, field.isID) {
+			return false
+		}
+	}
+
+	createdAt := fb.CreateAt
+	if filter.CreatedAfter != nil && (createdAt == nil || createdAt.Before(*filter.CreatedAfter)) {
+		return false
+	}
+	if filter.CreatedBefore != nil && (createdAt == nil || !createdAt.Before(*filter.CreatedBefore)) {
+		return false
+	}
+	if filter.CreatedWithin != nil {
+		since, err := windowStart(filter.CreatedWithin, now)
+		if err != nil || createdAt == nil || createdAt.Before(since) {
+			return false
+		}
+	}
+	if filter.Sentiment != nil && !matchSentiment(filter.Sentiment, fb.Sentiment) {
+		return false
+	}
+	// feedback is only matched in memory as it is saved, before it can have tags
+	if filter.Tags != nil && (filter.Tags.Any != nil || len(filter.Tags.All) > 0) {
+		return false
+	}
+
+	for _, and := range filter.And {
+		if !matchFilter(and, fb, now) {
+			return false
+		}
+	}
+
+	if len(filter.Or) > 0 {
+		matched := false
+		for _, or := range filter.Or {
+			if matchFilter(or, fb, now) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return filter.Not == nil || !matchFilter(filter.Not, fb, now)
+}
+
+// matchString compares case-insensitively for contains and startsWith like sqlite LIKE does
+func matchString(filter *model.StringFilter, value *string, isID bool) bool {
+	decode := func(v string) string {
+		if !isID {
+			return v
+		}
+		_, id, _ := fromGlobalID(v)
+		return id
+	}
+
+	if filter.Eq != nil && (value == nil || *value != decode(*filter.Eq)) {
+		return false
+	}
+	if filter.Neq != nil && value != nil && *value == decode(*filter.Neq) {
+		return false
+	}
+	if filter.In != nil {
+		if value == nil {
+			return false
+		}
+		found := false
+		for _, in := range filter.In {
+			if *value == decode(in) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if filter.Contains != nil && (value == nil || !strings.Contains(strings.ToLower(*value), strings.ToLower(*filter.Contains))) {
+		return false
+	}
+	if filter.StartsWith != nil && (value == nil || !strings.HasPrefix(strings.ToLower(*value), strings.ToLower(*filter.StartsWith))) {
+		return false
+	}
+
+	return true
+}
+
+func matchSentiment(filter *model.SentimentFilter, value *model.Sentiment) bool {
+	if value == nil {
+		return false
+	}
+	if filter.In != nil {
+		found := false
+		for _, in := range filter.In {
+			if value.Label == in {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if filter.MinScore != nil && value.Score < *filter.MinScore {
+		return false
+	}
+
+	return filter.MaxScore == nil || value.Score <= *filter.MaxScore
+}
+
+// windowStart returns the beginning of a window that ends at now
+func windowStart(window *model.TimeWindow, now time.Time) (time.Time, error) {
+	if window.Last <= 0 {
+		return time.Time{}, ErrInvalidTimeWindow
+	}
+
+	switch window.Unit {
+	case model.TimeUnitHour:
+		return now.Add(-time.Duration(window.Last) * time.Hour), nil
+	case model.TimeUnitDay:
+		return now.AddDate(0, 0, -window.Last), nil
+	case model.TimeUnitWeek:
+		return now.AddDate(0, 0, -7*window.Last), nil
+	case model.TimeUnitMonth:
+		return now.AddDate(0, -window.Last, 0), nil
+	default:
+		return time.Time{}, ErrInvalidTimeWindow
+	}
+}
+
+func like(column, pattern string) sqlFilter {
+	return sqlFilter{
+		expr: column + ` LIKE ? ESCAPE '\'`,
+		args: []interface{}{pattern},
+	}
+}
+
+// join combines filters with the given operator, filters that match everything are
+// dropped from AND and make the whole OR match everything.
+func join(operator string, filters []sqlFilter) sqlFilter {
+	var exprs []string
+	var args []interface{}
+	for _, f := range filters {
+		if f.expr == "" {
+			if operator == " OR " {
+				return sqlFilter{}
+			}
+			continue
+		}
+		exprs = append(exprs, f.expr)
+		args = append(args, f.args...)
+	}
+
+	switch len(exprs) {
+	case 0:
+		return sqlFilter{}
+	case 1:
+		return sqlFilter{expr: exprs[0], args: args}
+	default:
+		return sqlFilter{expr: "(" + strings.Join(exprs, operator) + ")", args: args}
+	}
+}
@@ -0,0 +1,234 @@
+package graph
+
+import (
+	"testing"
+	"time"
+
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileFilter(t *testing.T) {
+	test, dev, qa := "test", "Developer", "QA"
+	globalID := toGlobalID(userFeedbackType, id)
+	badID := "not a global id"
+
+	scenarios := []struct {
+		name        string
+		in          *model.FilterInput
+		expr        string
+		args        []interface{}
+		expectedErr error
+	}{
+		{
+			name: "empty filter matches everything",
+			in:   &model.FilterInput{},
+		},
+		{
+			name: "job title in list and email does not contain",
+			in: &model.FilterInput{
+				JobTitle: &model.StringFilter{In: []string{dev, qa}},
+				Not: &model.FilterInput{
+					Email: &model.StringFilter{Contains: &test},
+				},
+			},
+			expr: `(job_title IN (?, ?) AND NOT (email LIKE ? ESCAPE '\'))`,
+			args: []interface{}{dev, qa, "%test%"},
+		},
+		{
+			name: "or of nested filters",
+			in: &model.FilterInput{
+				Or: []*model.FilterInput{
+					{FirstName: &model.StringFilter{Eq: &firstName}},
+					{LastName: &model.StringFilter{StartsWith: &lastName}, JobTitle: &model.StringFilter{Neq: &qa}},
+				},
+			},
+			expr: `(first_name = ? OR (last_name LIKE ? ESCAPE '\' AND job_title IS NOT ?))`,
+			args: []interface{}{firstName, "Doe%", qa},
+		},
+		{
+			name: "or with an empty branch matches everything",
+			in: &model.FilterInput{
+				Or: []*model.FilterInput{
+					{FirstName: &model.StringFilter{Eq: &firstName}},
+					{},
+				},
+			},
+		},
+		{
+			name: "like wildcards are escaped",
+			in: &model.FilterInput{
+				Email: &model.StringFilter{Contains: func() *string { s := `50%_off\`; return &s }()},
+			},
+			expr: `email LIKE ? ESCAPE '\'`,
+			args: []interface{}{`%50\%\_off\\%`},
+		},
+		{
+			name: "created between two times",
+			in: &model.FilterInput{
+				CreatedAfter:  &createdAt,
+				CreatedBefore: func() *time.Time { t := createdAt.Add(time.Hour); return &t }(),
+			},
+			expr: `(created_at >= ? AND created_at < ?)`,
+			args: []interface{}{formatDateTime(createdAt), formatDateTime(createdAt.Add(time.Hour))},
+		},
+		{
+			name: "created within the last 7 days",
+			in: &model.FilterInput{
+				CreatedWithin: &model.TimeWindow{Last: 7, Unit: model.TimeUnitDay},
+			},
+			expr: `created_at >= ?`,
+			args: []interface{}{formatDateTime(createdAt.AddDate(0, 0, -7))},
+		},
+		{
+			name: "empty time window",
+			in: &model.FilterInput{
+				CreatedWithin: &model.TimeWindow{Last: 0, Unit: model.TimeUnitWeek},
+			},
+			expectedErr: ErrInvalidTimeWindow,
+		},
+		{
+			name: "sentiment label and score range",
+			in: &model.FilterInput{
+				Sentiment: &model.SentimentFilter{
+					In:       []model.SentimentLabel{model.SentimentLabelNegative, model.SentimentLabelNeutral},
+					MaxScore: func() *float64 { f := 0.2; return &f }(),
+				},
+			},
+			expr: `(sentiment_score IS NOT NULL AND sentiment_label IN (?, ?) AND sentiment_score <= ?)`,
+			args: []interface{}{"NEGATIVE", "NEUTRAL", 0.2},
+		},
+		{
+			name: "tagged with any and all of",
+			in: &model.FilterInput{
+				Tags: &model.TagFilter{Any: []string{"Bug", "billing"}, All: []string{"ux"}},
+			},
+			expr: `(` + queryTaggedIn + `(?, ?)) AND ` + queryTaggedIn + `(?)` + havingEveryTag + `))`,
+			args: []interface{}{"billing", "bug", "ux", 1},
+		},
+		{
+			name: "invalid tag",
+			in: &model.FilterInput{
+				Tags: &model.TagFilter{Any: []string{"not a tag"}},
+			},
+			expectedErr: ErrInvalidTag,
+		},
+		{
+			name: "ids are decoded from global ids",
+			in: &model.FilterInput{
+				ID: &model.StringFilter{Eq: &globalID},
+			},
+			expr: `id = ?`,
+			args: []interface{}{id},
+		},
+		{
+			name: "invalid global id",
+			in: &model.FilterInput{
+				ID: &model.StringFilter{In: []string{badID}},
+			},
+			expectedErr: ErrInvalidGlobalID,
+		},
+	}
+
+	timeNow = func() time.Time { return createdAt }
+	defer func() { timeNow = time.Now }()
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			compiled, err := compileFilter(scenario.in)
+			assert.Equal(t, scenario.expectedErr, err)
+			assert.Equal(t, scenario.expr, compiled.expr)
+			assert.Equal(t, scenario.args, compiled.args)
+		})
+	}
+}
+
+func TestCompileFilterTooDeep(t *testing.T) {
+	filter := &model.FilterInput{}
+	for i := 0; i <= maxFilterDepth+1; i++ {
+		filter = &model.FilterInput{Not: filter}
+	}
+
+	_, err := compileFilter(filter)
+	assert.Equal(t, ErrFilterTooDeep, err)
+}
+
+func TestMatchFilter(t *testing.T) {
+	dev, qa, test := "Developer", "QA", "TEST"
+	fb := &model.UserFeedback{
+		ID:        id,
+		FirstName: &firstName,
+		Email:     &email,
+		JobTitle:  &dev,
+		CreateAt:  &createdAt,
+		Sentiment: &model.Sentiment{Score: 0.6, Label: model.SentimentLabelPositive},
+	}
+
+	scenarios := []struct {
+		name    string
+		in      *model.FilterInput
+		matches bool
+	}{
+		{
+			name:    "no filter",
+			matches: true,
+		},
+		{
+			name: "job title in list and email does not contain",
+			in: &model.FilterInput{
+				JobTitle: &model.StringFilter{In: []string{dev, qa}},
+				Not: &model.FilterInput{
+					Email: &model.StringFilter{Contains: &test},
+				},
+			},
+			matches: false,
+		},
+		{
+			name: "or of nested filters",
+			in: &model.FilterInput{
+				Or: []*model.FilterInput{
+					{JobTitle: &model.StringFilter{Eq: &qa}},
+					{ID: &model.StringFilter{Eq: func() *string { s := toGlobalID(userFeedbackType, id); return &s }()}},
+				},
+			},
+			matches: true,
+		},
+		{
+			name: "created within the last hour",
+			in: &model.FilterInput{
+				CreatedWithin: &model.TimeWindow{Last: 1, Unit: model.TimeUnitHour},
+			},
+			matches: true,
+		},
+		{
+			name: "created before",
+			in: &model.FilterInput{
+				CreatedBefore: &createdAt,
+			},
+			matches: false,
+		},
+		{
+			name: "positive with a minimum score",
+			in: &model.FilterInput{
+				Sentiment: &model.SentimentFilter{
+					In:       []model.SentimentLabel{model.SentimentLabelPositive},
+					MinScore: func() *float64 { f := 0.5; return &f }(),
+				},
+			},
+			matches: true,
+		},
+		{
+			name: "sentiment above the maximum score",
+			in: &model.FilterInput{
+				Sentiment: &model.SentimentFilter{MaxScore: func() *float64 { f := 0.5; return &f }()},
+			},
+			matches: false,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			assert.Equal(t, scenario.matches, matchFilter(scenario.in, fb, createdAt))
+		})
+	}
+}
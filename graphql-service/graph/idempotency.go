@@ -0,0 +1,182 @@
+package graph
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	// IdempotencyKeyHeader can carry the idempotency key of SaveUserFeedback instead of the input
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// maxIdempotencyKeyLength matches the constraint on the input field
+	maxIdempotencyKeyLength = 255
+)
+
+var (
+	queryGetIdempotencyKey  = `SELECT request_hash, feedback_id FROM idempotency_keys WHERE tenant = ? AND key = ? AND expires_at > ?`
+	querySaveIdempotencyKey = `INSERT INTO idempotency_keys (tenant, key, request_hash, feedback_id, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?)`
+	queryDeleteExpiredKeys  = `DELETE FROM idempotency_keys WHERE expires_at <= ?`
+)
+
+var (
+	// ErrIdempotencyKeyReused means that the key was already used to save feedback with a different input
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used with a different input")
+
+	// ErrInvalidIdempotencyKey means that the idempotency key header is longer than 255 characters
+	ErrInvalidIdempotencyKey = fmt.Errorf("idempotency key must be at most %d characters long", maxIdempotencyKeyLength)
+
+	// errIdempotencyKeyTaken means that a concurrent request saved feedback with the key first
+	errIdempotencyKeyTaken = errors.New("idempotency key is taken")
+)
+
+// idempotentReplays counts the saves that returned the feedback of an earlier request
+var idempotentReplays, _ = meter.Int64Counter(
+	"graphql.feedback.idempotent_replays",
+	metric.WithDescription("number of SaveUserFeedback calls answered with feedback saved by an earlier call"),
+)
+
+type idempotencyKeyKey struct{}
+
+// IdempotencyKeyMiddleware makes the Idempotency-Key header available to the resolvers
+func IdempotencyKeyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key := r.Header.Get(IdempotencyKeyHeader); key != "" {
+			r = r.WithContext(context.WithValue(r.Context(), idempotencyKeyKey{}, key))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// idempotencyClaim is stored together with the feedback it was sent with,
+// keys are only unique within a tenant.
+type idempotencyClaim struct {
+	tenant    string
+	key       string
+	hash      string
+	createdAt time.Time
+	expiresAt time.Time
+}
+
+// claimIdempotencyKey prepares the claim of the key sent with the input, it returns nil when there is no key
+func (r *Resolver) claimIdempotencyKey(ctx context.Context, input model.UserFeedbackInput, createdAt time.Time) (*idempotencyClaim, error) {
+	key, err := idempotencyKey(ctx, input)
+	if err != nil || key == "" {
+		return nil, err
+	}
+
+	hash, err := requestHash(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return &idempotencyClaim{
+		tenant:    tenantFrom(ctx),
+		key:       key,
+		hash:      hash,
+		createdAt: createdAt,
+		expiresAt: createdAt.Add(r.idempotencyTTL),
+	}, nil
+}
+
+// idempotencyKey returns the key of the input or else the one of the header, empty when neither is set
+func idempotencyKey(ctx context.Context, input model.UserFeedbackInput) (string, error) {
+	if input.IdempotencyKey != nil {
+		return *input.IdempotencyKey, nil
+	}
+
+	key, _ := ctx.Value(idempotencyKeyKey{}).(string)
+	if len([]rune(key)) > maxIdempotencyKeyLength {
+		return "", ErrInvalidIdempotencyKey
+	}
+
+	return key, nil
+}
+
+// requestHash identifies the input sent with a key, attachments are compared by name and content
+func requestHash(input model.UserFeedbackInput) (string, error) {
+	fields := input
+	fields.IdempotencyKey = nil
+	fields.Attachments = nil
+
+	h := sha256.New()
+	if err := json.NewEncoder(h).Encode(fields); err != nil {
+		return "", err
+	}
+	for _, upload := range input.Attachments {
+		fmt.Fprintf(h, "%s\n%d\n", upload.Filename, upload.Size)
+		if _, err := io.Copy(h, upload.File); err != nil {
+			return "", err
+		}
+		if _, err := upload.File.Seek(0, io.SeekStart); err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// replay returns the feedback saved with the key, or nil when the key has not been used or has expired.
+// A key sent with a different input is rejected so that a client bug can't silently drop feedback.
+func (r *Resolver) replay(ctx context.Context, claim *idempotencyClaim) (*model.UserFeedback, error) {
+	var hash, feedbackID string
+	err := r.db.QueryRowContext(ctx, queryGetIdempotencyKey, claim.tenant, claim.key, formatDateTime(claim.createdAt)).Scan(&hash, &feedbackID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if hash != claim.hash {
+		return nil, ErrIdempotencyKeyReused
+	}
+
+	feedback, err := getUserByID(r.db, claim.tenant, feedbackID)
+	if err == sql.ErrNoRows {
+		return nil, ErrFeedbackNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	idempotentReplays.Add(ctx, 1)
+
+	return feedback, nil
+}
+
+// saveIdempotencyKey claims the key for the feedback being saved in the transaction,
+// expired keys are dropped first so that they can be used again.
+func saveIdempotencyKey(tx *sql.Tx, claim *idempotencyClaim, feedbackID string) error {
+	if claim == nil {
+		return nil
+	}
+
+	if _, err := tx.Exec(queryDeleteExpiredKeys, formatDateTime(claim.createdAt)); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(querySaveIdempotencyKey,
+		claim.tenant,
+		claim.key,
+		claim.hash,
+		feedbackID,
+		formatDateTime(claim.createdAt),
+		formatDateTime(claim.expiresAt),
+	)
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+		return errIdempotencyKeyTaken
+	}
+
+	return err
+}
@@ -0,0 +1,189 @@
+package graph
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/mattn/go-sqlite3"
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	inputKey, headerKey := "from-input", "from-header"
+
+	var ctx context.Context
+	handler := IdempotencyKeyMiddleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		ctx = r.Context()
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+	req.Header.Set(IdempotencyKeyHeader, headerKey)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	key, err := idempotencyKey(ctx, model.UserFeedbackInput{IdempotencyKey: &inputKey})
+	assert.NoError(t, err)
+	assert.Equal(t, inputKey, key)
+
+	key, err = idempotencyKey(ctx, model.UserFeedbackInput{})
+	assert.NoError(t, err)
+	assert.Equal(t, headerKey, key)
+
+	key, err = idempotencyKey(context.Background(), model.UserFeedbackInput{})
+	assert.NoError(t, err)
+	assert.Equal(t, "", key)
+
+	tooLong := context.WithValue(context.Background(), idempotencyKeyKey{}, strings.Repeat("k", 256))
+	_, err = idempotencyKey(tooLong, model.UserFeedbackInput{})
+	assert.Equal(t, ErrInvalidIdempotencyKey, err)
+}
+
+func TestRequestHash(t *testing.T) {
+	one, two := "one", "two"
+	input := model.UserFeedbackInput{FirstName: firstName, LastName: lastName, Email: email, Feedback: feedback}
+
+	hash, err := requestHash(input)
+	assert.NoError(t, err)
+
+	// the key itself is not part of the request
+	withKey := input
+	withKey.IdempotencyKey = &one
+	sameHash, err := requestHash(withKey)
+	assert.NoError(t, err)
+	assert.Equal(t, hash, sameHash)
+	withKey.IdempotencyKey = &two
+	sameHash, err = requestHash(withKey)
+	assert.NoError(t, err)
+	assert.Equal(t, hash, sameHash)
+
+	changed := input
+	changed.Feedback = "Another feedback"
+	otherHash, err := requestHash(changed)
+	assert.NoError(t, err)
+	assert.NotEqual(t, hash, otherHash)
+
+	withFile := input
+	withFile.Attachments = []*graphql.Upload{upload("screen.png", pngHeader)}
+	fileHash, err := requestHash(withFile)
+	assert.NoError(t, err)
+	assert.NotEqual(t, hash, fileHash)
+
+	// the file can still be stored after it was hashed
+	data, err := io.ReadAll(withFile.Attachments[0].File)
+	assert.NoError(t, err)
+	assert.Equal(t, pngHeader, data)
+}
+
+func TestMutationResolverSaveUserFeedbackIdempotent(t *testing.T) {
+	key := "double-click"
+	input := model.UserFeedbackInput{
+		FirstName:      firstName,
+		LastName:       lastName,
+		Email:          email,
+		Feedback:       feedback,
+		IdempotencyKey: &key,
+	}
+	hash, err := requestHash(input)
+	assert.NoError(t, err)
+
+	storedFeedback := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta(queryGetIdempotencyKey)).
+			WithArgs(DefaultTenant, key, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"request_hash", "feedback_id"}).AddRow(hash, id))
+		mock.ExpectQuery(regexp.QuoteMeta(queryGetUserByID)).
+			WithArgs(DefaultTenant, id).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "first_name", "last_name", "email", "job_title", "feedback",
+				"created_at", "version", "sentiment_score", "sentiment_label", "score", "cluster_id"}).
+				AddRow(id, firstName, lastName, email, nil, feedback, createdAt, 1, nil, nil, nil, nil))
+	}
+
+	scenarios := []struct {
+		name             string
+		mockDB           func(mock sqlmock.Sqlmock)
+		expectedID       string
+		expectedMessages int
+		expectedErr      error
+	}{
+		{
+			name: "first request saves the feedback",
+			mockDB: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(queryGetIdempotencyKey)).
+					WithArgs(DefaultTenant, key, sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"request_hash", "feedback_id"}))
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(queryDeleteExpiredKeys)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta(querySaveIdempotencyKey)).
+					WithArgs(DefaultTenant, key, hash, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				expectCandidates(mock, sqlmock.NewRows(candidateColumns))
+				mock.ExpectPrepare(regexp.QuoteMeta(querySaveUser)).WillBeClosed()
+				mock.ExpectExec(regexp.QuoteMeta(querySaveUser)).WillReturnResult(sqlmock.NewResult(1, 1))
+				expectSaveSignature(mock, sqlmock.AnyArg())
+				mock.ExpectCommit()
+			},
+			expectedMessages: 1,
+		},
+		{
+			name:       "replay returns the saved feedback without publishing",
+			mockDB:     storedFeedback,
+			expectedID: id,
+		},
+		{
+			name: "same key with another input",
+			mockDB: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(queryGetIdempotencyKey)).
+					WithArgs(DefaultTenant, key, sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"request_hash", "feedback_id"}).AddRow("other", id))
+			},
+			expectedErr: ErrIdempotencyKeyReused,
+		},
+		{
+			name: "concurrent request with the same key wins",
+			mockDB: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(queryGetIdempotencyKey)).
+					WithArgs(DefaultTenant, key, sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"request_hash", "feedback_id"}))
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(queryDeleteExpiredKeys)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta(querySaveIdempotencyKey)).
+					WillReturnError(sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintPrimaryKey})
+				mock.ExpectRollback()
+				storedFeedback(mock)
+			},
+			expectedID: id,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			scenario.mockDB(mock)
+
+			producer := &mockProducer{}
+			resolver := &mutationResolver{
+				Resolver: &Resolver{
+					logger:         logger,
+					db:             db,
+					KafkaConfig:    &KafkaConfig{Topic: "test", Producer: producer},
+					broadcaster:    NewBroadcaster(logger, 1),
+					idempotencyTTL: 24 * time.Hour,
+				},
+			}
+			saved, err := resolver.SaveUserFeedback(context.Background(), input)
+			assert.Equal(t, scenario.expectedErr, err)
+			if scenario.expectedID != "" {
+				assert.Equal(t, scenario.expectedID, saved.ID)
+			}
+			assert.Len(t, producer.messages, scenario.expectedMessages)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package graph
+
+import (
+	"context"
+	"strings"
+
+	"github.com/99designs/gqlgen/complexity"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/riyadennis/sigist/graphql-service/graph/generated"
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"github.com/uptrace/opentelemetry-go-extra/otelzap"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"go.uber.org/zap"
+)
+
+// error codes set in the extensions of operations rejected by QueryLimits
+const (
+	ErrCodeQueryTooDeep    = "QUERY_TOO_DEEP"
+	ErrCodeQueryTooComplex = "QUERY_TOO_COMPLEX"
+)
+
+// estimated number of items in the lists of a feedback, they are not paginated so their size is unknown up front
+const (
+	estimatedReplies     = 10
+	estimatedTags        = 5
+	estimatedAttachments = 3
+)
+
+// Complexity weights every paginated field by the number of edges the client asks for and every list
+// of a feedback by its estimated size, fields that are not listed here count as one plus the complexity
+// of their selections. A list inside a page is multiplied by the page size like any other selection.
+func Complexity() generated.ComplexityRoot {
+	var c generated.ComplexityRoot
+
+	c.Query.GetUserFeedback = func(childComplexity int, _ model.FilterInput, _ []*model.FeedbackOrder, first *int, _ *string, last *int, _ *string) int {
+		return pageComplexity(childComplexity, first, last)
+	}
+	c.Query.SearchUserFeedback = func(childComplexity int, _ string, first *int, _ *string) int {
+		return pageComplexity(childComplexity, first, nil)
+	}
+	c.Submitter.Feedback = func(childComplexity int, first *int, _ *string) int {
+		return pageComplexity(childComplexity, first, nil)
+	}
+	c.Query.SimilarFeedback = func(childComplexity int, _ string, _ float64, first *int) int {
+		return pageComplexity(childComplexity, first, nil)
+	}
+	c.Query.Clusters = func(childComplexity int, first *int, _ int) int {
+		return pageComplexity(childComplexity, first, nil)
+	}
+	c.FeedbackCluster.Items = func(childComplexity int, first *int) int {
+		return pageComplexity(childComplexity, first, nil)
+	}
+	c.UserFeedback.Replies = func(childComplexity int) int {
+		return listComplexity(childComplexity, estimatedReplies)
+	}
+	c.UserFeedback.Tags = func(childComplexity int) int {
+		return listComplexity(childComplexity, estimatedTags)
+	}
+	c.UserFeedback.Attachments = func(childComplexity int) int {
+		return listComplexity(childComplexity, estimatedAttachments)
+	}
+
+	return c
+}
+
+// listComplexity counts the selections once per estimated item, items of a scalar list count as one each
+func listComplexity(childComplexity, items int) int {
+	if childComplexity < 1 {
+		childComplexity = 1
+	}
+
+	return 1 + items*childComplexity
+}
+
+// pageComplexity counts the selections once per requested edge, sizes the resolver
+// would reject are capped so that they fail on the page size rather than on complexity.
+func pageComplexity(childComplexity int, first, last *int) int {
+	size := defaultPageSize
+	switch {
+	case first != nil:
+		size = *first
+	case last != nil:
+		size = *last
+	}
+
+	if size < 0 {
+		size = 0
+	}
+	if size > maxPageSize {
+		size = maxPageSize
+	}
+
+	return 1 + size*childComplexity
+}
+
+// QueryLimits rejects operations that nest selections deeper than MaxDepth or that
+// are more complex than MaxComplexity before any resolver runs.
+type QueryLimits struct {
+	Logger        *otelzap.Logger
+	MaxDepth      int
+	MaxComplexity int
+
+	es graphql.ExecutableSchema
+}
+
+var _ interface {
+	graphql.OperationContextMutator
+	graphql.HandlerExtension
+} = &QueryLimits{}
+
+// ExtensionName returns the name of the extension
+func (q *QueryLimits) ExtensionName() string {
+	return "QueryLimits"
+}
+
+// Validate keeps the schema that complexity is calculated against
+func (q *QueryLimits) Validate(schema graphql.ExecutableSchema) error {
+	q.es = schema
+	return nil
+}
+
+// MutateOperationContext measures the operation once it has been parsed and validated
+func (q *QueryLimits) MutateOperationContext(ctx context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+	op := rc.Doc.Operations.ForName(rc.OperationName)
+	if op == nil {
+		return nil
+	}
+
+	if depth := selectionDepth(op.SelectionSet); depth > q.MaxDepth {
+		q.Logger.Warn("rejected operation nested too deeply",
+			zap.String("operation", op.Name),
+			zap.Int("depth", depth),
+			zap.Int("limit", q.MaxDepth),
+		)
+		err := gqlerror.Errorf("operation has depth %d, which exceeds the limit of %d", depth, q.MaxDepth)
+		errcode.Set(err, ErrCodeQueryTooDeep)
+		return err
+	}
+
+	if c := complexity.Calculate(q.es, op, rc.Variables); c > q.MaxComplexity {
+		q.Logger.Warn("rejected operation that is too complex",
+			zap.String("operation", op.Name),
+			zap.Int("complexity", c),
+			zap.Int("limit", q.MaxComplexity),
+		)
+		err := gqlerror.Errorf("operation has complexity %d, which exceeds the limit of %d", c, q.MaxComplexity)
+		errcode.Set(err, ErrCodeQueryTooComplex)
+		return err
+	}
+
+	return nil
+}
+
+// selectionDepth is the number of nested fields below the selection set, fragments
+// do not add a level and introspection fields are not counted so tools keep working.
+func selectionDepth(set ast.SelectionSet) int {
+	depth := 0
+	for _, selection := range set {
+		var d int
+		switch sel := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(sel.Name, "__") {
+				continue
+			}
+			d = 1 + selectionDepth(sel.SelectionSet)
+		case *ast.InlineFragment:
+			d = selectionDepth(sel.SelectionSet)
+		case *ast.FragmentSpread:
+			if sel.Definition != nil {
+				d = selectionDepth(sel.Definition.SelectionSet)
+			}
+		}
+		if d > depth {
+			depth = d
+		}
+	}
+
+	return depth
+}
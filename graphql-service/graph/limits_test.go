@@ -0,0 +1,117 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/riyadennis/sigist/graphql-service/graph/generated"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryLimits(t *testing.T) {
+	scenarios := []struct {
+		name          string
+		query         string
+		maxDepth      int
+		maxComplexity int
+		expectedCode  string
+	}{
+		{
+			name:          "within limits",
+			query:         `query Service { _service { sdl } }`,
+			maxDepth:      2,
+			maxComplexity: 10,
+		},
+		{
+			name: "too deep",
+			query: `query Submitters { _entities(representations: []) {
+				... on Submitter { feedback { edges { node { id } } } }
+			} }`,
+			maxDepth:      4,
+			maxComplexity: 1000,
+			expectedCode:  ErrCodeQueryTooDeep,
+		},
+		{
+			name:          "fragments count towards depth",
+			query:         `query Feedback { GetUserFeedback(filter: {}) { ...edges } } fragment edges on UserFeedbackConnection { edges { node { id } } }`,
+			maxDepth:      3,
+			maxComplexity: 1000,
+			expectedCode:  ErrCodeQueryTooDeep,
+		},
+		{
+			name:          "introspection is not counted",
+			query:         `{ __schema { types { fields { type { ofType { ofType { name } } } } } } }`,
+			maxDepth:      1,
+			maxComplexity: 1000,
+		},
+		{
+			name:          "page size multiplies complexity",
+			query:         `query Feedback { GetUserFeedback(filter: {}, first: 100) { edges { node { id firstName } } } }`,
+			maxDepth:      10,
+			maxComplexity: 100,
+			expectedCode:  ErrCodeQueryTooComplex,
+		},
+		{
+			// 1 + 10 * (edges 1 + node 1 + replies (1 + 10 * 1)) = 131
+			name:          "replies multiply complexity",
+			query:         `query Feedback { GetUserFeedback(filter: {}, first: 10) { edges { node { replies { id } } } } }`,
+			maxDepth:      10,
+			maxComplexity: 130,
+			expectedCode:  ErrCodeQueryTooComplex,
+		},
+		{
+			// 1 + 10 * (edges 1 + node 1 + tags (1 + 5)) = 81
+			name:          "tags multiply complexity",
+			query:         `query Feedback { GetUserFeedback(filter: {}, first: 10) { edges { node { tags } } } }`,
+			maxDepth:      10,
+			maxComplexity: 80,
+			expectedCode:  ErrCodeQueryTooComplex,
+		},
+		{
+			// 1 + 10 * (edges 1 + node 1 + attachments (1 + 3 * 2)) = 91
+			name:          "attachments multiply complexity",
+			query:         `query Feedback { GetUserFeedback(filter: {}, first: 10) { edges { node { attachments { id filename } } } } }`,
+			maxDepth:      10,
+			maxComplexity: 90,
+			expectedCode:  ErrCodeQueryTooComplex,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			srv := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{
+				Resolvers:  &Resolver{logger: logger},
+				Complexity: Complexity(),
+			}))
+			srv.Use(&QueryLimits{
+				Logger:        logger,
+				MaxDepth:      scenario.maxDepth,
+				MaxComplexity: scenario.maxComplexity,
+			})
+
+			var resp map[string]interface{}
+			err := client.New(srv).Post(scenario.query, &resp)
+			if scenario.expectedCode == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, `"code":"`+scenario.expectedCode+`"`)
+		})
+	}
+}
+
+func TestPageComplexity(t *testing.T) {
+	first, negative, huge := 5, -1, 1000
+
+	assert.Equal(t, 1+defaultPageSize*3, pageComplexity(3, nil, nil))
+	assert.Equal(t, 16, pageComplexity(3, &first, nil))
+	assert.Equal(t, 16, pageComplexity(3, nil, &first))
+	assert.Equal(t, 1, pageComplexity(3, &negative, nil))
+	assert.Equal(t, 1+maxPageSize*3, pageComplexity(3, &huge, nil))
+}
+
+func TestListComplexity(t *testing.T) {
+	assert.Equal(t, 1+estimatedTags, listComplexity(0, estimatedTags))
+	assert.Equal(t, 1+estimatedReplies*2, listComplexity(2, estimatedReplies))
+}
@@ -0,0 +1,332 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/go-chi/chi/middleware"
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"github.com/uptrace/opentelemetry-go-extra/otelzap"
+	"go.uber.org/zap"
+)
+
+// personal fields of UserFeedback that can be masked, named like in the schema
+const (
+	// maskedEmail is also the key of Submitter entities, it has to stay unmasked for the admins that resolve them
+	maskedEmail    = "email"
+	maskedLastName = "lastName"
+)
+
+// masks replace a value with a hint of it
+const (
+	// MaskEmail keeps the first letter and the domain, j***@doe.com
+	MaskEmail = "email"
+	// MaskInitial keeps the first letter, D.
+	MaskInitial = "initial"
+	// MaskRedact replaces the whole value
+	MaskRedact = "redact"
+	// MaskNone shows the value to everyone
+	MaskNone = "none"
+)
+
+// auditBatchSize keeps the bind parameters of one insert well below the sqlite limit
+const auditBatchSize = 100
+
+var (
+	// ErrMaskedField means that the filter or orderBy uses a field the caller can only see masked,
+	// it would let them guess the value.
+	ErrMaskedField = errors.New("can't filter or sort by a field that is masked for you")
+
+	// ErrInvalidMaskingPolicy means that the policy file names an unknown field or mask,
+	// or masks the email for admins
+	ErrInvalidMaskingPolicy = errors.New("invalid masking policy")
+
+	queryAuditRead = `INSERT INTO pii_audit_log (tenant, subject, field, feedback_id, request_id, read_at) VALUES `
+)
+
+// MaskingPolicy says how a field is masked and who sees it as it is
+type MaskingPolicy struct {
+	Mask        string       `json:"mask"`
+	UnmaskedFor []model.Role `json:"unmaskedFor"`
+}
+
+// MaskingPolicies are the policies by field
+type MaskingPolicies map[string]MaskingPolicy
+
+// DefaultMaskingPolicies shows emails and last names only to admins
+func DefaultMaskingPolicies() MaskingPolicies {
+	return MaskingPolicies{
+		maskedEmail:    {Mask: MaskEmail, UnmaskedFor: []model.Role{model.RoleAdmin}},
+		maskedLastName: {Mask: MaskInitial, UnmaskedFor: []model.Role{model.RoleAdmin}},
+	}
+}
+
+// LoadMaskingPolicies reads a JSON file of policies by field, fields left out keep their default policy
+func LoadMaskingPolicies(path string) (MaskingPolicies, error) {
+	policies := DefaultMaskingPolicies()
+	if path == "" {
+		return policies, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading masking policy: %w", err)
+	}
+	var file MaskingPolicies
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMaskingPolicy, err)
+	}
+
+	for field, policy := range file {
+		if _, ok := policies[field]; !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidMaskingPolicy, field)
+		}
+		switch policy.Mask {
+		case MaskEmail, MaskInitial, MaskRedact, MaskNone:
+		default:
+			return nil, fmt.Errorf("%w: unknown mask %q", ErrInvalidMaskingPolicy, policy.Mask)
+		}
+		unmaskedForAdmin := policy.Mask == MaskNone
+		for _, role := range policy.UnmaskedFor {
+			if !role.IsValid() {
+				return nil, fmt.Errorf("%w: unknown role %q", ErrInvalidMaskingPolicy, role)
+			}
+			unmaskedForAdmin = unmaskedForAdmin || role == model.RoleAdmin
+		}
+		// Submitter entities and their feedback are looked up by email on behalf of admins
+		if field == maskedEmail && !unmaskedForAdmin {
+			return nil, fmt.Errorf("%w: the email is the key of submitters and can't be masked for %s", ErrInvalidMaskingPolicy, model.RoleAdmin)
+		}
+		policies[field] = policy
+	}
+
+	return policies, nil
+}
+
+// Masking hides personal data from callers whose role doesn't allow seeing it and
+// keeps an audit log of every unmasked read, it is a gqlgen extension so that the reads
+// of a response are logged together once it is ready.
+type Masking struct {
+	logger   *otelzap.Logger
+	db       *sql.DB
+	policies MaskingPolicies
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+} = &Masking{}
+
+// NewMasking creates the masking of personal fields
+func NewMasking(logger *otelzap.Logger, db *sql.DB, policies MaskingPolicies) *Masking {
+	return &Masking{logger: logger, db: db, policies: policies}
+}
+
+// auditRead is an unmasked read of a field
+type auditRead struct {
+	field      string
+	feedbackID string
+}
+
+// auditTrail collects the unmasked reads of one response, fields resolve concurrently
+type auditTrail struct {
+	mu    sync.Mutex
+	reads map[auditRead]struct{}
+}
+
+type auditTrailKey struct{}
+
+func (t *auditTrail) add(read auditRead) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.reads[read] = struct{}{}
+}
+
+// ExtensionName returns the name of the extension
+func (m *Masking) ExtensionName() string {
+	return "Masking"
+}
+
+// Validate is called when adding an extension to the server
+func (m *Masking) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// InterceptResponse writes the unmasked reads of the response to the audit log,
+// the response is replaced by an error when they can't be written.
+func (m *Masking) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	trail := &auditTrail{reads: map[auditRead]struct{}{}}
+	resp := next(context.WithValue(ctx, auditTrailKey{}, trail))
+	if resp == nil || len(trail.reads) == 0 {
+		return resp
+	}
+
+	if err := m.audit(ctx, trail); err != nil {
+		m.logger.Error("failed to write audit log", zap.Error(err))
+		errResp := graphql.ErrorResponse(ctx, errUnavailable.Error())
+		errcode.Set(errResp.Errors[0], ErrCodeUnavailable)
+		return errResp
+	}
+
+	return resp
+}
+
+// audit inserts the reads in batches inside one transaction
+func (m *Masking) audit(ctx context.Context, trail *auditTrail) error {
+	var subject string
+	if claims := claimsFrom(ctx); claims != nil {
+		subject = claims.Subject
+	}
+	tenant, requestID, readAt := tenantFrom(ctx), middleware.GetReqID(ctx), formatDateTime(time.Now())
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	args := make([]interface{}, 0, auditBatchSize*6)
+	flush := func(rows int) error {
+		if rows == 0 {
+			return nil
+		}
+		values := strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?, ?, ?), ", rows), ", ")
+		if _, err := tx.Exec(queryAuditRead+values, args...); err != nil {
+			return err
+		}
+		args = args[:0]
+		return nil
+	}
+
+	rows := 0
+	for read := range trail.reads {
+		args = append(args, tenant, subject, read.field, read.feedbackID, requestID, readAt)
+		rows++
+		if rows == auditBatchSize {
+			if err := flush(rows); err != nil {
+				return err
+			}
+			rows = 0
+		}
+	}
+	if err := flush(rows); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// unmasked reports whether the caller sees the field as it is
+func (m *Masking) unmasked(ctx context.Context, field string) bool {
+	policy := m.policy(field)
+	if policy.Mask == MaskNone {
+		return true
+	}
+	claims := claimsFrom(ctx)
+	if claims == nil {
+		return false
+	}
+	for _, role := range policy.UnmaskedFor {
+		if claims.HasRole(role.String()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *Masking) policy(field string) MaskingPolicy {
+	if m == nil {
+		return DefaultMaskingPolicies()[field]
+	}
+
+	return m.policies[field]
+}
+
+// apply returns the value of the field of a feedback as the caller may see it,
+// unmasked reads are added to the audit trail of the response.
+func (m *Masking) apply(ctx context.Context, field, feedbackID string, value *string) *string {
+	if value == nil {
+		return nil
+	}
+	if !m.unmasked(ctx, field) {
+		masked := mask(m.policy(field).Mask, *value)
+		return &masked
+	}
+
+	if m.policy(field).Mask != MaskNone {
+		if trail, ok := ctx.Value(auditTrailKey{}).(*auditTrail); ok {
+			trail.add(auditRead{field: field, feedbackID: feedbackID})
+		}
+	}
+
+	return value
+}
+
+// checkFilter rejects filters on fields that are masked for the caller, nested filters included
+func (m *Masking) checkFilter(ctx context.Context, filter *model.FilterInput) error {
+	if filter == nil {
+		return nil
+	}
+	if filter.Email != nil && !m.unmasked(ctx, maskedEmail) {
+		return ErrMaskedField
+	}
+	if filter.LastName != nil && !m.unmasked(ctx, maskedLastName) {
+		return ErrMaskedField
+	}
+	for _, f := range append(append([]*model.FilterInput{filter.Not}, filter.And...), filter.Or...) {
+		if err := m.checkFilter(ctx, f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkOrder rejects sorting by fields that are masked for the caller, cursors would carry their values
+func (m *Masking) checkOrder(ctx context.Context, orderBy []*model.FeedbackOrder) error {
+	for _, order := range orderBy {
+		if order == nil {
+			continue
+		}
+		if order.Field == model.FeedbackOrderFieldEmail && !m.unmasked(ctx, maskedEmail) ||
+			order.Field == model.FeedbackOrderFieldLastName && !m.unmasked(ctx, maskedLastName) {
+			return ErrMaskedField
+		}
+	}
+
+	return nil
+}
+
+// mask hides the value, empty values stay empty
+func mask(kind, value string) string {
+	if value == "" {
+		return ""
+	}
+
+	first, _ := utf8.DecodeRuneInString(value)
+	switch kind {
+	case MaskEmail:
+		at := strings.LastIndex(value, "@")
+		if at < 0 {
+			return string(first) + "***"
+		}
+		return string(first) + "***" + value[at:]
+	case MaskInitial:
+		return string(first) + "."
+	case MaskNone:
+		return value
+	default:
+		return "***"
+	}
+}
@@ -0,0 +1,188 @@
+package graph
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/riyadennis/sigist/graphql-service/graph/generated"
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"github.com/riyadennis/sigist/graphql-service/internal/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+// asAnalyst makes a test client request on behalf of an analyst
+func asAnalyst(r *client.Request) {
+	r.HTTP = r.HTTP.WithContext(WithClaims(r.HTTP.Context(), &auth.Claims{Roles: []string{"analyst"}}))
+}
+
+func TestMask(t *testing.T) {
+	scenarios := []struct {
+		kind     string
+		value    string
+		expected string
+	}{
+		{kind: MaskEmail, value: "john@doe.com", expected: "j***@doe.com"},
+		{kind: MaskEmail, value: "not-an-email", expected: "n***"},
+		{kind: MaskInitial, value: "Doe", expected: "D."},
+		{kind: MaskInitial, value: "Ørsted", expected: "Ø."},
+		{kind: MaskRedact, value: "Doe", expected: "***"},
+		{kind: MaskNone, value: "Doe", expected: "Doe"},
+		{kind: MaskEmail, value: "", expected: ""},
+	}
+
+	for _, scenario := range scenarios {
+		assert.Equal(t, scenario.expected, mask(scenario.kind, scenario.value), scenario.kind+" "+scenario.value)
+	}
+}
+
+func TestLoadMaskingPolicies(t *testing.T) {
+	write := func(content string) string {
+		path := filepath.Join(t.TempDir(), "policy.json")
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	policies, err := LoadMaskingPolicies("")
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultMaskingPolicies(), policies)
+
+	policies, err = LoadMaskingPolicies(write(`{"email": {"mask": "redact", "unmaskedFor": ["ADMIN", "ANALYST"]}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, MaskingPolicy{Mask: MaskRedact, UnmaskedFor: []model.Role{model.RoleAdmin, model.RoleAnalyst}}, policies[maskedEmail])
+	assert.Equal(t, DefaultMaskingPolicies()[maskedLastName], policies[maskedLastName])
+
+	// the last name isn't a key, so it can be hidden from everyone
+	policies, err = LoadMaskingPolicies(write(`{"email": {"mask": "none"}, "lastName": {"mask": "redact"}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, MaskingPolicy{Mask: MaskRedact}, policies[maskedLastName])
+
+	for _, invalid := range []string{
+		`{"firstName": {"mask": "initial"}}`,
+		`{"email": {"mask": "hash"}}`,
+		`{"email": {"mask": "email", "unmaskedFor": ["SUPPORT"]}}`,
+		`{"email": {"mask": "email", "unmaskedFor": ["ANALYST"]}}`,
+		`{"email": {"mask": "redact"}}`,
+		`not json`,
+	} {
+		_, err = LoadMaskingPolicies(write(invalid))
+		assert.ErrorIs(t, err, ErrInvalidMaskingPolicy, invalid)
+	}
+}
+
+func TestMaskingApply(t *testing.T) {
+	m := NewMasking(logger, nil, MaskingPolicies{
+		maskedEmail:    {Mask: MaskEmail, UnmaskedFor: []model.Role{model.RoleAdmin}},
+		maskedLastName: {Mask: MaskNone},
+	})
+	admin := WithClaims(context.Background(), &auth.Claims{Roles: []string{"admin"}})
+	analyst := WithClaims(context.Background(), &auth.Claims{Roles: []string{"analyst"}})
+
+	assert.Equal(t, "j***@test.com", *m.apply(context.Background(), maskedEmail, id, &email))
+	assert.Equal(t, "j***@test.com", *m.apply(analyst, maskedEmail, id, &email))
+	assert.Nil(t, m.apply(analyst, maskedEmail, id, nil))
+
+	trail := &auditTrail{reads: map[auditRead]struct{}{}}
+	ctx := context.WithValue(admin, auditTrailKey{}, trail)
+	assert.Equal(t, email, *m.apply(ctx, maskedEmail, id, &email))
+	assert.Equal(t, email, *m.apply(ctx, maskedEmail, id, &email))
+	// fields that are never masked are not audited
+	assert.Equal(t, lastName, *m.apply(ctx, maskedLastName, id, &lastName))
+	assert.Equal(t, map[auditRead]struct{}{{field: maskedEmail, feedbackID: id}: {}}, trail.reads)
+}
+
+func TestMaskingCheckArguments(t *testing.T) {
+	m := NewMasking(logger, nil, DefaultMaskingPolicies())
+	admin := WithClaims(context.Background(), &auth.Claims{Roles: []string{"admin"}})
+	analyst := WithClaims(context.Background(), &auth.Claims{Roles: []string{"analyst"}})
+	nested := &model.FilterInput{Or: []*model.FilterInput{
+		{JobTitle: &model.StringFilter{Eq: &jobTitle}},
+		{Not: &model.FilterInput{LastName: &model.StringFilter{StartsWith: &lastName}}},
+	}}
+
+	assert.NoError(t, m.checkFilter(analyst, &model.FilterInput{JobTitle: &model.StringFilter{Eq: &jobTitle}}))
+	assert.Equal(t, ErrMaskedField, m.checkFilter(analyst, &model.FilterInput{Email: &model.StringFilter{Eq: &email}}))
+	assert.Equal(t, ErrMaskedField, m.checkFilter(analyst, nested))
+	assert.NoError(t, m.checkFilter(admin, nested))
+	assert.Equal(t, ErrCodeValidationFailed, errorCode(ErrMaskedField))
+
+	byEmail := []*model.FeedbackOrder{{Field: model.FeedbackOrderFieldEmail, Direction: model.OrderDirectionAsc}}
+	assert.Equal(t, ErrMaskedField, m.checkOrder(analyst, byEmail))
+	assert.NoError(t, m.checkOrder(admin, byEmail))
+	assert.NoError(t, m.checkOrder(analyst, []*model.FeedbackOrder{{Field: model.FeedbackOrderFieldCreateAt}}))
+}
+
+func TestMaskingAuditsUnmaskedReads(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		as          client.Option
+		auditErr    error
+		expectedErr string
+		expected    map[string]interface{}
+	}{
+		{
+			name:     "analyst reads masked",
+			as:       asAnalyst,
+			expected: map[string]interface{}{"lastName": "D.", "email": "j***@test.com"},
+		},
+		{
+			name:     "admin reads unmasked",
+			as:       asAdmin,
+			expected: map[string]interface{}{"lastName": lastName, "email": email},
+		},
+		{
+			name:        "unmasked read that can't be audited",
+			as:          asAdmin,
+			auditErr:    errFailedDBOperation,
+			expectedErr: `"code":"` + ErrCodeUnavailable + `"`,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			mock.ExpectQuery(regexp.QuoteMeta(queryGetUsersIn+"(?)")).WithArgs(DefaultTenant, id).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "first_name",
+					"last_name", "email",
+					"job_title", "feedback", "created_at", "version", "sentiment_score", "sentiment_label", "score", "cluster_id"}).
+					AddRow(id, firstName, lastName, email, jobTitle, feedback, createdAt, 1, nil, nil, nil, nil))
+			if scenario.expected["email"] == email || scenario.auditErr != nil {
+				mock.ExpectBegin()
+				insert := mock.ExpectExec(regexp.QuoteMeta(queryAuditRead + "(?, ?, ?, ?, ?, ?), (?, ?, ?, ?, ?, ?)"))
+				if scenario.auditErr != nil {
+					insert.WillReturnError(scenario.auditErr)
+					mock.ExpectRollback()
+				} else {
+					insert.WillReturnResult(sqlmock.NewResult(2, 2))
+					mock.ExpectCommit()
+				}
+			}
+
+			masking := NewMasking(logger, db, DefaultMaskingPolicies())
+			srv := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{
+				Resolvers:  &Resolver{logger: logger, db: db, masking: masking},
+				Directives: generated.DirectiveRoot{Constraint: Constraint, HasRole: HasRole},
+			}))
+			srv.Use(masking)
+
+			var resp struct {
+				Node map[string]interface{}
+			}
+			err = client.New(srv).Post(`query($id: ID!) { node(id: $id) { ... on UserFeedback { lastName email } } }`,
+				&resp, client.Var("id", toGlobalID(userFeedbackType, id)), scenario.as)
+			if scenario.expectedErr != "" {
+				assert.ErrorContains(t, err, scenario.expectedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, scenario.expected, resp.Node)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// ErrInvalidDateTime means that a DateTime value is not an RFC3339 string
+var ErrInvalidDateTime = errors.New("DateTime must be an RFC3339 string")
+
+// MarshalDateTime writes the DateTime scalar as an RFC3339 string in UTC
+func MarshalDateTime(t time.Time) graphql.Marshaler {
+	if t.IsZero() {
+		return graphql.Null
+	}
+
+	return graphql.WriterFunc(func(w io.Writer) {
+		_, _ = io.WriteString(w, strconv.Quote(t.UTC().Format(time.RFC3339)))
+	})
+}
+
+// UnmarshalDateTime reads an RFC3339 string with any offset and normalises it to UTC
+func UnmarshalDateTime(v interface{}) (time.Time, error) {
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, ErrInvalidDateTime
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, ErrInvalidDateTime
+	}
+
+	return t.UTC(), nil
+}
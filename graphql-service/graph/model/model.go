@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type User struct {
 	ID        string `json:"id"`
 	FirstName string `json:"firstName"`
@@ -9,3 +11,53 @@ type User struct {
 	Feedback  string `json:"feedback"`
 	CreateAt  string `json:"createAt"`
 }
+
+// FeedbackStats carries the arguments of feedbackStats to the resolvers of its fields,
+// so that only the aggregates a client selects are computed.
+type FeedbackStats struct {
+	Filter  FilterInput
+	GroupBy []FeedbackGroupBy
+}
+
+// FeedbackReply is a response from our team, FeedbackID is the database id of the feedback it answers
+type FeedbackReply struct {
+	ID         string    `json:"id"`
+	FeedbackID string    `json:"feedbackId"`
+	Author     string    `json:"author"`
+	Body       string    `json:"body"`
+	CreateAt   time.Time `json:"createAt"`
+}
+
+func (FeedbackReply) IsNode()            {}
+func (this FeedbackReply) GetID() string { return this.ID }
+
+// Attachment is a file uploaded with a feedback, the file itself is kept in storage under StorageKey
+type Attachment struct {
+	ID          string    `json:"id"`
+	FeedbackID  string    `json:"feedbackId"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"contentType"`
+	Size        int       `json:"size"`
+	StorageKey  string    `json:"-"`
+	CreateAt    time.Time `json:"createAt"`
+}
+
+func (Attachment) IsNode()            {}
+func (this Attachment) GetID() string { return this.ID }
+
+// FeedbackCluster is one group of feedback worded alike, ID is the cluster id its feedback share
+type FeedbackCluster struct {
+	ID   string `json:"id"`
+	Size int    `json:"size"`
+}
+
+// Erasure is the proof that the personal data held on a subject was erased,
+// FeedbackErased is how much feedback this service deleted for it.
+type Erasure struct {
+	ID             string              `json:"id"`
+	SubjectHash    string              `json:"subjectHash"`
+	RequestedBy    string              `json:"requestedBy"`
+	RequestedAt    time.Time           `json:"requestedAt"`
+	Limitations    []ErasureLimitation `json:"limitations"`
+	FeedbackErased int                 `json:"-"`
+}
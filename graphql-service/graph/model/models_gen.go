@@ -2,23 +2,221 @@
 
 package model
 
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+type Node interface {
+	IsNode()
+	GetID() string
+}
+
+type ErasureServiceStatus struct {
+	Service string        `json:"service"`
+	Status  ErasureStatus `json:"status"`
+	// Rows the service deleted, null until it completed
+	ErasedCount *int       `json:"erasedCount,omitempty"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+type FacetCount struct {
+	Value *string `json:"value,omitempty"`
+	Count int     `json:"count"`
+}
+
+type FeedbackFacets struct {
+	// Distinct job titles, most common first
+	JobTitles []*FacetCount `json:"jobTitles"`
+}
+
+// Number of feedback in one group, only the fields of the requested dimensions are set
+type FeedbackGroup struct {
+	JobTitle    *string    `json:"jobTitle,omitempty"`
+	Day         *time.Time `json:"day,omitempty"`
+	Week        *time.Time `json:"week,omitempty"`
+	EmailDomain *string    `json:"emailDomain,omitempty"`
+	Count       int        `json:"count"`
+}
+
+// Missing last names, emails and job titles sort as empty strings, unscored sentiment as 0
+type FeedbackOrder struct {
+	Field     FeedbackOrderField `json:"field"`
+	Direction OrderDirection     `json:"direction"`
+}
+
+type FeedbackReplyInput struct {
+	FeedbackID string `json:"feedbackId"`
+	Author     string `json:"author"`
+	Body       string `json:"body"`
+}
+
 type FilterInput struct {
-	ID        *string `json:"id,omitempty"`
+	ID        *StringFilter `json:"id,omitempty"`
+	FirstName *StringFilter `json:"firstName,omitempty"`
+	LastName  *StringFilter `json:"lastName,omitempty"`
+	Email     *StringFilter `json:"email,omitempty"`
+	JobTitle  *StringFilter `json:"jobTitle,omitempty"`
+	// Feedback created at or after this time
+	CreatedAfter *time.Time `json:"createdAfter,omitempty"`
+	// Feedback created strictly before this time
+	CreatedBefore *time.Time `json:"createdBefore,omitempty"`
+	// Feedback created within a window ending now, e.g. the last 7 days
+	CreatedWithin *TimeWindow `json:"createdWithin,omitempty"`
+	// Feedback that has not been scored yet never matches
+	Sentiment *SentimentFilter `json:"sentiment,omitempty"`
+	Tags      *TagFilter       `json:"tags,omitempty"`
+	And       []*FilterInput   `json:"and,omitempty"`
+	Or        []*FilterInput   `json:"or,omitempty"`
+	Not       *FilterInput     `json:"not,omitempty"`
+}
+
+type NpsPeriod struct {
+	Start time.Time `json:"start"`
+	Score *NpsScore `json:"score"`
+}
+
+type NpsReport struct {
+	Overall *NpsScore `json:"overall"`
+	// Sorted by start, periods without responses are left out
+	Periods []*NpsPeriod `json:"periods"`
+}
+
+// Net promoter score of the feedback that has a score, 9 and 10 are promoters and 0 to 6 detractors
+type NpsScore struct {
+	Responses  int `json:"responses"`
+	Promoters  int `json:"promoters"`
+	Passives   int `json:"passives"`
+	Detractors int `json:"detractors"`
+	// Percentage of promoters minus percentage of detractors, null without responses
+	Nps *float64 `json:"nps,omitempty"`
+}
+
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor,omitempty"`
+	EndCursor       *string `json:"endCursor,omitempty"`
+}
+
+// Tone of the feedback text, scored offline from the words it uses
+type Sentiment struct {
+	// Between -1 (most negative) and 1 (most positive)
+	Score float64        `json:"score"`
+	Label SentimentLabel `json:"label"`
+}
+
+// Bounds are inclusive
+type SentimentFilter struct {
+	In       []SentimentLabel `json:"in,omitempty"`
+	MinScore *float64         `json:"minScore,omitempty"`
+	MaxScore *float64         `json:"maxScore,omitempty"`
+}
+
+// Feedback that is worded like another feedback
+type SimilarFeedback struct {
+	Feedback *UserFeedback `json:"feedback"`
+	// Estimated share of text the two have in common, between 0 and 1
+	Similarity float64 `json:"similarity"`
+}
+
+type StringFilter struct {
+	Eq         *string  `json:"eq,omitempty"`
+	Neq        *string  `json:"neq,omitempty"`
+	In         []string `json:"in,omitempty"`
+	Contains   *string  `json:"contains,omitempty"`
+	StartsWith *string  `json:"startsWith,omitempty"`
+}
+
+// Someone who submitted feedback, other subgraphs can reference it by email
+type Submitter struct {
+	Email    string                  `json:"email"`
+	Feedback *UserFeedbackConnection `json:"feedback"`
+}
+
+func (Submitter) IsEntity() {}
+
+type SubmitterByEmailsInput struct {
+	Email string `json:"Email"`
+}
+
+// Tags are compared case-insensitively
+type TagFilter struct {
+	// Feedback with at least one of the tags
+	Any []string `json:"any,omitempty"`
+	// Feedback with every one of the tags
+	All []string `json:"all,omitempty"`
+}
+
+type TimeWindow struct {
+	Last int      `json:"last"`
+	Unit TimeUnit `json:"unit"`
+}
+
+// Fields left out are not changed, version must match the stored version
+type UpdateUserFeedbackInput struct {
+	ID        string  `json:"id"`
+	Version   int     `json:"version"`
 	FirstName *string `json:"firstName,omitempty"`
 	LastName  *string `json:"lastName,omitempty"`
 	Email     *string `json:"email,omitempty"`
 	JobTitle  *string `json:"jobTitle,omitempty"`
-	CreateAt  *string `json:"createAt,omitempty"`
+	Feedback  *string `json:"feedback,omitempty"`
+	Score     *int    `json:"score,omitempty"`
 }
 
 type UserFeedback struct {
-	ID        *string `json:"id,omitempty"`
+	ID        string  `json:"id"`
 	FirstName *string `json:"firstName,omitempty"`
-	LastName  *string `json:"lastName,omitempty"`
-	Email     *string `json:"email,omitempty"`
-	JobTitle  *string `json:"jobTitle,omitempty"`
-	Feedback  *string `json:"feedback,omitempty"`
-	CreateAt  *string `json:"createAt,omitempty"`
+	// Masked, by default to the initial, unless your role is allowed to see last names
+	LastName *string `json:"lastName,omitempty"`
+	// Masked, by default like j***@doe.com, unless your role is allowed to see emails
+	Email    *string    `json:"email,omitempty"`
+	JobTitle *string    `json:"jobTitle,omitempty"`
+	Feedback *string    `json:"feedback,omitempty"`
+	CreateAt *time.Time `json:"createAt,omitempty"`
+	// Incremented on every change, pass it back when updating or deleting
+	Version int `json:"version"`
+	// Null until the feedback has been scored
+	Sentiment *Sentiment `json:"sentiment,omitempty"`
+	// How likely the submitter is to recommend us, from 0 to 10
+	Score *int `json:"score,omitempty"`
+	// Sorted by name
+	Tags []string `json:"tags"`
+	// Oldest first
+	Replies []*FeedbackReply `json:"replies"`
+	// When our team first replied, null until then
+	FirstReplyAt *time.Time `json:"firstReplyAt,omitempty"`
+	// Seconds from submission until the first reply
+	FirstResponseSeconds *int `json:"firstResponseSeconds,omitempty"`
+	// In the order they were uploaded
+	Attachments []*Attachment `json:"attachments"`
+	// Feedback worded alike share a cluster, null for feedback saved before clustering was backfilled
+	ClusterID *string `json:"clusterId,omitempty"`
+}
+
+func (UserFeedback) IsNode()            {}
+func (this UserFeedback) GetID() string { return this.ID }
+
+func (UserFeedback) IsEntity() {}
+
+type UserFeedbackByIDsInput struct {
+	ID string `json:"ID"`
+}
+
+type UserFeedbackConnection struct {
+	Edges      []*UserFeedbackEdge `json:"edges,omitempty"`
+	PageInfo   *PageInfo           `json:"pageInfo"`
+	TotalCount int                 `json:"totalCount"`
+}
+
+type UserFeedbackEdge struct {
+	Cursor string        `json:"cursor"`
+	Node   *UserFeedback `json:"node,omitempty"`
 }
 
 type UserFeedbackInput struct {
@@ -27,4 +225,422 @@ type UserFeedbackInput struct {
 	Email     string  `json:"email"`
 	JobTitle  *string `json:"jobTitle,omitempty"`
 	Feedback  string  `json:"feedback"`
+	Score     *int    `json:"score,omitempty"`
+	// Only images and PDFs are accepted by default, the size and number of files are limited
+	Attachments []*graphql.Upload `json:"attachments,omitempty"`
+	// Sending the same key again returns the feedback saved the first time instead of saving it twice,
+	// it takes precedence over the Idempotency-Key header. Keys expire after a day by default.
+	IdempotencyKey *string `json:"idempotencyKey,omitempty"`
+}
+
+type UserFeedbackSearchConnection struct {
+	Edges      []*UserFeedbackSearchEdge `json:"edges,omitempty"`
+	PageInfo   *PageInfo                 `json:"pageInfo"`
+	TotalCount int                       `json:"totalCount"`
+}
+
+type UserFeedbackSearchEdge struct {
+	Cursor string        `json:"cursor"`
+	Node   *UserFeedback `json:"node,omitempty"`
+	// bm25 relevance, lower is more relevant
+	Rank float64 `json:"rank"`
+	// HTML of the feedback around the matched terms, the text is escaped and matches are wrapped in <mark></mark>
+	Snippet string `json:"snippet"`
+}
+
+type ErasureLimitation string
+
+const (
+	// Events of the erased feedback may have been published before events were keyed by feedback id.
+	// Compaction never removes them, they stay in the feedback topic until its retention or recreation.
+	ErasureLimitationUnkeyedEvents ErasureLimitation = "UNKEYED_EVENTS"
+)
+
+var AllErasureLimitation = []ErasureLimitation{
+	ErasureLimitationUnkeyedEvents,
+}
+
+func (e ErasureLimitation) IsValid() bool {
+	switch e {
+	case ErasureLimitationUnkeyedEvents:
+		return true
+	}
+	return false
+}
+
+func (e ErasureLimitation) String() string {
+	return string(e)
+}
+
+func (e *ErasureLimitation) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ErasureLimitation(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ErasureLimitation", str)
+	}
+	return nil
+}
+
+func (e ErasureLimitation) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ErasureStatus string
+
+const (
+	// The service hasn't received the erasure yet
+	ErasureStatusPending   ErasureStatus = "PENDING"
+	ErasureStatusCompleted ErasureStatus = "COMPLETED"
+	// The service couldn't be asked, try again later
+	ErasureStatusUnknown ErasureStatus = "UNKNOWN"
+)
+
+var AllErasureStatus = []ErasureStatus{
+	ErasureStatusPending,
+	ErasureStatusCompleted,
+	ErasureStatusUnknown,
+}
+
+func (e ErasureStatus) IsValid() bool {
+	switch e {
+	case ErasureStatusPending, ErasureStatusCompleted, ErasureStatusUnknown:
+		return true
+	}
+	return false
+}
+
+func (e ErasureStatus) String() string {
+	return string(e)
+}
+
+func (e *ErasureStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ErasureStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ErasureStatus", str)
+	}
+	return nil
+}
+
+func (e ErasureStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// Dimensions feedback can be counted by, DAY and WEEK are UTC and weeks start on Monday
+type FeedbackGroupBy string
+
+const (
+	FeedbackGroupByJobTitle    FeedbackGroupBy = "JOB_TITLE"
+	FeedbackGroupByDay         FeedbackGroupBy = "DAY"
+	FeedbackGroupByWeek        FeedbackGroupBy = "WEEK"
+	FeedbackGroupByEmailDomain FeedbackGroupBy = "EMAIL_DOMAIN"
+)
+
+var AllFeedbackGroupBy = []FeedbackGroupBy{
+	FeedbackGroupByJobTitle,
+	FeedbackGroupByDay,
+	FeedbackGroupByWeek,
+	FeedbackGroupByEmailDomain,
+}
+
+func (e FeedbackGroupBy) IsValid() bool {
+	switch e {
+	case FeedbackGroupByJobTitle, FeedbackGroupByDay, FeedbackGroupByWeek, FeedbackGroupByEmailDomain:
+		return true
+	}
+	return false
+}
+
+func (e FeedbackGroupBy) String() string {
+	return string(e)
+}
+
+func (e *FeedbackGroupBy) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = FeedbackGroupBy(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid FeedbackGroupBy", str)
+	}
+	return nil
+}
+
+func (e FeedbackGroupBy) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// Fields feedback can be sorted by, ties are always broken by id
+type FeedbackOrderField string
+
+const (
+	FeedbackOrderFieldCreateAt  FeedbackOrderField = "CREATE_AT"
+	FeedbackOrderFieldLastName  FeedbackOrderField = "LAST_NAME"
+	FeedbackOrderFieldEmail     FeedbackOrderField = "EMAIL"
+	FeedbackOrderFieldJobTitle  FeedbackOrderField = "JOB_TITLE"
+	FeedbackOrderFieldSentiment FeedbackOrderField = "SENTIMENT"
+)
+
+var AllFeedbackOrderField = []FeedbackOrderField{
+	FeedbackOrderFieldCreateAt,
+	FeedbackOrderFieldLastName,
+	FeedbackOrderFieldEmail,
+	FeedbackOrderFieldJobTitle,
+	FeedbackOrderFieldSentiment,
+}
+
+func (e FeedbackOrderField) IsValid() bool {
+	switch e {
+	case FeedbackOrderFieldCreateAt, FeedbackOrderFieldLastName, FeedbackOrderFieldEmail, FeedbackOrderFieldJobTitle, FeedbackOrderFieldSentiment:
+		return true
+	}
+	return false
+}
+
+func (e FeedbackOrderField) String() string {
+	return string(e)
+}
+
+func (e *FeedbackOrderField) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = FeedbackOrderField(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid FeedbackOrderField", str)
+	}
+	return nil
+}
+
+func (e FeedbackOrderField) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type OrderDirection string
+
+const (
+	OrderDirectionAsc  OrderDirection = "ASC"
+	OrderDirectionDesc OrderDirection = "DESC"
+)
+
+var AllOrderDirection = []OrderDirection{
+	OrderDirectionAsc,
+	OrderDirectionDesc,
+}
+
+func (e OrderDirection) IsValid() bool {
+	switch e {
+	case OrderDirectionAsc, OrderDirectionDesc:
+		return true
+	}
+	return false
+}
+
+func (e OrderDirection) String() string {
+	return string(e)
+}
+
+func (e *OrderDirection) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = OrderDirection(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid OrderDirection", str)
+	}
+	return nil
+}
+
+func (e OrderDirection) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// Periods are UTC, weeks start on Monday
+type ReportInterval string
+
+const (
+	ReportIntervalDay   ReportInterval = "DAY"
+	ReportIntervalWeek  ReportInterval = "WEEK"
+	ReportIntervalMonth ReportInterval = "MONTH"
+)
+
+var AllReportInterval = []ReportInterval{
+	ReportIntervalDay,
+	ReportIntervalWeek,
+	ReportIntervalMonth,
+}
+
+func (e ReportInterval) IsValid() bool {
+	switch e {
+	case ReportIntervalDay, ReportIntervalWeek, ReportIntervalMonth:
+		return true
+	}
+	return false
+}
+
+func (e ReportInterval) String() string {
+	return string(e)
+}
+
+func (e *ReportInterval) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ReportInterval(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ReportInterval", str)
+	}
+	return nil
+}
+
+func (e ReportInterval) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// Roles granted by the roles claim of the bearer token
+type Role string
+
+const (
+	// Reads and changes everything
+	RoleAdmin Role = "ADMIN"
+	// Reads feedback with personal data masked
+	RoleAnalyst Role = "ANALYST"
+)
+
+var AllRole = []Role{
+	RoleAdmin,
+	RoleAnalyst,
+}
+
+func (e Role) IsValid() bool {
+	switch e {
+	case RoleAdmin, RoleAnalyst:
+		return true
+	}
+	return false
+}
+
+func (e Role) String() string {
+	return string(e)
+}
+
+func (e *Role) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Role(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Role", str)
+	}
+	return nil
+}
+
+func (e Role) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type SentimentLabel string
+
+const (
+	SentimentLabelPositive SentimentLabel = "POSITIVE"
+	SentimentLabelNeutral  SentimentLabel = "NEUTRAL"
+	SentimentLabelNegative SentimentLabel = "NEGATIVE"
+)
+
+var AllSentimentLabel = []SentimentLabel{
+	SentimentLabelPositive,
+	SentimentLabelNeutral,
+	SentimentLabelNegative,
+}
+
+func (e SentimentLabel) IsValid() bool {
+	switch e {
+	case SentimentLabelPositive, SentimentLabelNeutral, SentimentLabelNegative:
+		return true
+	}
+	return false
+}
+
+func (e SentimentLabel) String() string {
+	return string(e)
+}
+
+func (e *SentimentLabel) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SentimentLabel(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SentimentLabel", str)
+	}
+	return nil
+}
+
+func (e SentimentLabel) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type TimeUnit string
+
+const (
+	TimeUnitHour  TimeUnit = "HOUR"
+	TimeUnitDay   TimeUnit = "DAY"
+	TimeUnitWeek  TimeUnit = "WEEK"
+	TimeUnitMonth TimeUnit = "MONTH"
+)
+
+var AllTimeUnit = []TimeUnit{
+	TimeUnitHour,
+	TimeUnitDay,
+	TimeUnitWeek,
+	TimeUnitMonth,
+}
+
+func (e TimeUnit) IsValid() bool {
+	switch e {
+	case TimeUnitHour, TimeUnitDay, TimeUnitWeek, TimeUnitMonth:
+		return true
+	}
+	return false
+}
+
+func (e TimeUnit) String() string {
+	return string(e)
+}
+
+func (e *TimeUnit) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = TimeUnit(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid TimeUnit", str)
+	}
+	return nil
+}
+
+func (e TimeUnit) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
 }
@@ -0,0 +1,72 @@
+package graph
+
+import (
+	"database/sql"
+	"math"
+	"time"
+
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+)
+
+// npsCounts splits the scored feedback of a period into promoters, passives and detractors
+var npsCounts = `, COUNT(*), SUM(score >= 9), SUM(score BETWEEN 7 AND 8), SUM(score <= 6) FROM user_feedback`
+
+// intervalColumns is the sql that truncates created_at to the start of its period
+var intervalColumns = map[model.ReportInterval]string{
+	model.ReportIntervalDay:   groupColumns[model.FeedbackGroupByDay],
+	model.ReportIntervalWeek:  groupColumns[model.FeedbackGroupByWeek],
+	model.ReportIntervalMonth: "strftime('%Y-%m-01T00:00:00Z', created_at)",
+}
+
+// npsReport computes the net promoter score of the tenant's feedback matching the filter per period
+// and overall, feedback without a score is left out.
+func npsReport(db *sql.DB, tenant string, filter model.FilterInput, interval model.ReportInterval) (*model.NpsReport, error) {
+	conditions, args, err := filterConditions(tenant, filter)
+	if err != nil {
+		return nil, err
+	}
+	conditions = append(conditions, "score IS NOT NULL")
+
+	column := intervalColumns[interval]
+	rows, err := db.Query("SELECT "+column+npsCounts+where(conditions)+" GROUP BY "+column+" ORDER BY "+column, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := &model.NpsReport{Overall: &model.NpsScore{}, Periods: []*model.NpsPeriod{}}
+	for rows.Next() {
+		var start string
+		score := &model.NpsScore{}
+		if err := rows.Scan(&start, &score.Responses, &score.Promoters, &score.Passives, &score.Detractors); err != nil {
+			return nil, err
+		}
+		t, err := time.Parse(time.RFC3339, start)
+		if err != nil {
+			return nil, err
+		}
+		score.Nps = nps(score)
+		report.Periods = append(report.Periods, &model.NpsPeriod{Start: t, Score: score})
+
+		report.Overall.Responses += score.Responses
+		report.Overall.Promoters += score.Promoters
+		report.Overall.Passives += score.Passives
+		report.Overall.Detractors += score.Detractors
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	report.Overall.Nps = nps(report.Overall)
+
+	return report, nil
+}
+
+// nps is the percentage of promoters minus the percentage of detractors to one decimal
+func nps(score *model.NpsScore) *float64 {
+	if score.Responses == 0 {
+		return nil
+	}
+
+	n := math.Round(float64(score.Promoters-score.Detractors)*1000/float64(score.Responses)) / 10
+	return &n
+}
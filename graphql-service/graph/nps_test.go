@@ -0,0 +1,94 @@
+package graph
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryResolverNpsReport(t *testing.T) {
+	may, june := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	nps := func(f float64) *float64 { return &f }
+
+	scenarios := []struct {
+		name           string
+		filter         *model.FilterInput
+		mockDB         *mockDB
+		expectedReport *model.NpsReport
+		expectedErr    error
+	}{
+		{
+			name:   "monthly with a filter",
+			filter: &model.FilterInput{JobTitle: &model.StringFilter{Eq: &jobTitle}},
+			mockDB: func() *mockDB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT "+intervalColumns[model.ReportIntervalMonth]+npsCounts+
+					" WHERE tenant = ? AND job_title = ? AND score IS NOT NULL GROUP BY")).
+					WithArgs(DefaultTenant, jobTitle).
+					WillReturnRows(sqlmock.NewRows([]string{"start", "responses", "promoters", "passives", "detractors"}).
+						AddRow("2023-05-01T00:00:00Z", 3, 2, 0, 1).
+						AddRow("2023-06-01T00:00:00Z", 1, 0, 0, 1))
+				return &mockDB{
+					db:   db,
+					mock: mock,
+				}
+			}(),
+			expectedReport: &model.NpsReport{
+				Overall: &model.NpsScore{Responses: 4, Promoters: 2, Detractors: 2, Nps: nps(0)},
+				Periods: []*model.NpsPeriod{
+					{Start: may, Score: &model.NpsScore{Responses: 3, Promoters: 2, Detractors: 1, Nps: nps(33.3)}},
+					{Start: june, Score: &model.NpsScore{Responses: 1, Detractors: 1, Nps: nps(-100)}},
+				},
+			},
+		},
+		{
+			name: "no scored feedback",
+			mockDB: func() *mockDB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(regexp.QuoteMeta(npsCounts + " WHERE tenant = ? AND score IS NOT NULL")).
+					WithArgs(DefaultTenant).
+					WillReturnRows(sqlmock.NewRows([]string{"start", "responses", "promoters", "passives", "detractors"}))
+				return &mockDB{
+					db:   db,
+					mock: mock,
+				}
+			}(),
+			expectedReport: &model.NpsReport{Overall: &model.NpsScore{}, Periods: []*model.NpsPeriod{}},
+		},
+		{
+			name: "db failure",
+			mockDB: func() *mockDB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(regexp.QuoteMeta(npsCounts)).WillReturnError(errFailedDBOperation)
+				return &mockDB{
+					db:   db,
+					mock: mock,
+				}
+			}(),
+			expectedErr: errFailedDBOperation,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			resolver := &queryResolver{
+				Resolver: &Resolver{
+					logger: logger,
+					db:     scenario.mockDB.db,
+				},
+			}
+			report, err := resolver.NpsReport(context.Background(), scenario.filter, model.ReportIntervalMonth)
+			assert.Equal(t, scenario.expectedErr, err)
+			assert.Equal(t, scenario.expectedReport, report)
+			assert.NoError(t, scenario.mockDB.mock.ExpectationsWereMet())
+		})
+	}
+}
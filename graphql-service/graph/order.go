@@ -0,0 +1,159 @@
+package graph
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+)
+
+// ErrInvalidOrder means that orderBy names the same field more than once
+var ErrInvalidOrder = errors.New("orderBy can only use each field once")
+
+// orderColumns maps the sortable fields to the sql they are sorted by, nullable
+// columns are compared as empty strings so that keyset conditions never meet a NULL.
+// Cursors keep values as text, arg converts them back for columns that are not text.
+var orderColumns = map[model.FeedbackOrderField]struct {
+	column string
+	value  func(fb *model.UserFeedback) string
+	arg    func(v string) (interface{}, error)
+}{
+	model.FeedbackOrderFieldCreateAt: {
+		column: "created_at",
+		value: func(fb *model.UserFeedback) string {
+			if fb.CreateAt == nil {
+				return ""
+			}
+			return formatDateTime(*fb.CreateAt)
+		},
+	},
+	model.FeedbackOrderFieldLastName: {
+		column: "COALESCE(last_name, '')",
+		value:  func(fb *model.UserFeedback) string { return stringValue(fb.LastName) },
+	},
+	model.FeedbackOrderFieldEmail: {
+		column: "COALESCE(email, '')",
+		value:  func(fb *model.UserFeedback) string { return stringValue(fb.Email) },
+	},
+	model.FeedbackOrderFieldJobTitle: {
+		column: "COALESCE(job_title, '')",
+		value:  func(fb *model.UserFeedback) string { return stringValue(fb.JobTitle) },
+	},
+	model.FeedbackOrderFieldSentiment: {
+		column: "COALESCE(sentiment_score, 0)",
+		value: func(fb *model.UserFeedback) string {
+			if fb.Sentiment == nil {
+				return "0"
+			}
+			return strconv.FormatFloat(fb.Sentiment.Score, 'g', -1, 64)
+		},
+		arg: func(v string) (interface{}, error) {
+			return strconv.ParseFloat(v, 64)
+		},
+	},
+}
+
+// defaultOrder is used when the client doesn't ask for an order
+var defaultOrder = []*model.FeedbackOrder{{Field: model.FeedbackOrderFieldCreateAt, Direction: model.OrderDirectionAsc}}
+
+// ordering is the list of fields feedback is sorted by, id is always added as the last tiebreaker
+type ordering []*model.FeedbackOrder
+
+func newOrdering(orderBy []*model.FeedbackOrder) (ordering, error) {
+	if len(orderBy) == 0 {
+		return defaultOrder, nil
+	}
+
+	seen := make(map[model.FeedbackOrderField]bool, len(orderBy))
+	for _, order := range orderBy {
+		if seen[order.Field] {
+			return nil, ErrInvalidOrder
+		}
+		seen[order.Field] = true
+	}
+
+	return orderBy, nil
+}
+
+// key identifies the ordering inside cursors
+func (o ordering) key() string {
+	keys := make([]string, 0, len(o))
+	for _, order := range o {
+		keys = append(keys, order.Field.String()+":"+order.Direction.String())
+	}
+
+	return strings.Join(keys, ",")
+}
+
+// orderBy returns the ORDER BY clause, backward pages are read in reverse and flipped afterwards
+func (o ordering) orderBy(backward bool) string {
+	columns := make([]string, 0, len(o)+1)
+	for _, order := range o {
+		columns = append(columns, orderColumns[order.Field].column+direction(order.Direction == model.OrderDirectionDesc, backward))
+	}
+	columns = append(columns, "id"+direction(false, backward))
+
+	return " ORDER BY " + strings.Join(columns, ", ") + " LIMIT ?"
+}
+
+// keyset returns the condition for rows that come after the cursor in the read direction,
+// it expands to (a > ? OR (a = ? AND (b > ? OR (b = ? AND id > ?)))) with < for descending fields.
+func (o ordering) keyset(c *cursor, backward bool) (sqlFilter, error) {
+	if c.Order != o.key() || len(c.Values) != len(o) {
+		return sqlFilter{}, ErrInvalidCursor
+	}
+
+	expr := "id " + comparison(false, backward) + " ?"
+	args := []interface{}{c.ID}
+	for i := len(o) - 1; i >= 0; i-- {
+		column := orderColumns[o[i].Field]
+		var value interface{} = c.Values[i]
+		if column.arg != nil {
+			v, err := column.arg(c.Values[i])
+			if err != nil {
+				return sqlFilter{}, ErrInvalidCursor
+			}
+			value = v
+		}
+		expr = "(" + column.column + " " + comparison(o[i].Direction == model.OrderDirectionDesc, backward) + " ? OR (" +
+			column.column + " = ? AND " + expr + "))"
+		args = append([]interface{}{value, value}, args...)
+	}
+
+	return sqlFilter{expr: expr, args: args}, nil
+}
+
+// cursorOf returns the position of the feedback in this ordering
+func (o ordering) cursorOf(fb *model.UserFeedback) cursor {
+	values := make([]string, 0, len(o))
+	for _, order := range o {
+		values = append(values, orderColumns[order.Field].value(fb))
+	}
+
+	return cursor{Values: values, Order: o.key(), ID: fb.ID}
+}
+
+func direction(desc, backward bool) string {
+	if desc != backward {
+		return " DESC"
+	}
+
+	return ""
+}
+
+func comparison(desc, backward bool) string {
+	if desc != backward {
+		return "<"
+	}
+
+	return ">"
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
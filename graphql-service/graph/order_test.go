@@ -0,0 +1,112 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrdering(t *testing.T) {
+	byLastNameThenNewest := []*model.FeedbackOrder{
+		{Field: model.FeedbackOrderFieldLastName, Direction: model.OrderDirectionAsc},
+		{Field: model.FeedbackOrderFieldCreateAt, Direction: model.OrderDirectionDesc},
+	}
+	c := &cursor{Values: []string{"Doe", "2023-05-01T10:00:00Z"}, Order: "LAST_NAME:ASC,CREATE_AT:DESC", ID: id}
+
+	scenarios := []struct {
+		name           string
+		orderBy        []*model.FeedbackOrder
+		backward       bool
+		expectedOrder  string
+		expectedKeyset string
+		expectedArgs   []interface{}
+	}{
+		{
+			name:           "default",
+			expectedOrder:  " ORDER BY created_at, id LIMIT ?",
+			expectedKeyset: "(created_at > ? OR (created_at = ? AND id > ?))",
+		},
+		{
+			name:           "default backward",
+			backward:       true,
+			expectedOrder:  " ORDER BY created_at DESC, id DESC LIMIT ?",
+			expectedKeyset: "(created_at < ? OR (created_at = ? AND id < ?))",
+		},
+		{
+			name:          "mixed directions",
+			orderBy:       byLastNameThenNewest,
+			expectedOrder: " ORDER BY COALESCE(last_name, ''), created_at DESC, id LIMIT ?",
+			expectedKeyset: "(COALESCE(last_name, '') > ? OR (COALESCE(last_name, '') = ? AND " +
+				"(created_at < ? OR (created_at = ? AND id > ?))))",
+			expectedArgs: []interface{}{"Doe", "Doe", "2023-05-01T10:00:00Z", "2023-05-01T10:00:00Z", id},
+		},
+		{
+			name:          "mixed directions backward",
+			orderBy:       byLastNameThenNewest,
+			backward:      true,
+			expectedOrder: " ORDER BY COALESCE(last_name, '') DESC, created_at, id DESC LIMIT ?",
+			expectedKeyset: "(COALESCE(last_name, '') < ? OR (COALESCE(last_name, '') = ? AND " +
+				"(created_at > ? OR (created_at = ? AND id < ?))))",
+			expectedArgs: []interface{}{"Doe", "Doe", "2023-05-01T10:00:00Z", "2023-05-01T10:00:00Z", id},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			o, err := newOrdering(scenario.orderBy)
+			assert.NoError(t, err)
+			assert.Equal(t, scenario.expectedOrder, o.orderBy(scenario.backward))
+
+			cur := o.cursorOf(&model.UserFeedback{ID: id, LastName: &lastName, CreateAt: &createdAt})
+			if scenario.expectedArgs != nil {
+				cur = *c
+			}
+			keyset, err := o.keyset(&cur, scenario.backward)
+			assert.NoError(t, err)
+			assert.Equal(t, scenario.expectedKeyset, keyset.expr)
+			if scenario.expectedArgs != nil {
+				assert.Equal(t, scenario.expectedArgs, keyset.args)
+			}
+		})
+	}
+}
+
+func TestOrderingRejects(t *testing.T) {
+	_, err := newOrdering([]*model.FeedbackOrder{
+		{Field: model.FeedbackOrderFieldEmail, Direction: model.OrderDirectionAsc},
+		{Field: model.FeedbackOrderFieldEmail, Direction: model.OrderDirectionDesc},
+	})
+	assert.Equal(t, ErrInvalidOrder, err)
+
+	// a cursor only works with the ordering it was returned for
+	byEmail := []*model.FeedbackOrder{{Field: model.FeedbackOrderFieldEmail, Direction: model.OrderDirectionAsc}}
+	after := encodeCursor(ordering(defaultOrder).cursorOf(&model.UserFeedback{ID: id, CreateAt: &createdAt}))
+	_, err = newFeedbackPageQuery(byEmail, nil, &after, nil, nil)
+	assert.Equal(t, ErrInvalidCursor, err)
+
+	page, err := newFeedbackPageQuery(nil, nil, &after, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{formatDateTime(createdAt)}, page.cursor.Values)
+
+	_, err = ordering(byEmail).keyset(&cursor{Order: "EMAIL:ASC", ID: id}, false)
+	assert.Equal(t, ErrInvalidCursor, err)
+
+	bySentiment := ordering{{Field: model.FeedbackOrderFieldSentiment, Direction: model.OrderDirectionDesc}}
+	_, err = bySentiment.keyset(&cursor{Values: []string{"high"}, Order: "SENTIMENT:DESC", ID: id}, false)
+	assert.Equal(t, ErrInvalidCursor, err)
+}
+
+func TestOrderingBySentiment(t *testing.T) {
+	bySentiment := ordering{{Field: model.FeedbackOrderFieldSentiment, Direction: model.OrderDirectionDesc}}
+
+	// scores are bound as numbers, the COALESCE expression has no affinity so text would never compare equal
+	c := bySentiment.cursorOf(&model.UserFeedback{ID: id, Sentiment: &model.Sentiment{Score: -0.25}})
+	keyset, err := bySentiment.keyset(&c, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "(COALESCE(sentiment_score, 0) < ? OR (COALESCE(sentiment_score, 0) = ? AND id > ?))", keyset.expr)
+	assert.Equal(t, []interface{}{-0.25, -0.25, id}, keyset.args)
+
+	unscored := bySentiment.cursorOf(&model.UserFeedback{ID: id})
+	assert.Equal(t, []string{"0"}, unscored.Values)
+}
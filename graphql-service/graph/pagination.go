@@ -0,0 +1,210 @@
+package graph
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+)
+
+const (
+	// defaultPageSize is used when the client asks for neither first nor last
+	defaultPageSize = 20
+	// maxPageSize caps the number of edges returned in one page
+	maxPageSize = 100
+
+	userFeedbackType = "UserFeedback"
+)
+
+var (
+	// ErrInvalidCursor means that the after or before cursor couldn't be decoded
+	ErrInvalidCursor = errors.New("invalid cursor")
+
+	// ErrInvalidPageArguments means that first and last were used together
+	ErrInvalidPageArguments = errors.New("first and last can not be used together")
+
+	// ErrInvalidPageSize means that first or last is negative or above the maximum page size
+	ErrInvalidPageSize = errors.New("page size must be between 0 and 100")
+
+	// ErrInvalidGlobalID means that the node id couldn't be decoded
+	ErrInvalidGlobalID = errors.New("invalid global id")
+)
+
+// cursor is the position of an edge in the keyset ordering,
+// it is handed to clients as an opaque base64 string.
+type cursor struct {
+	Values []string `json:"v,omitempty"`
+	Order  string   `json:"o,omitempty"`
+	Rank   float64  `json:"r,omitempty"`
+	ID     string   `json:"i"`
+}
+
+// pageQuery describes which slice of the keyset ordering to fetch
+type pageQuery struct {
+	limit    int
+	backward bool
+	cursor   *cursor
+	order    ordering
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.URLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	data, err := base64.URLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	c := &cursor{}
+	if err := json.Unmarshal(data, c); err != nil || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// toGlobalID builds a Relay global id out of a type name and its database id
+func toGlobalID(typeName, id string) string {
+	return base64.StdEncoding.EncodeToString([]byte(typeName + ":" + id))
+}
+
+// fromGlobalID splits a Relay global id into its type name and database id
+func fromGlobalID(globalID string) (string, string, error) {
+	data, err := base64.StdEncoding.DecodeString(globalID)
+	if err != nil {
+		return "", "", ErrInvalidGlobalID
+	}
+
+	typeName, id, found := strings.Cut(string(data), ":")
+	if !found || typeName == "" || id == "" {
+		return "", "", ErrInvalidGlobalID
+	}
+
+	return typeName, id, nil
+}
+
+// feedbackID returns the database id of a UserFeedback global id
+func feedbackID(globalID string) (string, error) {
+	typeName, id, err := fromGlobalID(globalID)
+	if err != nil {
+		return "", err
+	}
+	if typeName != userFeedbackType {
+		return "", ErrInvalidGlobalID
+	}
+
+	return id, nil
+}
+
+// pageSize validates the size of a list that is not a connection, defaultSize is used when first is not given
+func pageSize(first *int, defaultSize int) (int, error) {
+	if first == nil {
+		return defaultSize, nil
+	}
+	if *first < 0 || *first > maxPageSize {
+		return 0, ErrInvalidPageSize
+	}
+
+	return *first, nil
+}
+
+// newPageQuery validates the Relay connection arguments
+func newPageQuery(first *int, after *string, last *int, before *string) (pageQuery, error) {
+	page := pageQuery{limit: defaultPageSize}
+	if first != nil && last != nil {
+		return page, ErrInvalidPageArguments
+	}
+
+	var err error
+	switch {
+	case last != nil || (first == nil && before != nil):
+		page.backward = true
+		if last != nil {
+			page.limit = *last
+		}
+		if before != nil {
+			page.cursor, err = decodeCursor(*before)
+		}
+	default:
+		if first != nil {
+			page.limit = *first
+		}
+		if after != nil {
+			page.cursor, err = decodeCursor(*after)
+		}
+	}
+	if err != nil {
+		return page, err
+	}
+
+	if page.limit < 0 || page.limit > maxPageSize {
+		return page, ErrInvalidPageSize
+	}
+
+	return page, nil
+}
+
+// newFeedbackPageQuery validates the Relay connection arguments together with the order they page through
+func newFeedbackPageQuery(orderBy []*model.FeedbackOrder, first *int, after *string, last *int, before *string) (pageQuery, error) {
+	page, err := newPageQuery(first, after, last, before)
+	if err != nil {
+		return page, err
+	}
+
+	page.order, err = newOrdering(orderBy)
+	if err != nil {
+		return page, err
+	}
+	if page.cursor != nil && page.cursor.Order != page.order.key() {
+		return page, ErrInvalidCursor
+	}
+
+	return page, nil
+}
+
+// newUserFeedbackConnection trims the extra row fetched to detect more pages
+// and wraps the remaining rows into edges.
+func newUserFeedbackConnection(feedbacks []*model.UserFeedback, page pageQuery, totalCount int) *model.UserFeedbackConnection {
+	hasMore := len(feedbacks) > page.limit
+	if hasMore {
+		feedbacks = feedbacks[:page.limit]
+	}
+
+	if page.backward {
+		for i, j := 0, len(feedbacks)-1; i < j; i, j = i+1, j-1 {
+			feedbacks[i], feedbacks[j] = feedbacks[j], feedbacks[i]
+		}
+	}
+
+	edges := make([]*model.UserFeedbackEdge, 0, len(feedbacks))
+	for _, fb := range feedbacks {
+		edges = append(edges, &model.UserFeedbackEdge{
+			Cursor: encodeCursor(page.order.cursorOf(fb)),
+			Node:   fb,
+		})
+	}
+
+	pageInfo := &model.PageInfo{
+		HasNextPage:     hasMore,
+		HasPreviousPage: page.cursor != nil,
+	}
+	if page.backward {
+		pageInfo.HasNextPage, pageInfo.HasPreviousPage = page.cursor != nil, hasMore
+	}
+
+	if len(edges) > 0 {
+		pageInfo.StartCursor = &edges[0].Cursor
+		pageInfo.EndCursor = &edges[len(edges)-1].Cursor
+	}
+
+	return &model.UserFeedbackConnection{
+		Edges:      edges,
+		PageInfo:   pageInfo,
+		TotalCount: totalCount,
+	}
+}
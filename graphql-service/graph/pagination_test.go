@@ -0,0 +1,108 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPageQuery(t *testing.T) {
+	validCursor := encodeCursor(cursor{Values: []string{formatDateTime(createdAt)}, ID: id})
+	one, negative := 1, -1
+	invalid := "not a cursor"
+
+	scenarios := []struct {
+		name        string
+		first       *int
+		after       *string
+		last        *int
+		before      *string
+		out         pageQuery
+		expectedErr error
+	}{
+		{
+			name: "defaults to the first page",
+			out:  pageQuery{limit: defaultPageSize},
+		},
+		{
+			name:  "forward page after cursor",
+			first: &one,
+			after: &validCursor,
+			out: pageQuery{
+				limit:  1,
+				cursor: &cursor{Values: []string{formatDateTime(createdAt)}, ID: id},
+			},
+		},
+		{
+			name:   "backward page before cursor",
+			last:   &one,
+			before: &validCursor,
+			out: pageQuery{
+				limit:    1,
+				backward: true,
+				cursor:   &cursor{Values: []string{formatDateTime(createdAt)}, ID: id},
+			},
+		},
+		{
+			name:        "first and last together",
+			first:       &one,
+			last:        &one,
+			out:         pageQuery{limit: defaultPageSize},
+			expectedErr: ErrInvalidPageArguments,
+		},
+		{
+			name:        "negative page size",
+			first:       &negative,
+			out:         pageQuery{limit: negative},
+			expectedErr: ErrInvalidPageSize,
+		},
+		{
+			name:        "invalid cursor",
+			after:       &invalid,
+			out:         pageQuery{limit: defaultPageSize},
+			expectedErr: ErrInvalidCursor,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			page, err := newPageQuery(scenario.first, scenario.after, scenario.last, scenario.before)
+			assert.Equal(t, scenario.expectedErr, err)
+			assert.Equal(t, scenario.out, page)
+		})
+	}
+}
+
+func TestNewUserFeedbackConnection(t *testing.T) {
+	feedbacks := func() []*model.UserFeedback {
+		return []*model.UserFeedback{
+			{ID: "3", CreateAt: &createdAt},
+			{ID: "2", CreateAt: &createdAt},
+			{ID: "1", CreateAt: &createdAt},
+		}
+	}
+
+	conn := newUserFeedbackConnection(feedbacks(), pageQuery{limit: 2, backward: true, order: defaultOrder}, 3)
+	assert.Len(t, conn.Edges, 2)
+	assert.Equal(t, "2", conn.Edges[0].Node.ID)
+	assert.Equal(t, "3", conn.Edges[1].Node.ID)
+	assert.True(t, conn.PageInfo.HasPreviousPage)
+	assert.False(t, conn.PageInfo.HasNextPage)
+	assert.Equal(t, conn.Edges[0].Cursor, *conn.PageInfo.StartCursor)
+	assert.Equal(t, 3, conn.TotalCount)
+
+	c, err := decodeCursor(*conn.PageInfo.EndCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, &cursor{Values: []string{formatDateTime(createdAt)}, Order: "CREATE_AT:ASC", ID: "3"}, c)
+}
+
+func TestGlobalID(t *testing.T) {
+	typeName, dbID, err := fromGlobalID(toGlobalID(userFeedbackType, id))
+	assert.NoError(t, err)
+	assert.Equal(t, userFeedbackType, typeName)
+	assert.Equal(t, id, dbID)
+
+	_, _, err = fromGlobalID("VXNlckZlZWRiYWNr")
+	assert.Equal(t, ErrInvalidGlobalID, err)
+}
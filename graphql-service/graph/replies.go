@@ -0,0 +1,125 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+const feedbackReplyType = "FeedbackReply"
+
+var (
+	querySaveReply     = `INSERT INTO feedback_replies (id, feedback_id, author, body, created_at) VALUES (?, ?, ?, ?, ?)`
+	queryCountReplies  = `SELECT COUNT(*) FROM feedback_replies WHERE feedback_id = ?`
+	queryGetReplyByID  = `SELECT id, feedback_id, author, body, created_at FROM feedback_replies WHERE id = ?`
+	queryGetRepliesIn  = `SELECT id, feedback_id, author, body, created_at FROM feedback_replies WHERE feedback_id IN `
+	orderRepliesOldest = ` ORDER BY created_at, id`
+)
+
+// firstResponseTimes records how long feedback waited for its first reply
+var firstResponseTimes, _ = meter.Float64Histogram(
+	"graphql.feedback.first_response_time",
+	metric.WithUnit("s"),
+	metric.WithDescription("seconds from submitting a feedback until its first reply"),
+)
+
+// firstReply returns the oldest reply to the feedback, or nil when nobody replied yet
+func (r *Resolver) firstReply(ctx context.Context, feedback *model.UserFeedback) (*model.FeedbackReply, error) {
+	replies, err := r.loaders(ctx).RepliesByFeedbackID.Load(ctx, feedback.ID)
+	if err != nil {
+		r.logger.Error("failed to load replies", zap.String("id", feedback.ID), zap.Error(err))
+		return nil, err
+	}
+	if len(replies) == 0 {
+		return nil, nil
+	}
+
+	return replies[0], nil
+}
+
+// saveReply stores the reply to the tenant's feedback and returns the feedback it answers and whether it is the first reply
+func saveReply(db *sql.DB, tenant string, reply *model.FeedbackReply) (*model.UserFeedback, bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	feedback, err := getUserByID(tx, tenant, reply.FeedbackID)
+	if err == sql.ErrNoRows {
+		return nil, false, ErrFeedbackNotFound
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var replies int
+	if err := tx.QueryRow(queryCountReplies, reply.FeedbackID).Scan(&replies); err != nil {
+		return nil, false, err
+	}
+
+	_, err = tx.Exec(querySaveReply, reply.ID, reply.FeedbackID, reply.Author, reply.Body, formatDateTime(reply.CreateAt))
+	if err != nil {
+		return nil, false, err
+	}
+
+	return feedback, replies == 0, tx.Commit()
+}
+
+// getReplyByID returns sql.ErrNoRows when there is no reply with the given id
+func getReplyByID(db *sql.DB, id string) (*model.FeedbackReply, error) {
+	rows, err := db.Query(queryGetReplyByID, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	replies, err := scanReplies(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(replies) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return replies[0], nil
+}
+
+// getRepliesByFeedbackIDs fetches the replies of every feedback in a single query, oldest first,
+// feedback without replies is left out of the result.
+func getRepliesByFeedbackIDs(db *sql.DB, ids []string) (map[string][]*model.FeedbackReply, error) {
+	rows, err := db.Query(queryGetRepliesIn+placeholders(len(ids))+orderRepliesOldest, stringArgs(ids)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	replies, err := scanReplies(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	byFeedback := make(map[string][]*model.FeedbackReply, len(ids))
+	for _, reply := range replies {
+		byFeedback[reply.FeedbackID] = append(byFeedback[reply.FeedbackID], reply)
+	}
+
+	return byFeedback, nil
+}
+
+func scanReplies(rows *sql.Rows) ([]*model.FeedbackReply, error) {
+	var replies []*model.FeedbackReply
+	for rows.Next() {
+		reply := &model.FeedbackReply{}
+		err := rows.Scan(&reply.ID, &reply.FeedbackID, &reply.Author, &reply.Body, &reply.CreateAt)
+		if err != nil {
+			return nil, err
+		}
+		replies = append(replies, reply)
+	}
+
+	return replies, rows.Err()
+}
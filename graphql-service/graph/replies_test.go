@@ -0,0 +1,179 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMutationResolverReplyToFeedback(t *testing.T) {
+	input := model.FeedbackReplyInput{
+		FeedbackID: toGlobalID(userFeedbackType, id),
+		Author:     "support@sigist.com",
+		Body:       "Thanks, we are looking into it",
+	}
+
+	scenarios := []struct {
+		name          string
+		in            model.FeedbackReplyInput
+		mockDB        *mockDB
+		expectedFirst bool
+		expectedErr   error
+	}{
+		{
+			name: "first reply",
+			in:   input,
+			mockDB: func() *mockDB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryGetUserByID)).WithArgs(DefaultTenant, id).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "first_name",
+						"last_name", "email",
+						"job_title", "feedback", "created_at", "version", "sentiment_score", "sentiment_label", "score", "cluster_id"}).AddRow(
+						id, firstName, lastName, email, jobTitle, feedback, createdAt, 1, nil, nil, nil, nil))
+				mock.ExpectQuery(regexp.QuoteMeta(queryCountReplies)).WithArgs(id).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				mock.ExpectExec(regexp.QuoteMeta(querySaveReply)).
+					WithArgs(sqlmock.AnyArg(), id, input.Author, input.Body, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				return &mockDB{
+					db:   db,
+					mock: mock,
+				}
+			}(),
+			expectedFirst: true,
+		},
+		{
+			name: "missing feedback",
+			in:   input,
+			mockDB: func() *mockDB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryGetUserByID)).WithArgs(DefaultTenant, id).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectRollback()
+				return &mockDB{
+					db:   db,
+					mock: mock,
+				}
+			}(),
+			expectedErr: ErrFeedbackNotFound,
+		},
+		{
+			name: "reply id instead of a feedback id",
+			in: model.FeedbackReplyInput{
+				FeedbackID: toGlobalID(feedbackReplyType, id),
+				Author:     input.Author,
+				Body:       input.Body,
+			},
+			mockDB: func() *mockDB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				return &mockDB{
+					db:   db,
+					mock: mock,
+				}
+			}(),
+			expectedErr: ErrInvalidGlobalID,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			producer := &mockProducer{}
+			resolver := &mutationResolver{
+				Resolver: &Resolver{
+					logger: logger,
+					db:     scenario.mockDB.db,
+					KafkaConfig: &KafkaConfig{
+						Topic:    "test",
+						Producer: producer,
+					},
+				},
+			}
+			reply, err := resolver.ReplyToFeedback(context.Background(), scenario.in)
+			assert.Equal(t, scenario.expectedErr, err)
+			if reply != nil {
+				assert.Equal(t, id, reply.FeedbackID)
+				assert.Len(t, producer.messages, 1)
+				assert.Equal(t, []byte(id), producer.messages[0].Key)
+				assert.Equal(t, []byte(EventFeedbackReplied), producer.messages[0].Headers[0].Value)
+
+				var published RepliedFeedback
+				assert.NoError(t, json.Unmarshal(producer.messages[0].Value, &published))
+				assert.Equal(t, reply.ID, published.ReplyID)
+				assert.Equal(t, email, *published.Email)
+				assert.Equal(t, firstName, *published.FirstName)
+				assert.Equal(t, scenario.expectedFirst, published.FirstReply)
+			} else {
+				assert.Empty(t, producer.messages)
+			}
+			assert.NoError(t, scenario.mockDB.mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserFeedbackResolverReplies(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	firstReply, secondReply := createdAt.Add(90*time.Minute), createdAt.Add(2*time.Hour)
+	mock.ExpectQuery(regexp.QuoteMeta(queryGetRepliesIn + "(?)" + orderRepliesOldest)).WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "feedback_id", "author", "body", "created_at"}).
+			AddRow("r1", id, "support", "looking into it", firstReply).
+			AddRow("r2", id, "support", "fixed", secondReply))
+
+	resolver := &userFeedbackResolver{Resolver: &Resolver{logger: logger, db: db}}
+	ctx := context.WithValue(context.Background(), loadersKey{}, NewLoaders(db, DefaultTenant))
+	fb := &model.UserFeedback{ID: id, CreateAt: &createdAt}
+
+	replies, err := resolver.Replies(ctx, fb)
+	assert.NoError(t, err)
+	assert.Len(t, replies, 2)
+	assert.Equal(t, "r1", replies[0].ID)
+
+	// computed from the replies already loaded for the request
+	at, err := resolver.FirstReplyAt(ctx, fb)
+	assert.NoError(t, err)
+	assert.Equal(t, firstReply, *at)
+	seconds, err := resolver.FirstResponseSeconds(ctx, fb)
+	assert.NoError(t, err)
+	assert.Equal(t, 5400, *seconds)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestQueryResolverNodeReply(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	mock.ExpectQuery(regexp.QuoteMeta(queryGetReplyByID)).WithArgs("r1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "feedback_id", "author", "body", "created_at"}).
+			AddRow("r1", id, "support", "fixed", createdAt))
+	mock.ExpectQuery(regexp.QuoteMeta(queryGetUsersIn+"(?)")).WithArgs(DefaultTenant, id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "first_name",
+			"last_name", "email",
+			"job_title", "feedback", "created_at", "version", "sentiment_score", "sentiment_label", "score", "cluster_id"}).
+			AddRow(id, firstName, lastName, email, jobTitle, feedback, createdAt, 1, nil, nil, nil, nil))
+	mock.ExpectQuery(regexp.QuoteMeta(queryGetReplyByID)).WithArgs("missing").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "feedback_id", "author", "body", "created_at"}))
+
+	resolver := &queryResolver{Resolver: &Resolver{logger: logger, db: db}}
+	node, err := resolver.Node(context.Background(), toGlobalID(feedbackReplyType, "r1"))
+	assert.NoError(t, err)
+	assert.Equal(t, id, node.(*model.FeedbackReply).FeedbackID)
+
+	node, err = resolver.Node(context.Background(), toGlobalID(feedbackReplyType, "missing"))
+	assert.NoError(t, err)
+	assert.Nil(t, node)
+
+	_, err = resolver.Node(context.Background(), toGlobalID("Submitter", email))
+	assert.Equal(t, ErrInvalidGlobalID, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
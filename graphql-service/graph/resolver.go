@@ -3,6 +3,8 @@ package graph
 import (
 	"database/sql"
 	"errors"
+	"time"
+
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/uptrace/opentelemetry-go-extra/otelzap"
 )
@@ -10,6 +12,12 @@ import (
 var (
 	// ErrorFailedToSaveUser means that the user couldn't be saved to db
 	ErrorFailedToSaveUser = errors.New("failed to save user")
+
+	// ErrFeedbackNotFound means that there is no feedback with the given id
+	ErrFeedbackNotFound = errors.New("feedback not found")
+
+	// ErrVersionConflict means that the feedback was changed since the given version was read
+	ErrVersionConflict = errors.New("feedback was modified by someone else, reload it and try again")
 )
 
 // This file will not be regenerated automatically.
@@ -20,6 +28,19 @@ var (
 type KafkaConfig struct {
 	Topic    string
 	Producer Producer
+	// TenantTopics sends the events of a tenant to its own topic, other tenants use Topic
+	TenantTopics map[string]string
+	// KeyedSince is when events started to be keyed by feedback id, zero when it is not known
+	KeyedSince time.Time
+}
+
+// topic returns where the events of the tenant are sent
+func (c *KafkaConfig) topic(tenant string) *string {
+	if topic, ok := c.TenantTopics[tenant]; ok {
+		return &topic
+	}
+
+	return &c.Topic
 }
 
 type Producer interface {
@@ -31,13 +52,24 @@ type Resolver struct {
 	logger      *otelzap.Logger
 	db          *sql.DB
 	KafkaConfig *KafkaConfig
+	broadcaster *Broadcaster
+	attachments *Attachments
+	masking     *Masking
+	erasures    *Erasures
+	// idempotencyTTL is how long the idempotency key of a saved feedback is remembered
+	idempotencyTTL time.Duration
 }
 
 // NewResolver creates a new resolver
-func NewResolver(logger *otelzap.Logger, db *sql.DB, kafkaConfig *KafkaConfig) *Resolver {
+func NewResolver(logger *otelzap.Logger, db *sql.DB, kafkaConfig *KafkaConfig, broadcaster *Broadcaster, attachments *Attachments, masking *Masking, erasures *Erasures, idempotencyTTL time.Duration) *Resolver {
 	return &Resolver{
-		logger:      logger,
-		db:          db,
-		KafkaConfig: kafkaConfig,
+		logger:         logger,
+		db:             db,
+		KafkaConfig:    kafkaConfig,
+		broadcaster:    broadcaster,
+		attachments:    attachments,
+		masking:        masking,
+		erasures:       erasures,
+		idempotencyTTL: idempotencyTTL,
 	}
 }
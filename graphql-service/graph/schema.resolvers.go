@@ -6,23 +6,160 @@ package graph
 
 import (
 	"context"
-	"encoding/json"
+	"database/sql"
+	"errors"
 	"time"
 
-	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/google/uuid"
 	"github.com/riyadennis/sigist/graphql-service/graph/generated"
 	"github.com/riyadennis/sigist/graphql-service/graph/model"
 	"go.uber.org/zap"
 )
 
+// ID is the resolver for the id field.
+func (r *attachmentResolver) ID(ctx context.Context, obj *model.Attachment) (string, error) {
+	return toGlobalID(attachmentType, obj.ID), nil
+}
+
+// URL is the resolver for the url field.
+func (r *attachmentResolver) URL(ctx context.Context, obj *model.Attachment) (string, error) {
+	return r.attachments.URL(obj.ID, time.Now())
+}
+
+// Services is the resolver for the services field.
+func (r *erasureResolver) Services(ctx context.Context, obj *model.Erasure) ([]*model.ErasureServiceStatus, error) {
+	// this service erases the subject before the erasure is recorded
+	completedAt := obj.RequestedAt
+	statuses := []*model.ErasureServiceStatus{{
+		Service:     erasureService,
+		Status:      model.ErasureStatusCompleted,
+		ErasedCount: &obj.FeedbackErased,
+		CompletedAt: &completedAt,
+	}}
+
+	return append(statuses, r.erasures.statuses(ctx, tenantFrom(ctx), obj.ID)...), nil
+}
+
+// Representative is the resolver for the representative field.
+func (r *feedbackClusterResolver) Representative(ctx context.Context, obj *model.FeedbackCluster) (*model.UserFeedback, error) {
+	items, err := getClusterItems(r.db, tenantFrom(ctx), obj.ID, 1)
+	if err != nil {
+		r.logger.Error("failed to fetch cluster representative", zap.String("cluster", obj.ID), zap.Error(err))
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, ErrFeedbackNotFound
+	}
+
+	return items[0], nil
+}
+
+// Items is the resolver for the items field.
+func (r *feedbackClusterResolver) Items(ctx context.Context, obj *model.FeedbackCluster, first *int) ([]*model.UserFeedback, error) {
+	limit, err := pageSize(first, defaultClusterItems)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := getClusterItems(r.db, tenantFrom(ctx), obj.ID, limit)
+	if err != nil {
+		r.logger.Error("failed to fetch cluster items", zap.String("cluster", obj.ID), zap.Error(err))
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// ID is the resolver for the id field.
+func (r *feedbackReplyResolver) ID(ctx context.Context, obj *model.FeedbackReply) (string, error) {
+	return toGlobalID(feedbackReplyType, obj.ID), nil
+}
+
+// Feedback is the resolver for the feedback field.
+func (r *feedbackReplyResolver) Feedback(ctx context.Context, obj *model.FeedbackReply) (*model.UserFeedback, error) {
+	feedback, err := r.loaders(ctx).FeedbackByID.Load(ctx, obj.FeedbackID)
+	if err != nil {
+		r.logger.Error("failed to fetch feedback", zap.String("id", obj.FeedbackID), zap.Error(err))
+		return nil, err
+	}
+	if feedback == nil {
+		return nil, ErrFeedbackNotFound
+	}
+
+	return feedback, nil
+}
+
+// Total is the resolver for the total field.
+func (r *feedbackStatsResolver) Total(ctx context.Context, obj *model.FeedbackStats) (int, error) {
+	total, err := countUsers(r.db, tenantFrom(ctx), obj.Filter)
+	if err != nil {
+		r.logger.Error("failed to count feedback", zap.Error(err))
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// Groups is the resolver for the groups field.
+func (r *feedbackStatsResolver) Groups(ctx context.Context, obj *model.FeedbackStats) ([]*model.FeedbackGroup, error) {
+	groups, err := countGroups(r.db, tenantFrom(ctx), obj.Filter, obj.GroupBy)
+	if err != nil {
+		r.logger.Error("failed to count feedback groups", zap.Error(err))
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// Facets is the resolver for the facets field.
+func (r *feedbackStatsResolver) Facets(ctx context.Context, obj *model.FeedbackStats) (*model.FeedbackFacets, error) {
+	jobTitles, err := jobTitleFacets(r.db, tenantFrom(ctx), obj.Filter)
+	if err != nil {
+		r.logger.Error("failed to list job title facets", zap.Error(err))
+		return nil, err
+	}
+
+	return &model.FeedbackFacets{JobTitles: jobTitles}, nil
+}
+
 // SaveUserFeedback is the resolver for the SaveUserFeedback field.
 func (r *mutationResolver) SaveUserFeedback(ctx context.Context, input model.UserFeedbackInput) (*model.UserFeedback, error) {
-	createdAt := time.Now().Format(time.RFC3339)
+	createdAt := time.Now().UTC().Truncate(time.Second)
 	id := uuid.New().String()
+	tenant := tenantFrom(ctx)
 
-	res, err := saveUserFeedback(r.db, input, id, createdAt)
+	claim, err := r.claimIdempotencyKey(ctx, input, createdAt)
 	if err != nil {
+		return nil, err
+	}
+	if claim != nil {
+		feedback, err := r.replay(ctx, claim)
+		if feedback != nil || err != nil {
+			return feedback, err
+		}
+	}
+
+	sentiment := analyzeSentiment(input.Feedback)
+
+	attachments, err := r.attachments.store(ctx, id, input.Attachments, createdAt)
+	if err != nil {
+		r.logger.Error("failed to store attachments", zap.Error(err))
+		return nil, err
+	}
+
+	res, clusterID, err := saveUserFeedback(r.db, tenant, input, id, formatDateTime(createdAt), sentiment, attachments, claim)
+	if err != nil {
+		if err := r.attachments.remove(ctx, storageKeys(attachments)); err != nil {
+			r.logger.Warn("failed to delete attachment files", zap.Error(err))
+		}
+		// a concurrent request with the same key won, answer with its feedback
+		if errors.Is(err, errIdempotencyKeyTaken) {
+			feedback, err := r.replay(ctx, claim)
+			if feedback == nil && err == nil {
+				err = ErrorFailedToSaveUser
+			}
+			return feedback, err
+		}
 		r.logger.Error("failed to execute statement", zap.Error(err))
 		return nil, err
 	}
@@ -35,29 +172,49 @@ func (r *mutationResolver) SaveUserFeedback(ctx context.Context, input model.Use
 	if rows == 0 {
 		return nil, ErrorFailedToSaveUser
 	}
+	r.loaders(ctx).Clear()
 
 	feedback := &model.UserFeedback{
-		ID:        &id,
-		Email:     &input.Email,
-		FirstName: &input.FirstName,
-		LastName:  &input.LastName,
-		JobTitle:  input.JobTitle,
-		Feedback:  &input.Feedback,
-		CreateAt:  &createdAt,
+		ID:          id,
+		Email:       &input.Email,
+		FirstName:   &input.FirstName,
+		LastName:    &input.LastName,
+		JobTitle:    input.JobTitle,
+		Feedback:    &input.Feedback,
+		CreateAt:    &createdAt,
+		Version:     1,
+		Sentiment:   sentiment,
+		Score:       input.Score,
+		Attachments: attachments,
+		ClusterID:   &clusterID,
+	}
+
+	err = r.publish(tenant, EventFeedbackCreated, id, feedback)
+	if err != nil {
+		r.logger.Error("failed to publish to  kafka", zap.Error(err))
+		return nil, err
 	}
+	r.broadcaster.Publish(tenant, feedback)
 
-	kafkaData, err := json.Marshal(feedback)
+	return feedback, nil
+}
+
+// UpdateUserFeedback is the resolver for the UpdateUserFeedback field.
+func (r *mutationResolver) UpdateUserFeedback(ctx context.Context, input model.UpdateUserFeedbackInput) (*model.UserFeedback, error) {
+	id, err := feedbackID(input.ID)
 	if err != nil {
-		r.logger.Error("failed to marshal feedback", zap.Error(err))
 		return nil, err
 	}
-	message := &kafka.Message{
-		TopicPartition: kafka.TopicPartition{Topic: &r.KafkaConfig.Topic, Partition: kafka.PartitionAny},
-		Value:          kafkaData,
-		Headers:        []kafka.Header{{Key: id, Value: []byte("header values are binary")}},
+
+	tenant := tenantFrom(ctx)
+	feedback, err := updateUserFeedback(r.db, tenant, id, input)
+	if err != nil {
+		r.logger.Error("failed to update feedback", zap.String("id", id), zap.Error(err))
+		return nil, err
 	}
+	r.loaders(ctx).Clear()
 
-	err = r.KafkaConfig.Producer.Produce(message, nil)
+	err = r.publish(tenant, EventFeedbackUpdated, id, feedback)
 	if err != nil {
 		r.logger.Error("failed to publish to  kafka", zap.Error(err))
 		return nil, err
@@ -66,41 +223,504 @@ func (r *mutationResolver) SaveUserFeedback(ctx context.Context, input model.Use
 	return feedback, nil
 }
 
+// DeleteUserFeedback is the resolver for the DeleteUserFeedback field.
+func (r *mutationResolver) DeleteUserFeedback(ctx context.Context, id string, version int) (string, error) {
+	dbID, err := feedbackID(id)
+	if err != nil {
+		return "", err
+	}
+
+	tenant := tenantFrom(ctx)
+	keys, err := deleteUserFeedback(r.db, tenant, dbID, version)
+	if err != nil {
+		r.logger.Error("failed to delete feedback", zap.String("id", dbID), zap.Error(err))
+		return "", err
+	}
+	r.loaders(ctx).Clear()
+	if err := r.attachments.remove(ctx, keys); err != nil {
+		r.logger.Warn("failed to delete attachment files", zap.String("id", dbID), zap.Error(err))
+	}
+
+	err = r.publish(tenant, EventFeedbackDeleted, dbID, &DeletedFeedback{ID: dbID, Version: version})
+	if err != nil {
+		r.logger.Error("failed to publish to  kafka", zap.Error(err))
+		return "", err
+	}
+
+	return id, nil
+}
+
+// AddFeedbackTags is the resolver for the AddFeedbackTags field.
+func (r *mutationResolver) AddFeedbackTags(ctx context.Context, id string, tags []string) (*model.UserFeedback, error) {
+	return r.changeTags(ctx, id, tags, EventFeedbackTagged, func(tenant, dbID string, tags []string) ([]string, error) {
+		return addFeedbackTags(r.db, tenant, dbID, tags, formatDateTime(time.Now()))
+	})
+}
+
+// RemoveFeedbackTags is the resolver for the RemoveFeedbackTags field.
+func (r *mutationResolver) RemoveFeedbackTags(ctx context.Context, id string, tags []string) (*model.UserFeedback, error) {
+	return r.changeTags(ctx, id, tags, EventFeedbackUntagged, func(tenant, dbID string, tags []string) ([]string, error) {
+		return removeFeedbackTags(r.db, tenant, dbID, tags)
+	})
+}
+
+// ReplyToFeedback is the resolver for the replyToFeedback field.
+func (r *mutationResolver) ReplyToFeedback(ctx context.Context, input model.FeedbackReplyInput) (*model.FeedbackReply, error) {
+	dbID, err := feedbackID(input.FeedbackID)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := &model.FeedbackReply{
+		ID:         uuid.New().String(),
+		FeedbackID: dbID,
+		Author:     input.Author,
+		Body:       input.Body,
+		CreateAt:   time.Now().UTC().Truncate(time.Second),
+	}
+	tenant := tenantFrom(ctx)
+	feedback, first, err := saveReply(r.db, tenant, reply)
+	if err != nil {
+		r.logger.Error("failed to save reply", zap.String("id", dbID), zap.Error(err))
+		return nil, err
+	}
+	r.loaders(ctx).Clear()
+
+	if first && feedback.CreateAt != nil {
+		firstResponseTimes.Record(ctx, reply.CreateAt.Sub(*feedback.CreateAt).Seconds())
+	}
+
+	err = r.publish(tenant, EventFeedbackReplied, dbID, RepliedFeedback{
+		ID:         dbID,
+		ReplyID:    reply.ID,
+		Email:      feedback.Email,
+		FirstName:  feedback.FirstName,
+		Author:     reply.Author,
+		Body:       reply.Body,
+		CreateAt:   reply.CreateAt,
+		FirstReply: first,
+	})
+	if err != nil {
+		r.logger.Error("failed to publish to  kafka", zap.Error(err))
+		return nil, err
+	}
+
+	return reply, nil
+}
+
+// EraseSubject is the resolver for the eraseSubject field.
+func (r *mutationResolver) EraseSubject(ctx context.Context, email string) (*model.Erasure, error) {
+	subject := normalizeSubject(email)
+	if err := validate.Var(subject, "required,max=254,email"); err != nil {
+		return nil, ErrInvalidSubject
+	}
+
+	erasure := &model.Erasure{
+		ID:          uuid.New().String(),
+		SubjectHash: subjectHash(subject),
+		RequestedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	if claims := claimsFrom(ctx); claims != nil {
+		erasure.RequestedBy = claims.Subject
+	}
+	tenant := tenantFrom(ctx)
+	ids, keys, err := eraseSubject(r.db, tenant, erasure, r.KafkaConfig.KeyedSince)
+	if err != nil {
+		r.logger.Error("failed to erase subject", zap.String("erasure", erasure.ID), zap.Error(err))
+		return nil, err
+	}
+	r.loaders(ctx).Clear()
+	if err := r.attachments.remove(ctx, keys); err != nil {
+		r.logger.Warn("failed to delete attachment files", zap.String("erasure", erasure.ID), zap.Error(err))
+	}
+
+	for _, id := range ids {
+		if err := r.publishTombstone(tenant, id); err != nil {
+			r.logger.Error("failed to publish to  kafka", zap.Error(err))
+			return nil, err
+		}
+	}
+	err = r.publish(tenant, EventSubjectErased, erasure.ID, &SubjectErased{
+		ID:          erasure.ID,
+		SubjectHash: erasure.SubjectHash,
+		RequestedAt: erasure.RequestedAt,
+	})
+	if err != nil {
+		r.logger.Error("failed to publish to  kafka", zap.Error(err))
+		return nil, err
+	}
+
+	return erasure, nil
+}
+
 // GetUserFeedback is the resolver for the GetUserFeedback field.
-func (r *queryResolver) GetUserFeedback(ctx context.Context, filter model.FilterInput) ([]*model.UserFeedback, error) {
-	var userFeedbacks []*model.UserFeedback
+func (r *queryResolver) GetUserFeedback(ctx context.Context, filter model.FilterInput, orderBy []*model.FeedbackOrder, first *int, after *string, last *int, before *string) (*model.UserFeedbackConnection, error) {
+	if err := r.masking.checkFilter(ctx, &filter); err != nil {
+		return nil, err
+	}
+	if err := r.masking.checkOrder(ctx, orderBy); err != nil {
+		return nil, err
+	}
+	page, err := newFeedbackPageQuery(orderBy, first, after, last, before)
+	if err != nil {
+		return nil, err
+	}
+
+	tenant := tenantFrom(ctx)
+	totalCount, err := countUsers(r.db, tenant, filter)
+	if err != nil {
+		r.logger.Error("failed to count feedback", zap.Error(err))
+		return nil, err
+	}
 
-	rows, err := getUserRows(r.db, filter)
+	rows, err := getUserRows(r.db, tenant, filter, page)
 	if err != nil {
 		r.logger.Error("failed to execute query", zap.Error(err))
 		return nil, err
 	}
 	defer rows.Close()
-	for rows.Next() {
-		user := model.UserFeedback{}
-		err := rows.Scan(
-			&user.ID, &user.FirstName,
-			&user.LastName, &user.Email,
-			&user.JobTitle, &user.Feedback,
-			&user.CreateAt,
-		)
+
+	userFeedbacks, err := scanUsers(rows)
+	if err != nil {
+		r.logger.Error("failed to scan row", zap.Error(err))
+		return nil, err
+	}
+
+	return newUserFeedbackConnection(userFeedbacks, page, totalCount), nil
+}
+
+// Node is the resolver for the node field.
+func (r *queryResolver) Node(ctx context.Context, id string) (model.Node, error) {
+	typeName, dbID, err := fromGlobalID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch typeName {
+	case userFeedbackType:
+		feedback, err := r.loaders(ctx).FeedbackByID.Load(ctx, dbID)
+		if err != nil {
+			r.logger.Error("failed to fetch feedback", zap.Error(err))
+			return nil, err
+		}
+		if feedback == nil {
+			return nil, nil
+		}
+		return feedback, nil
+	case feedbackReplyType:
+		reply, err := getReplyByID(r.db, dbID)
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
 		if err != nil {
-			r.logger.Error("failed to scan row", zap.Error(err))
+			r.logger.Error("failed to fetch reply", zap.Error(err))
 			return nil, err
 		}
-		userFeedbacks = append(userFeedbacks, &user)
+		return r.tenantNode(ctx, reply.FeedbackID, reply)
+	case attachmentType:
+		attachment, err := getAttachmentByID(r.db, dbID)
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		if err != nil {
+			r.logger.Error("failed to fetch attachment", zap.Error(err))
+			return nil, err
+		}
+		return r.tenantNode(ctx, attachment.FeedbackID, attachment)
+	default:
+		return nil, ErrInvalidGlobalID
+	}
+}
+
+// FeedbackStats is the resolver for the feedbackStats field.
+func (r *queryResolver) FeedbackStats(ctx context.Context, filter *model.FilterInput, groupBy []model.FeedbackGroupBy) (*model.FeedbackStats, error) {
+	stats := &model.FeedbackStats{GroupBy: groupBy}
+	if filter != nil {
+		stats.Filter = *filter
+	}
+
+	// validate the arguments once instead of in every selected aggregate
+	if err := r.masking.checkFilter(ctx, filter); err != nil {
+		return nil, err
+	}
+	if _, err := compileFilter(&stats.Filter); err != nil {
+		return nil, err
+	}
+	if _, err := groupByColumns(groupBy); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// NpsReport is the resolver for the npsReport field.
+func (r *queryResolver) NpsReport(ctx context.Context, filter *model.FilterInput, interval model.ReportInterval) (*model.NpsReport, error) {
+	if err := r.masking.checkFilter(ctx, filter); err != nil {
+		return nil, err
+	}
+	var f model.FilterInput
+	if filter != nil {
+		f = *filter
+	}
+
+	report, err := npsReport(r.db, tenantFrom(ctx), f, interval)
+	if err != nil {
+		r.logger.Error("failed to compute nps report", zap.Error(err))
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// SimilarFeedback is the resolver for the similarFeedback field.
+func (r *queryResolver) SimilarFeedback(ctx context.Context, id string, threshold float64, first *int) ([]*model.SimilarFeedback, error) {
+	if threshold <= 0 || threshold > 1 {
+		return nil, ErrInvalidThreshold
+	}
+	limit, err := pageSize(first, defaultSimilarFeedback)
+	if err != nil {
+		return nil, err
+	}
+	dbID, err := feedbackID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	loaders := r.loaders(ctx)
+	feedback, err := loaders.FeedbackByID.Load(ctx, dbID)
+	if err != nil {
+		r.logger.Error("failed to fetch feedback", zap.String("id", dbID), zap.Error(err))
+		return nil, err
+	}
+	if feedback == nil {
+		return nil, ErrFeedbackNotFound
+	}
+
+	matches, err := findSimilar(r.db, tenantFrom(ctx), dbID, signFeedback(feedback.Feedback), threshold)
+	if err != nil {
+		r.logger.Error("failed to find similar feedback", zap.String("id", dbID), zap.Error(err))
+		return nil, err
+	}
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	ids := make([]string, 0, len(matches))
+	for _, match := range matches {
+		ids = append(ids, match.feedbackID)
+	}
+	feedbacks, err := loaders.FeedbackByID.LoadMany(ctx, ids)
+	if err != nil {
+		r.logger.Error("failed to fetch similar feedback", zap.Error(err))
+		return nil, err
+	}
+
+	similar := make([]*model.SimilarFeedback, 0, len(matches))
+	for i, match := range matches {
+		// feedback deleted since its signature was read is left out
+		if feedbacks[i] != nil {
+			similar = append(similar, &model.SimilarFeedback{Feedback: feedbacks[i], Similarity: match.similarity})
+		}
+	}
+
+	return similar, nil
+}
+
+// Clusters is the resolver for the clusters field.
+func (r *queryResolver) Clusters(ctx context.Context, first *int, minSize int) ([]*model.FeedbackCluster, error) {
+	limit, err := pageSize(first, defaultPageSize)
+	if err != nil {
+		return nil, err
+	}
+	if minSize < 1 {
+		return nil, ErrInvalidClusterSize
+	}
+
+	clusters, err := getClusters(r.db, tenantFrom(ctx), minSize, limit)
+	if err != nil {
+		r.logger.Error("failed to fetch clusters", zap.Error(err))
+		return nil, err
+	}
+
+	return clusters, nil
+}
+
+// SearchUserFeedback is the resolver for the SearchUserFeedback field.
+func (r *queryResolver) SearchUserFeedback(ctx context.Context, query string, first *int, after *string) (*model.UserFeedbackSearchConnection, error) {
+	page, err := newPageQuery(first, after, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	tenant := tenantFrom(ctx)
+	totalCount, err := countSearchUsers(r.db, tenant, query)
+	if err != nil {
+		r.logger.Error("failed to count search results", zap.Error(err))
+		return nil, err
+	}
+
+	results, err := searchUsers(r.db, tenant, query, page)
+	if err != nil {
+		r.logger.Error("failed to search feedback", zap.Error(err))
+		return nil, err
+	}
+
+	return newUserFeedbackSearchConnection(results, page, totalCount), nil
+}
+
+// Erasure is the resolver for the erasure field.
+func (r *queryResolver) Erasure(ctx context.Context, id string) (*model.Erasure, error) {
+	erasure, err := getErasure(r.db, tenantFrom(ctx), id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("failed to get erasure", zap.String("id", id), zap.Error(err))
+		return nil, err
+	}
+
+	return erasure, nil
+}
+
+// Feedback is the resolver for the feedback field.
+func (r *submitterResolver) Feedback(ctx context.Context, obj *model.Submitter, first *int, after *string) (*model.UserFeedbackConnection, error) {
+	filter := model.FilterInput{Email: &model.StringFilter{Eq: &obj.Email}}
+	return r.Query().GetUserFeedback(ctx, filter, nil, first, after, nil, nil)
+}
+
+// FeedbackSubmitted is the resolver for the feedbackSubmitted field.
+func (r *subscriptionResolver) FeedbackSubmitted(ctx context.Context, filter *model.FilterInput) (<-chan *model.UserFeedback, error) {
+	if err := r.masking.checkFilter(ctx, filter); err != nil {
+		return nil, err
+	}
+	// compiling validates ids and time windows before anything is published
+	if _, err := compileFilter(filter); err != nil {
+		return nil, err
+	}
+
+	return r.broadcaster.Subscribe(ctx, tenantFrom(ctx), filter)
+}
+
+// ID is the resolver for the id field.
+func (r *userFeedbackResolver) ID(ctx context.Context, obj *model.UserFeedback) (string, error) {
+	return toGlobalID(userFeedbackType, obj.ID), nil
+}
+
+// LastName is the resolver for the lastName field.
+func (r *userFeedbackResolver) LastName(ctx context.Context, obj *model.UserFeedback) (*string, error) {
+	return r.masking.apply(ctx, maskedLastName, obj.ID, obj.LastName), nil
+}
+
+// Email is the resolver for the email field.
+func (r *userFeedbackResolver) Email(ctx context.Context, obj *model.UserFeedback) (*string, error) {
+	return r.masking.apply(ctx, maskedEmail, obj.ID, obj.Email), nil
+}
+
+// Tags is the resolver for the tags field.
+func (r *userFeedbackResolver) Tags(ctx context.Context, obj *model.UserFeedback) ([]string, error) {
+	tags, err := r.loaders(ctx).TagsByFeedbackID.Load(ctx, obj.ID)
+	if err != nil {
+		r.logger.Error("failed to load tags", zap.String("id", obj.ID), zap.Error(err))
+		return nil, err
+	}
+	if tags == nil {
+		return []string{}, nil
 	}
-	return userFeedbacks, nil
+
+	return tags, nil
 }
 
+// Replies is the resolver for the replies field.
+func (r *userFeedbackResolver) Replies(ctx context.Context, obj *model.UserFeedback) ([]*model.FeedbackReply, error) {
+	replies, err := r.loaders(ctx).RepliesByFeedbackID.Load(ctx, obj.ID)
+	if err != nil {
+		r.logger.Error("failed to load replies", zap.String("id", obj.ID), zap.Error(err))
+		return nil, err
+	}
+	if replies == nil {
+		return []*model.FeedbackReply{}, nil
+	}
+
+	return replies, nil
+}
+
+// FirstReplyAt is the resolver for the firstReplyAt field.
+func (r *userFeedbackResolver) FirstReplyAt(ctx context.Context, obj *model.UserFeedback) (*time.Time, error) {
+	first, err := r.firstReply(ctx, obj)
+	if err != nil || first == nil {
+		return nil, err
+	}
+
+	return &first.CreateAt, nil
+}
+
+// FirstResponseSeconds is the resolver for the firstResponseSeconds field.
+func (r *userFeedbackResolver) FirstResponseSeconds(ctx context.Context, obj *model.UserFeedback) (*int, error) {
+	first, err := r.firstReply(ctx, obj)
+	if err != nil || first == nil || obj.CreateAt == nil {
+		return nil, err
+	}
+
+	seconds := int(first.CreateAt.Sub(*obj.CreateAt).Seconds())
+	return &seconds, nil
+}
+
+// Attachments is the resolver for the attachments field.
+func (r *userFeedbackResolver) Attachments(ctx context.Context, obj *model.UserFeedback) ([]*model.Attachment, error) {
+	attachments, err := r.loaders(ctx).AttachmentsByFeedbackID.Load(ctx, obj.ID)
+	if err != nil {
+		r.logger.Error("failed to load attachments", zap.String("id", obj.ID), zap.Error(err))
+		return nil, err
+	}
+	if attachments == nil {
+		return []*model.Attachment{}, nil
+	}
+
+	return attachments, nil
+}
+
+// Attachment returns generated.AttachmentResolver implementation.
+func (r *Resolver) Attachment() generated.AttachmentResolver { return &attachmentResolver{r} }
+
+// Erasure returns generated.ErasureResolver implementation.
+func (r *Resolver) Erasure() generated.ErasureResolver { return &erasureResolver{r} }
+
+// FeedbackCluster returns generated.FeedbackClusterResolver implementation.
+func (r *Resolver) FeedbackCluster() generated.FeedbackClusterResolver {
+	return &feedbackClusterResolver{r}
+}
+
+// FeedbackReply returns generated.FeedbackReplyResolver implementation.
+func (r *Resolver) FeedbackReply() generated.FeedbackReplyResolver { return &feedbackReplyResolver{r} }
+
+// FeedbackStats returns generated.FeedbackStatsResolver implementation.
+func (r *Resolver) FeedbackStats() generated.FeedbackStatsResolver { return &feedbackStatsResolver{r} }
+
 // Mutation returns generated.MutationResolver implementation.
 func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
 
 // Query returns generated.QueryResolver implementation.
 func (r *Resolver) Query() generated.QueryResolver { return &queryResolver{r} }
 
+// Submitter returns generated.SubmitterResolver implementation.
+func (r *Resolver) Submitter() generated.SubmitterResolver { return &submitterResolver{r} }
+
+// Subscription returns generated.SubscriptionResolver implementation.
+func (r *Resolver) Subscription() generated.SubscriptionResolver { return &subscriptionResolver{r} }
+
+// UserFeedback returns generated.UserFeedbackResolver implementation.
+func (r *Resolver) UserFeedback() generated.UserFeedbackResolver { return &userFeedbackResolver{r} }
+
+type attachmentResolver struct{ *Resolver }
+type erasureResolver struct{ *Resolver }
+type feedbackClusterResolver struct{ *Resolver }
+type feedbackReplyResolver struct{ *Resolver }
+type feedbackStatsResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type submitterResolver struct{ *Resolver }
+type subscriptionResolver struct{ *Resolver }
+type userFeedbackResolver struct{ *Resolver }
 
 func (r *userResolver) ID(ctx context.Context, obj *model.User) (*string, error) {
 	return &obj.ID, nil
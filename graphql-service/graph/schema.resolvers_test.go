@@ -3,8 +3,10 @@ package graph
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"regexp"
 	"testing"
 	"time"
 
@@ -20,7 +22,7 @@ var (
 	errFailedToPublishToKafka = errors.New("failed to produce kafka message")
 	logger                    = otelzap.New(zap.NewNop())
 	id                        = "123"
-	createdAt                 = time.Now().Format(time.RFC3339)
+	createdAt                 = time.Now().UTC().Truncate(time.Second)
 	firstName                 = "John"
 	lastName                  = "Doe"
 	email                     = "john@test.com"
@@ -34,10 +36,12 @@ type mockDB struct {
 }
 
 type mockProducer struct {
-	err error
+	err      error
+	messages []*kafka.Message
 }
 
-func (m *mockProducer) Produce(_ *kafka.Message, _ chan kafka.Event) error {
+func (m *mockProducer) Produce(msg *kafka.Message, _ chan kafka.Event) error {
+	m.messages = append(m.messages, msg)
 	return m.err
 }
 
@@ -89,7 +93,7 @@ func TestMutationResolverSaveUserFeedback(t *testing.T) {
 			},
 			out: func() *model.UserFeedback {
 				return &model.UserFeedback{
-					ID:        &id,
+					ID:        id,
 					FirstName: &firstName,
 					LastName:  &lastName,
 					Email:     &email,
@@ -115,7 +119,7 @@ func TestMutationResolverSaveUserFeedback(t *testing.T) {
 			mockKafka: &mockProducer{},
 			out: func() *model.UserFeedback {
 				return &model.UserFeedback{
-					ID:        &id,
+					ID:        id,
 					FirstName: &firstName,
 					LastName:  &lastName,
 					Email:     &email,
@@ -143,7 +147,12 @@ func TestMutationResolverSaveUserFeedback(t *testing.T) {
 			assert.Equal(t, scenario.expectedErr, err)
 			if user != nil {
 				assert.Equal(t, *scenario.out.Feedback, *user.Feedback)
-				assert.Equal(t, *scenario.out.CreateAt, *user.CreateAt)
+				assert.WithinDuration(t, *scenario.out.CreateAt, *user.CreateAt, time.Second)
+				assert.Equal(t, analyzeSentiment(scenario.in.Feedback), user.Sentiment)
+
+				var published model.UserFeedback
+				assert.NoError(t, json.Unmarshal(scenario.mockKafka.messages[0].Value, &published))
+				assert.Equal(t, user.Sentiment, published.Sentiment)
 			}
 			err = scenario.mockDB.mock.ExpectationsWereMet()
 			assert.NoError(t, err)
@@ -152,21 +161,176 @@ func TestMutationResolverSaveUserFeedback(t *testing.T) {
 	}
 }
 
+func TestMutationResolverUpdateUserFeedback(t *testing.T) {
+	newFeedback := "This is an updated feedback"
+	updated := analyzeSentiment(newFeedback)
+	scenarios := []struct {
+		name        string
+		in          model.UpdateUserFeedbackInput
+		mockDB      *mockDB
+		expectedErr error
+	}{
+		{
+			name: "invalid id",
+			in:   model.UpdateUserFeedbackInput{ID: id, Version: 1},
+			mockDB: func() *mockDB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				return &mockDB{
+					db:   db,
+					mock: mock,
+				}
+			}(),
+			expectedErr: ErrInvalidGlobalID,
+		},
+		{
+			name: "stale version",
+			in:   model.UpdateUserFeedbackInput{ID: toGlobalID(userFeedbackType, id), Version: 1, Feedback: &newFeedback},
+			mockDB: func() *mockDB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				expectCandidates(mock, sqlmock.NewRows(candidateColumns))
+				mock.ExpectExec(regexp.QuoteMeta(queryUpdateUser)).
+					WithArgs(nil, nil, nil, nil, nil, newFeedback, updated.Score, updated.Label, nil, id, DefaultTenant, id, 1).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(regexp.QuoteMeta(queryUserExists)).
+					WithArgs(DefaultTenant, id).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectRollback()
+				return &mockDB{
+					db:   db,
+					mock: mock,
+				}
+			}(),
+			expectedErr: ErrVersionConflict,
+		},
+		{
+			name: "missing feedback",
+			in:   model.UpdateUserFeedbackInput{ID: toGlobalID(userFeedbackType, id), Version: 1},
+			mockDB: func() *mockDB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(queryUpdateUser)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(regexp.QuoteMeta(queryUserExists)).
+					WithArgs(DefaultTenant, id).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				mock.ExpectRollback()
+				return &mockDB{
+					db:   db,
+					mock: mock,
+				}
+			}(),
+			expectedErr: ErrFeedbackNotFound,
+		},
+		{
+			name: "success",
+			in:   model.UpdateUserFeedbackInput{ID: toGlobalID(userFeedbackType, id), Version: 1, Feedback: &newFeedback},
+			mockDB: func() *mockDB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				expectCandidates(mock, sqlmock.NewRows(candidateColumns))
+				mock.ExpectExec(regexp.QuoteMeta(queryUpdateUser)).
+					WithArgs(nil, nil, nil, nil, nil, newFeedback, updated.Score, updated.Label, nil, id, DefaultTenant, id, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectSaveSignature(mock, id)
+				rows := sqlmock.NewRows([]string{"id", "first_name",
+					"last_name", "email",
+					"job_title", "feedback", "created_at", "version", "sentiment_score", "sentiment_label", "score", "cluster_id"}).AddRow(
+					id, firstName, lastName, email, jobTitle, newFeedback, createdAt, 2, updated.Score, updated.Label, nil, nil)
+				mock.ExpectQuery(regexp.QuoteMeta(queryGetUserByID)).WithArgs(DefaultTenant, id).WillReturnRows(rows)
+				mock.ExpectCommit()
+				return &mockDB{
+					db:   db,
+					mock: mock,
+				}
+			}(),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			producer := &mockProducer{}
+			resolver := &mutationResolver{
+				Resolver: &Resolver{
+					logger: logger,
+					db:     scenario.mockDB.db,
+					KafkaConfig: &KafkaConfig{
+						Topic:    "test",
+						Producer: producer,
+					},
+				},
+			}
+			user, err := resolver.UpdateUserFeedback(context.Background(), scenario.in)
+			assert.Equal(t, scenario.expectedErr, err)
+			if user != nil {
+				assert.Equal(t, newFeedback, *user.Feedback)
+				assert.Equal(t, 2, user.Version)
+				assert.Len(t, producer.messages, 1)
+				assert.Equal(t, []byte(EventFeedbackUpdated), producer.messages[0].Headers[0].Value)
+			} else {
+				assert.Empty(t, producer.messages)
+			}
+			assert.NoError(t, scenario.mockDB.mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMutationResolverDeleteUserFeedback(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(queryAttachmentKeys)).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"storage_key"}))
+	mock.ExpectExec(regexp.QuoteMeta(queryDeleteUser)).
+		WithArgs(DefaultTenant, id, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	producer := &mockProducer{}
+	resolver := &mutationResolver{
+		Resolver: &Resolver{
+			logger: logger,
+			db:     db,
+			KafkaConfig: &KafkaConfig{
+				Topic:    "test",
+				Producer: producer,
+			},
+		},
+	}
+
+	globalID := toGlobalID(userFeedbackType, id)
+	deleted, err := resolver.DeleteUserFeedback(context.Background(), globalID, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, globalID, deleted)
+	assert.Len(t, producer.messages, 1)
+	assert.Equal(t, []byte(id), producer.messages[0].Key)
+	assert.Equal(t, []byte(EventFeedbackDeleted), producer.messages[0].Headers[0].Value)
+	assert.JSONEq(t, `{"id":"123","version":3}`, string(producer.messages[0].Value))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestQueryResolverGetUser(t *testing.T) {
 	scenarios := []struct {
 		name        string
 		in          *model.FilterInput
+		first       *int
 		out         []*model.UserFeedback
+		hasNextPage bool
 		mockDB      *mockDB
 		expectedErr error
 	}{
 		{
-			name: "db prepare error",
+			name: "db count error",
 			in:   &model.FilterInput{},
 			mockDB: func() *mockDB {
 				db, mock, err := sqlmock.New()
 				assert.NoError(t, err)
-				mock.ExpectQuery(queryGetAllUsers).
+				mock.ExpectQuery(regexp.QuoteMeta(queryCountUsers)).
 					WillReturnError(errFailedDBOperation)
 				return &mockDB{
 					db:   db,
@@ -181,7 +345,9 @@ func TestQueryResolverGetUser(t *testing.T) {
 			mockDB: func() *mockDB {
 				db, mock, err := sqlmock.New()
 				assert.NoError(t, err)
-				mock.ExpectQuery(queryGetAllUsers).WillReturnError(errFailedDBOperation)
+				mock.ExpectQuery(regexp.QuoteMeta(queryCountUsers)).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectQuery(regexp.QuoteMeta(queryGetUsers)).WillReturnError(errFailedDBOperation)
 				return &mockDB{
 					db:   db,
 					mock: mock,
@@ -190,18 +356,36 @@ func TestQueryResolverGetUser(t *testing.T) {
 			expectedErr: errors.New("failed to perform db operation"),
 		},
 		{
-			name: "db select success",
-			in:   &model.FilterInput{},
+			name:  "invalid page size",
+			in:    &model.FilterInput{},
+			first: func() *int { i := maxPageSize + 1; return &i }(),
 			mockDB: func() *mockDB {
 				db, mock, err := sqlmock.New()
 				assert.NoError(t, err)
+				return &mockDB{db: db, mock: mock}
+			}(),
+			expectedErr: ErrInvalidPageSize,
+		},
+		{
+			name:  "db select success",
+			in:    &model.FilterInput{},
+			first: func() *int { i := 1; return &i }(),
+			mockDB: func() *mockDB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(regexp.QuoteMeta(queryCountUsers)).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
 				rows := sqlmock.NewRows([]string{"id", "first_name",
 					"last_name", "email",
-					"job_title", "feedback", "created_at"}).AddRow(
+					"job_title", "feedback", "created_at", "version", "sentiment_score", "sentiment_label", "score", "cluster_id"}).AddRow(
 					"1", "John",
 					"Doe", "john.doe@gmail.com",
-					"Quality Engineer", "loved it", time.Now().Format(time.RFC3339))
-				mock.ExpectQuery(queryGetAllUsers).
+					"Quality Engineer", "loved it", createdAt, 1, 0.632, "POSITIVE", nil, nil).AddRow(
+					"2", "Jane",
+					"Doe", "jane.doe@gmail.com",
+					"Quality Engineer", "loved it", createdAt, 1, 0.632, "POSITIVE", nil, nil)
+				mock.ExpectQuery(regexp.QuoteMeta(queryGetUsers+" WHERE tenant = ? ORDER BY created_at, id LIMIT ?")).
+					WithArgs(DefaultTenant, 2).
 					WillReturnRows(rows)
 				return &mockDB{
 					db:   db,
@@ -211,7 +395,7 @@ func TestQueryResolverGetUser(t *testing.T) {
 			out: func() []*model.UserFeedback {
 				return []*model.UserFeedback{
 					{
-						ID:        &id,
+						ID:        id,
 						FirstName: &firstName,
 						LastName:  &lastName,
 						Email:     &email,
@@ -220,6 +404,7 @@ func TestQueryResolverGetUser(t *testing.T) {
 					},
 				}
 			}(),
+			hasNextPage: true,
 		},
 	}
 
@@ -231,12 +416,15 @@ func TestQueryResolverGetUser(t *testing.T) {
 					db:     scenario.mockDB.db,
 				},
 			}
-			users, err := resolver.GetUserFeedback(context.Background(), *scenario.in)
+			conn, err := resolver.GetUserFeedback(context.Background(), *scenario.in, nil, scenario.first, nil, nil, nil)
 			if err != nil {
 				assert.Equal(t, scenario.expectedErr.Error(), err.Error())
 			}
-			if len(users) > 0 {
-				assert.Equal(t, *scenario.out[0].FirstName, *users[0].FirstName)
+			if conn != nil {
+				assert.Len(t, conn.Edges, len(scenario.out))
+				assert.Equal(t, *scenario.out[0].FirstName, *conn.Edges[0].Node.FirstName)
+				assert.Equal(t, scenario.hasNextPage, conn.PageInfo.HasNextPage)
+				assert.Equal(t, 2, conn.TotalCount)
 			}
 			err = scenario.mockDB.mock.ExpectationsWereMet()
 			assert.NoError(t, err)
@@ -245,11 +433,33 @@ func TestQueryResolverGetUser(t *testing.T) {
 	}
 }
 
+func TestQueryResolverNode(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	rows := sqlmock.NewRows([]string{"id", "first_name",
+		"last_name", "email",
+		"job_title", "feedback", "created_at", "version", "sentiment_score", "sentiment_label", "score", "cluster_id"}).AddRow(
+		id, firstName, lastName, email, jobTitle, feedback, createdAt, 1, nil, nil, nil, nil)
+	mock.ExpectQuery(regexp.QuoteMeta(queryGetUsersIn+"(?)")).WithArgs(DefaultTenant, id).WillReturnRows(rows)
+
+	resolver := &queryResolver{Resolver: &Resolver{logger: logger, db: db}}
+	node, err := resolver.Node(context.Background(), toGlobalID(userFeedbackType, id))
+	assert.NoError(t, err)
+	assert.Equal(t, id, node.(*model.UserFeedback).ID)
+
+	_, err = resolver.Node(context.Background(), "not-a-global-id")
+	assert.Equal(t, ErrInvalidGlobalID, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func mockUserSavePrepareError(t *testing.T) *mockDB {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
+	mock.ExpectBegin()
+	expectCandidates(mock, sqlmock.NewRows(candidateColumns))
 	mock.ExpectPrepare("INSERT INTO").
 		WillReturnError(errFailedDBOperation)
+	mock.ExpectRollback()
 
 	return &mockDB{
 		db:   db,
@@ -260,9 +470,12 @@ func mockUserSavePrepareError(t *testing.T) *mockDB {
 func mockUserSaveStatementError(t *testing.T) *mockDB {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
+	mock.ExpectBegin()
+	expectCandidates(mock, sqlmock.NewRows(candidateColumns))
 	mock.ExpectPrepare("INSERT INTO").WillBeClosed()
 	mock.ExpectExec("INSERT INTO").
 		WillReturnError(errFailedDBOperation)
+	mock.ExpectRollback()
 
 	return &mockDB{
 		db:   db,
@@ -274,9 +487,13 @@ func mockUserSaveStatementSuccess(t *testing.T) *mockDB {
 	db, mock, err := sqlmock.New()
 	result := sqlmock.NewResult(1, 1)
 	assert.NoError(t, err)
+	mock.ExpectBegin()
+	expectCandidates(mock, sqlmock.NewRows(candidateColumns))
 	mock.ExpectPrepare("INSERT INTO").WillBeClosed()
 	mock.ExpectExec("INSERT INTO").
 		WillReturnResult(result)
+	expectSaveSignature(mock, sqlmock.AnyArg())
+	mock.ExpectCommit()
 
 	return &mockDB{
 		db:   db,
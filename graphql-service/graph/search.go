@@ -0,0 +1,149 @@
+package graph
+
+import (
+	"database/sql"
+	"errors"
+	"html"
+	"strings"
+
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+)
+
+// user_feedback_search is an fts5 table kept in sync with user_feedback by triggers,
+// the sqlite driver has to be built with the sqlite_fts5 tag for it to exist.
+var (
+	querySearchUsers = `SELECT id, first_name, last_name, email, job_title, feedback, created_at, version, sentiment_score, sentiment_label, score, cluster_id, rank, snippet FROM (
+		SELECT f.id, f.first_name, f.last_name, f.email, f.job_title, f.feedback, f.created_at, f.version,
+			f.sentiment_score, f.sentiment_label, f.score, f.cluster_id,
+			s.rank AS rank, snippet(user_feedback_search, 1, char(57344), char(57345), '…', 16) AS snippet
+		FROM user_feedback_search s JOIN user_feedback f ON f.id = s.id
+		WHERE user_feedback_search MATCH ? AND f.tenant = ?
+	)`
+	querySearchUsersAfter = ` WHERE (rank > ? OR (rank = ? AND id > ?))`
+	querySearchUsersOrder = ` ORDER BY rank, id LIMIT ?`
+	queryCountSearchUsers = `SELECT COUNT(*) FROM user_feedback_search s JOIN user_feedback f ON f.id = s.id WHERE user_feedback_search MATCH ? AND f.tenant = ?`
+)
+
+// matches are marked in snippets with private use characters so that the feedback text can be escaped
+// before the <mark> tags are added, a feedback that contains the characters itself only gets extra tags.
+const (
+	snippetMatchStart = "\ue000"
+	snippetMatchEnd   = "\ue001"
+)
+
+var snippetMarkup = strings.NewReplacer(snippetMatchStart, "<mark>", snippetMatchEnd, "</mark>")
+
+// ErrInvalidSearchQuery means that the search text is not valid fts5 query syntax
+var ErrInvalidSearchQuery = errors.New("invalid search query, use words, \"phrases\", prefix* and AND/OR/NOT")
+
+// searchResult is one matching feedback together with its relevance
+type searchResult struct {
+	feedback *model.UserFeedback
+	rank     float64
+	snippet  string
+}
+
+// searchUsers fetches one page of the tenant's feedback matching the full-text query, most relevant first
+func searchUsers(db *sql.DB, tenant, query string, page pageQuery) ([]*searchResult, error) {
+	// cursors of GetUserFeedback carry the values of their ordering instead of a rank
+	if page.cursor != nil && (page.cursor.Order != "" || len(page.cursor.Values) > 0) {
+		return nil, ErrInvalidCursor
+	}
+
+	q := querySearchUsers
+	args := []interface{}{query, tenant}
+	if page.cursor != nil {
+		q += querySearchUsersAfter
+		args = append(args, page.cursor.Rank, page.cursor.Rank, page.cursor.ID)
+	}
+	args = append(args, page.limit+1)
+
+	rows, err := db.Query(q+querySearchUsersOrder, args...)
+	if err != nil {
+		return nil, searchError(err)
+	}
+	defer rows.Close()
+
+	var results []*searchResult
+	for rows.Next() {
+		user := model.UserFeedback{}
+		result := &searchResult{feedback: &user}
+		var sentiment nullSentiment
+		err := rows.Scan(
+			&user.ID, &user.FirstName,
+			&user.LastName, &user.Email,
+			&user.JobTitle, &user.Feedback,
+			&user.CreateAt, &user.Version,
+			&sentiment.score, &sentiment.label,
+			&user.Score, &user.ClusterID,
+			&result.rank, &result.snippet,
+		)
+		if err != nil {
+			return nil, err
+		}
+		user.Sentiment = sentiment.value()
+		result.snippet = highlightSnippet(result.snippet)
+		results = append(results, result)
+	}
+
+	return results, searchError(rows.Err())
+}
+
+// highlightSnippet escapes the feedback text of a snippet and wraps the matches in <mark> tags
+func highlightSnippet(snippet string) string {
+	return snippetMarkup.Replace(html.EscapeString(snippet))
+}
+
+// countSearchUsers returns the number of the tenant's feedback matching the full-text query
+func countSearchUsers(db *sql.DB, tenant, query string) (int, error) {
+	var count int
+	err := db.QueryRow(queryCountSearchUsers, query, tenant).Scan(&count)
+	return count, searchError(err)
+}
+
+// searchError turns fts5 syntax errors into an error the client can act on,
+// "word:" and "-word" are read by fts5 as column filters so unknown columns are syntax errors too.
+func searchError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	msg := err.Error()
+	if strings.HasPrefix(msg, "fts5:") || strings.HasPrefix(msg, "no such column:") {
+		return ErrInvalidSearchQuery
+	}
+
+	return err
+}
+
+func newUserFeedbackSearchConnection(results []*searchResult, page pageQuery, totalCount int) *model.UserFeedbackSearchConnection {
+	hasMore := len(results) > page.limit
+	if hasMore {
+		results = results[:page.limit]
+	}
+
+	edges := make([]*model.UserFeedbackSearchEdge, 0, len(results))
+	for _, result := range results {
+		edges = append(edges, &model.UserFeedbackSearchEdge{
+			Cursor:  encodeCursor(cursor{Rank: result.rank, ID: result.feedback.ID}),
+			Node:    result.feedback,
+			Rank:    result.rank,
+			Snippet: result.snippet,
+		})
+	}
+
+	pageInfo := &model.PageInfo{
+		HasNextPage:     hasMore,
+		HasPreviousPage: page.cursor != nil,
+	}
+	if len(edges) > 0 {
+		pageInfo.StartCursor = &edges[0].Cursor
+		pageInfo.EndCursor = &edges[len(edges)-1].Cursor
+	}
+
+	return &model.UserFeedbackSearchConnection{
+		Edges:      edges,
+		PageInfo:   pageInfo,
+		TotalCount: totalCount,
+	}
+}
@@ -0,0 +1,121 @@
+//go:build sqlite_fts5
+
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"github.com/stretchr/testify/assert"
+
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+)
+
+// migratedDB returns a sqlite database with every migration applied, the fts5 table needs the sqlite_fts5 tag
+func migratedDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "feedback.sqlite"))
+	assert.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	driver, err := sqlite3.WithInstance(db, &sqlite3.Config{})
+	assert.NoError(t, err)
+	m, err := migrate.NewWithDatabaseInstance("file://../migrations", "sqlite3", driver)
+	assert.NoError(t, err)
+	assert.NoError(t, m.Up())
+
+	return db
+}
+
+func TestSearchUserFeedbackSQLite(t *testing.T) {
+	db := migratedDB(t)
+	insert := func(id, tenant, text string) {
+		_, err := db.Exec(`INSERT INTO user_feedback (id, first_name, last_name, email, feedback, created_at, tenant) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			id, firstName, lastName, email, text, createdAt, tenant)
+		assert.NoError(t, err)
+	}
+	insert("1", DefaultTenant, "the new design of the billing page is great")
+	insert("2", DefaultTenant, "billing billing billing, I was billed twice")
+	insert("3", DefaultTenant, "the design is new but the refund took weeks")
+	insert("4", DefaultTenant, "billing <script>alert(1)</script> broke")
+	insert("5", "team-b", "billing for another tenant")
+
+	resolver := &queryResolver{Resolver: &Resolver{logger: logger, db: db}}
+	search := func(query string, first *int, after *string) ([]string, []float64) {
+		conn, err := resolver.SearchUserFeedback(context.Background(), query, first, after)
+		assert.NoError(t, err)
+		var ids []string
+		var ranks []float64
+		for _, edge := range conn.Edges {
+			ids = append(ids, edge.Node.ID)
+			ranks = append(ranks, edge.Rank)
+		}
+		return ids, ranks
+	}
+
+	scenarios := []struct {
+		query    string
+		expected []string
+	}{
+		{query: `"new design"`, expected: []string{"1"}},
+		{query: `bill*`, expected: []string{"1", "2", "4"}},
+		{query: `design AND refund`, expected: []string{"3"}},
+		{query: `design OR twice`, expected: []string{"1", "2", "3"}},
+		{query: `billing NOT design`, expected: []string{"2", "4"}},
+	}
+	for _, scenario := range scenarios {
+		ids, _ := search(scenario.query, nil, nil)
+		assert.ElementsMatch(t, scenario.expected, ids, scenario.query)
+	}
+
+	// the most relevant feedback comes first and the cursor continues after it
+	all, ranks := search("billing", nil, nil)
+	assert.Equal(t, "2", all[0])
+	assert.IsNonDecreasing(t, ranks)
+	one := 1
+	var paged []string
+	var after *string
+	for {
+		conn, err := resolver.SearchUserFeedback(context.Background(), "billing", &one, after)
+		assert.NoError(t, err)
+		for _, edge := range conn.Edges {
+			paged = append(paged, edge.Node.ID)
+		}
+		if !conn.PageInfo.HasNextPage {
+			break
+		}
+		after = conn.PageInfo.EndCursor
+	}
+	assert.Equal(t, all, paged)
+
+	conn, err := resolver.SearchUserFeedback(context.Background(), "script", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "billing &lt;<mark>script</mark>&gt;alert(1)&lt;/<mark>script</mark>&gt; broke", conn.Edges[0].Snippet)
+
+	// edits and deletes are reflected in the index by triggers
+	_, err = db.Exec(`UPDATE user_feedback SET feedback = ? WHERE id = ?`, "the refund was quick", "2")
+	assert.NoError(t, err)
+	_, err = db.Exec(`DELETE FROM user_feedback WHERE id = ?`, "4")
+	assert.NoError(t, err)
+	ids, _ := search("billing", nil, nil)
+	assert.Equal(t, []string{"1"}, ids)
+	ids, _ = search("quick", nil, nil)
+	assert.Equal(t, []string{"2"}, ids)
+
+	_, err = resolver.SearchUserFeedback(context.Background(), "(", nil, nil)
+	assert.Equal(t, ErrInvalidSearchQuery, err)
+}
+
+func TestSearchUserFeedbackRejectsOrderedCursor(t *testing.T) {
+	resolver := &queryResolver{Resolver: &Resolver{logger: logger, db: migratedDB(t)}}
+	order, err := newOrdering(nil)
+	assert.NoError(t, err)
+	ordered := encodeCursor(order.cursorOf(&model.UserFeedback{ID: id, CreateAt: &createdAt}))
+
+	_, err = resolver.SearchUserFeedback(context.Background(), "billing", nil, &ordered)
+	assert.Equal(t, ErrInvalidCursor, err)
+}
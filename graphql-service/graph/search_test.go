@@ -0,0 +1,84 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryResolverSearchUserFeedback(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		query       string
+		mockDB      *mockDB
+		edges       int
+		expectedErr error
+	}{
+		{
+			name:  "invalid fts5 syntax",
+			query: "(",
+			mockDB: func() *mockDB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(regexp.QuoteMeta(queryCountSearchUsers)).
+					WithArgs("(", DefaultTenant).
+					WillReturnError(errors.New(`fts5: syntax error near ""`))
+				return &mockDB{
+					db:   db,
+					mock: mock,
+				}
+			}(),
+			expectedErr: ErrInvalidSearchQuery,
+		},
+		{
+			name:  "ranked results with snippets",
+			query: "bill*",
+			mockDB: func() *mockDB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(regexp.QuoteMeta(queryCountSearchUsers)).
+					WithArgs("bill*", DefaultTenant).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				rows := sqlmock.NewRows([]string{"id", "first_name",
+					"last_name", "email",
+					"job_title", "feedback", "created_at", "version", "sentiment_score", "sentiment_label", "score", "cluster_id", "rank", "snippet"}).AddRow(
+					id, firstName, lastName, email, jobTitle, feedback, createdAt, 1, 0.6, "POSITIVE", 9, nil, -1.5, "the \ue000billing\ue001 page <img onerror=alert(1)>")
+				mock.ExpectQuery(regexp.QuoteMeta(querySearchUsers+querySearchUsersOrder)).
+					WithArgs("bill*", DefaultTenant, defaultPageSize+1).
+					WillReturnRows(rows)
+				return &mockDB{
+					db:   db,
+					mock: mock,
+				}
+			}(),
+			edges: 1,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			resolver := &queryResolver{
+				Resolver: &Resolver{
+					logger: logger,
+					db:     scenario.mockDB.db,
+				},
+			}
+			conn, err := resolver.SearchUserFeedback(context.Background(), scenario.query, nil, nil)
+			assert.Equal(t, scenario.expectedErr, err)
+			if conn != nil {
+				assert.Len(t, conn.Edges, scenario.edges)
+				assert.Equal(t, -1.5, conn.Edges[0].Rank)
+				assert.Equal(t, "the <mark>billing</mark> page &lt;img onerror=alert(1)&gt;", conn.Edges[0].Snippet)
+
+				c, err := decodeCursor(conn.Edges[0].Cursor)
+				assert.NoError(t, err)
+				assert.Equal(t, &cursor{Rank: -1.5, ID: id}, c)
+			}
+			assert.NoError(t, scenario.mockDB.mock.ExpectationsWereMet())
+		})
+	}
+}
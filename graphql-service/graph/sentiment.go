@@ -0,0 +1,102 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"github.com/riyadennis/sigist/graphql-service/graph/sentiment"
+)
+
+var (
+	queryUnscoredUsers = `SELECT id, feedback FROM user_feedback WHERE sentiment_score IS NULL AND id > ? ORDER BY id LIMIT ?`
+	queryScoreUser     = `UPDATE user_feedback SET sentiment_score = ?, sentiment_label = ? WHERE id = ? AND sentiment_score IS NULL`
+)
+
+// analyzeSentiment scores the tone of a feedback text
+func analyzeSentiment(text string) *model.Sentiment {
+	result := sentiment.Analyze(text)
+	return &model.Sentiment{Score: result.Score, Label: model.SentimentLabel(result.Label)}
+}
+
+// nullSentiment scans the sentiment columns, they are null until the feedback is scored
+type nullSentiment struct {
+	score sql.NullFloat64
+	label sql.NullString
+}
+
+func (s nullSentiment) value() *model.Sentiment {
+	if !s.score.Valid || !s.label.Valid {
+		return nil
+	}
+
+	return &model.Sentiment{Score: s.score.Float64, Label: model.SentimentLabel(s.label.String)}
+}
+
+// BackfillSentiment scores the feedback that was saved before sentiment analysis existed,
+// batchSize rows per transaction, and returns how many rows it scored. Versions are left
+// alone and no events are published since the feedback itself did not change.
+func BackfillSentiment(ctx context.Context, db *sql.DB, batchSize int) (int, error) {
+	scored, lastID := 0, ""
+	for {
+		n, last, err := backfillSentimentBatch(ctx, db, lastID, batchSize)
+		scored += n
+		if err != nil || last == "" {
+			return scored, err
+		}
+		lastID = last
+	}
+}
+
+// backfillSentimentBatch scores the next batch of unscored rows after lastID,
+// it returns an empty id once there is nothing left to score.
+func backfillSentimentBatch(ctx context.Context, db *sql.DB, lastID string, batchSize int) (int, string, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, queryUnscoredUsers, lastID, batchSize)
+	if err != nil {
+		return 0, "", err
+	}
+
+	type unscored struct {
+		id       string
+		feedback sql.NullString
+	}
+	var batch []unscored
+	for rows.Next() {
+		var u unscored
+		if err := rows.Scan(&u.id, &u.feedback); err != nil {
+			rows.Close()
+			return 0, "", err
+		}
+		batch = append(batch, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, "", err
+	}
+	if len(batch) == 0 {
+		return 0, "", nil
+	}
+
+	scored := 0
+	for _, u := range batch {
+		s := analyzeSentiment(u.feedback.String)
+		res, err := tx.ExecContext(ctx, queryScoreUser, s.Score, s.Label, u.id)
+		if err != nil {
+			return 0, "", err
+		}
+		// rows scored concurrently by a save or an update are skipped
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, "", err
+		}
+		scored += int(n)
+	}
+
+	return scored, batch[len(batch)-1].id, tx.Commit()
+}
@@ -0,0 +1,136 @@
+// Package sentiment scores the tone of short english texts with an embedded
+// word list, it runs offline and gives the same result for the same text.
+package sentiment
+
+import (
+	"bufio"
+	_ "embed"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Label is the coarse tone of a text
+type Label string
+
+const (
+	Positive Label = "POSITIVE"
+	Neutral  Label = "NEUTRAL"
+	Negative Label = "NEGATIVE"
+)
+
+const (
+	// threshold is how far from zero a score has to be to not be neutral
+	threshold = 0.05
+
+	// alpha controls how fast the normalised score approaches -1 or 1 as words add up
+	alpha = 15
+
+	// negationScope is how many words after a negation have their valence flipped
+	negationScope = 3
+
+	// boost is how much an intensifier strengthens the next scored word
+	boost = 1.5
+)
+
+//go:embed lexicon.txt
+var lexiconFile string
+
+var (
+	lexicon = parseLexicon(lexiconFile)
+
+	negations = map[string]bool{
+		"not": true, "no": true, "never": true, "nothing": true, "without": true, "hardly": true,
+		"isn't": true, "wasn't": true, "aren't": true, "don't": true, "doesn't": true, "didn't": true,
+		"can't": true, "cannot": true, "won't": true, "wouldn't": true, "couldn't": true, "shouldn't": true,
+	}
+
+	intensifiers = map[string]bool{
+		"very": true, "really": true, "extremely": true, "so": true, "super": true,
+		"incredibly": true, "totally": true, "absolutely": true,
+	}
+)
+
+// Result is the score of a text between -1 and 1 and its label
+type Result struct {
+	Score float64
+	Label Label
+}
+
+// Analyze scores the text, texts without any known word are neutral with a score of 0
+func Analyze(text string) Result {
+	var sum float64
+	negated, intensified := 0, false
+	for _, word := range words(text) {
+		if negations[word] {
+			negated = negationScope
+			continue
+		}
+		if intensifiers[word] {
+			intensified = true
+			continue
+		}
+
+		valence, ok := lexicon[word]
+		if ok {
+			if intensified {
+				valence *= boost
+			}
+			// "not good" is milder than "bad"
+			if negated > 0 {
+				valence = -valence / 2
+			}
+			sum += valence
+		}
+		intensified = false
+		if negated > 0 {
+			negated--
+		}
+	}
+
+	score := sum / math.Sqrt(sum*sum+alpha)
+	return Result{Score: math.Round(score*1000) / 1000, Label: LabelOf(score)}
+}
+
+// LabelOf returns the label of a score
+func LabelOf(score float64) Label {
+	switch {
+	case score >= threshold:
+		return Positive
+	case score <= -threshold:
+		return Negative
+	default:
+		return Neutral
+	}
+}
+
+// words splits the text into lower case words, apostrophes are kept so that contractions stay whole
+func words(text string) []string {
+	text = strings.ReplaceAll(strings.ToLower(text), "’", "'")
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+}
+
+func parseLexicon(file string) map[string]float64 {
+	words := make(map[string]float64)
+	scanner := bufio.NewScanner(strings.NewReader(file))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		word, value, ok := strings.Cut(line, "\t")
+		if !ok {
+			panic("sentiment: malformed lexicon line " + strconv.Quote(line))
+		}
+		valence, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			panic("sentiment: malformed lexicon line " + strconv.Quote(line))
+		}
+		words[word] = valence
+	}
+
+	return words
+}
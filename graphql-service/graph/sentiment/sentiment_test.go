@@ -0,0 +1,60 @@
+package sentiment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyze(t *testing.T) {
+	scenarios := []struct {
+		name          string
+		text          string
+		expectedLabel Label
+	}{
+		{
+			name:          "positive",
+			text:          "Great product, the new dashboard is really intuitive!",
+			expectedLabel: Positive,
+		},
+		{
+			name:          "negative",
+			text:          "The app crashes every time I upload a file, terrible.",
+			expectedLabel: Negative,
+		},
+		{
+			name:          "no known words",
+			text:          "I opened the settings page on Tuesday.",
+			expectedLabel: Neutral,
+		},
+		{
+			name:          "negation flips the following words",
+			text:          "Honestly it wasn’t very good",
+			expectedLabel: Negative,
+		},
+		{
+			name:          "empty",
+			expectedLabel: Neutral,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			result := Analyze(scenario.text)
+			assert.Equal(t, scenario.expectedLabel, result.Label)
+			assert.GreaterOrEqual(t, result.Score, -1.0)
+			assert.LessOrEqual(t, result.Score, 1.0)
+		})
+	}
+}
+
+func TestAnalyzeIntensifiers(t *testing.T) {
+	good := Analyze("the support was good")
+	veryGood := Analyze("the support was very good")
+	notGood := Analyze("the support was not good")
+	bad := Analyze("the support was bad")
+
+	assert.Greater(t, veryGood.Score, good.Score)
+	assert.Less(t, notGood.Score, 0.0)
+	assert.Greater(t, notGood.Score, bad.Score)
+}
@@ -0,0 +1,90 @@
+package graph
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackfillSentiment(t *testing.T) {
+	positive, negative := analyzeSentiment("great, thanks"), analyzeSentiment("it crashes")
+
+	scenarios := []struct {
+		name           string
+		mockDB         *mockDB
+		expectedScored int
+		expectedErr    error
+	}{
+		{
+			name: "scores every batch until nothing is left",
+			mockDB: func() *mockDB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryUnscoredUsers)).
+					WithArgs("", 2).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "feedback"}).
+						AddRow("1", "great, thanks").
+						AddRow("2", "it crashes"))
+				mock.ExpectExec(regexp.QuoteMeta(queryScoreUser)).
+					WithArgs(positive.Score, positive.Label, "1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(queryScoreUser)).
+					WithArgs(negative.Score, negative.Label, "2").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryUnscoredUsers)).
+					WithArgs("2", 2).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "feedback"}).
+						AddRow("3", nil))
+				// scored by an update since it was read
+				mock.ExpectExec(regexp.QuoteMeta(queryScoreUser)).
+					WithArgs(0.0, "NEUTRAL", "3").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryUnscoredUsers)).
+					WithArgs("3", 2).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "feedback"}))
+				mock.ExpectRollback()
+				return &mockDB{
+					db:   db,
+					mock: mock,
+				}
+			}(),
+			expectedScored: 2,
+		},
+		{
+			name: "failed batch is rolled back",
+			mockDB: func() *mockDB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryUnscoredUsers)).
+					WithArgs("", 2).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "feedback"}).AddRow("1", "great, thanks"))
+				mock.ExpectExec(regexp.QuoteMeta(queryScoreUser)).
+					WillReturnError(errFailedDBOperation)
+				mock.ExpectRollback()
+				return &mockDB{
+					db:   db,
+					mock: mock,
+				}
+			}(),
+			expectedErr: errFailedDBOperation,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			scored, err := BackfillSentiment(context.Background(), scenario.mockDB.db, 2)
+			assert.Equal(t, scenario.expectedErr, err)
+			assert.Equal(t, scenario.expectedScored, scored)
+			assert.NoError(t, scenario.mockDB.mock.ExpectationsWereMet())
+		})
+	}
+}
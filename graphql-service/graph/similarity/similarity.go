@@ -0,0 +1,174 @@
+// Package similarity estimates how alike two texts are with MinHash signatures of their
+// character shingles, and buckets signatures so that similar texts can be found without
+// comparing every pair. Signatures are stable across runs so they can be stored.
+package similarity
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+const (
+	// NumHashes is the length of a signature, the estimate is off by about 1/sqrt(NumHashes)
+	NumHashes = 128
+
+	// Bands is how many buckets a signature is split into, two texts become candidates
+	// when all the rows of one band match. With 4 rows per band texts that are 40% alike
+	// have an even chance to be found and texts that are 70% alike almost always are.
+	Bands = 32
+
+	rows = NumHashes / Bands
+
+	// shingleSize is the number of characters in one shingle, words are compared
+	// across their boundaries so a changed word only changes a few shingles
+	shingleSize = 5
+)
+
+// ErrInvalidSignature means that stored bytes are not a signature
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// seeds pick the hash functions of the signature, they must never change
+// or stored signatures stop matching new ones.
+var seeds = func() [NumHashes]uint64 {
+	var s [NumHashes]uint64
+	state := uint64(0x5eed)
+	for i := range s {
+		state += 0x9e3779b97f4a7c15
+		s[i] = mix(state)
+	}
+	return s
+}()
+
+// Signature is the minimum of every hash function over the shingles of a text
+type Signature [NumHashes]uint32
+
+// Sign computes the signature of a text, case, punctuation and spacing are ignored
+func Sign(text string) Signature {
+	var sig Signature
+	for i := range sig {
+		sig[i] = math.MaxUint32
+	}
+
+	for _, shingle := range shingles(text) {
+		for i, seed := range seeds {
+			if h := uint32(mix(shingle^seed) >> 32); h < sig[i] {
+				sig[i] = h
+			}
+		}
+	}
+
+	return sig
+}
+
+// Empty is true for texts without letters or digits, they are not similar to anything
+func (s Signature) Empty() bool {
+	for _, h := range s {
+		if h != math.MaxUint32 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Similarity estimates the jaccard similarity of the shingles of two texts, between 0 and 1
+func (s Signature) Similarity(other Signature) float64 {
+	if s.Empty() || other.Empty() {
+		return 0
+	}
+
+	same := 0
+	for i := range s {
+		if s[i] == other[i] {
+			same++
+		}
+	}
+
+	return float64(same) / NumHashes
+}
+
+// Bands hashes every band of the signature, signatures sharing a band hash are candidates
+func (s Signature) Bands() [Bands]int64 {
+	var bands [Bands]int64
+	buf := make([]byte, 4*rows)
+	for b := range bands {
+		for r := 0; r < rows; r++ {
+			binary.BigEndian.PutUint32(buf[4*r:], s[b*rows+r])
+		}
+		h := fnv.New64a()
+		h.Write(buf)
+		bands[b] = int64(h.Sum64())
+	}
+
+	return bands
+}
+
+// Bytes encodes the signature for storage
+func (s Signature) Bytes() []byte {
+	buf := make([]byte, 4*NumHashes)
+	for i, h := range s {
+		binary.BigEndian.PutUint32(buf[4*i:], h)
+	}
+
+	return buf
+}
+
+// Parse decodes a signature encoded with Bytes
+func Parse(data []byte) (Signature, error) {
+	var sig Signature
+	if len(data) != 4*NumHashes {
+		return sig, ErrInvalidSignature
+	}
+	for i := range sig {
+		sig[i] = binary.BigEndian.Uint32(data[4*i:])
+	}
+
+	return sig, nil
+}
+
+// shingles hashes every run of shingleSize characters of the normalised text,
+// texts shorter than that are one shingle.
+func shingles(text string) []uint64 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	runes := []rune(strings.Join(words, " "))
+	if len(runes) == 0 {
+		return nil
+	}
+	if len(runes) < shingleSize {
+		return []uint64{hashString(string(runes))}
+	}
+
+	seen := make(map[uint64]bool, len(runes))
+	hashes := make([]uint64, 0, len(runes))
+	for i := 0; i+shingleSize <= len(runes); i++ {
+		h := hashString(string(runes[i : i+shingleSize]))
+		if !seen[h] {
+			seen[h] = true
+			hashes = append(hashes, h)
+		}
+	}
+
+	return hashes
+}
+
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// mix is the splitmix64 finaliser, it spreads every input bit over the whole output
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
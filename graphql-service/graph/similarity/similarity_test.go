@@ -0,0 +1,63 @@
+package similarity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimilarity(t *testing.T) {
+	base := "The app crashes every time I try to log in on my phone"
+
+	scenarios := []struct {
+		name     string
+		other    string
+		min, max float64
+	}{
+		{name: "same text", other: base, min: 1, max: 1},
+		{name: "case, punctuation and spacing", other: "the APP crashes, every time I try to log in on my phone!!", min: 1, max: 1},
+		{name: "slightly different wording", other: "The app crashes every single time I try to login on my phone", min: 0.5, max: 0.95},
+		{name: "unrelated", other: "Great support team, they answered my billing question quickly", min: 0, max: 0.15},
+		{name: "no words", other: "?!", min: 0, max: 0},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			similarity := Sign(base).Similarity(Sign(scenario.other))
+			assert.GreaterOrEqual(t, similarity, scenario.min)
+			assert.LessOrEqual(t, similarity, scenario.max)
+		})
+	}
+}
+
+func TestBandsMatchForNearDuplicates(t *testing.T) {
+	a := Sign("Checkout keeps failing when I pay with my credit card")
+	b := Sign("Checkout keeps failing when I pay with a credit card")
+	c := Sign("I love the new dark mode")
+
+	shared := func(x, y Signature) int {
+		n := 0
+		bx, by := x.Bands(), y.Bands()
+		for i := range bx {
+			if bx[i] == by[i] {
+				n++
+			}
+		}
+		return n
+	}
+	assert.Greater(t, shared(a, b), 0)
+	assert.Equal(t, 0, shared(a, c))
+}
+
+func TestSignatureBytes(t *testing.T) {
+	sig := Sign("short")
+	parsed, err := Parse(sig.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, sig, parsed)
+
+	_, err = Parse([]byte("nope"))
+	assert.Equal(t, ErrInvalidSignature, err)
+
+	assert.True(t, Sign("").Empty())
+	assert.False(t, Sign("ok").Empty())
+}
@@ -0,0 +1,143 @@
+package graph
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+)
+
+var (
+	queryJobTitleFacets = `SELECT job_title, COUNT(*) FROM user_feedback`
+	groupByJobTitle     = ` GROUP BY job_title ORDER BY COUNT(*) DESC, job_title`
+)
+
+// ErrInvalidGroupBy means that groupBy names the same dimension more than once
+var ErrInvalidGroupBy = errors.New("groupBy can only use each dimension once")
+
+// groupColumns is the sql each dimension groups by, created_at is stored
+// as RFC3339 in UTC so days and weeks are UTC too.
+var groupColumns = map[model.FeedbackGroupBy]string{
+	model.FeedbackGroupByJobTitle: "job_title",
+	model.FeedbackGroupByDay:      "strftime('%Y-%m-%dT00:00:00Z', created_at)",
+	// 'weekday 0' moves forward to the next sunday or stays on one, so weeks start on monday
+	model.FeedbackGroupByWeek:        "strftime('%Y-%m-%dT00:00:00Z', created_at, 'weekday 0', '-6 days')",
+	model.FeedbackGroupByEmailDomain: "lower(substr(email, instr(email, '@') + 1))",
+}
+
+// countGroups counts the tenant's feedback matching the filter per combination of the dimensions,
+// without dimensions there is one group with every matching feedback.
+func countGroups(db *sql.DB, tenant string, filter model.FilterInput, groupBy []model.FeedbackGroupBy) ([]*model.FeedbackGroup, error) {
+	columns, err := groupByColumns(groupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions, args, err := filterConditions(tenant, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	q := queryCountUsers + where(conditions)
+	if len(columns) > 0 {
+		keys := strings.Join(columns, ", ")
+		q = "SELECT " + keys + ", COUNT(*) FROM user_feedback" + where(conditions) +
+			" GROUP BY " + keys + " ORDER BY " + keys
+	}
+
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []*model.FeedbackGroup
+	for rows.Next() {
+		keys := make([]sql.NullString, len(groupBy))
+		dest := make([]interface{}, 0, len(groupBy)+1)
+		for i := range keys {
+			dest = append(dest, &keys[i])
+		}
+
+		group := &model.FeedbackGroup{}
+		if err := rows.Scan(append(dest, &group.Count)...); err != nil {
+			return nil, err
+		}
+		for i, dimension := range groupBy {
+			if err := setGroupKey(group, dimension, keys[i]); err != nil {
+				return nil, err
+			}
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, rows.Err()
+}
+
+// groupByColumns returns the sql of each dimension, in the order they were asked for
+func groupByColumns(groupBy []model.FeedbackGroupBy) ([]string, error) {
+	seen := make(map[model.FeedbackGroupBy]bool, len(groupBy))
+	columns := make([]string, 0, len(groupBy))
+	for _, dimension := range groupBy {
+		if seen[dimension] {
+			return nil, ErrInvalidGroupBy
+		}
+		seen[dimension] = true
+		columns = append(columns, groupColumns[dimension])
+	}
+
+	return columns, nil
+}
+
+func setGroupKey(group *model.FeedbackGroup, dimension model.FeedbackGroupBy, key sql.NullString) error {
+	if !key.Valid {
+		return nil
+	}
+
+	value := key.String
+	switch dimension {
+	case model.FeedbackGroupByJobTitle:
+		group.JobTitle = &value
+	case model.FeedbackGroupByEmailDomain:
+		group.EmailDomain = &value
+	case model.FeedbackGroupByDay, model.FeedbackGroupByWeek:
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return err
+		}
+		if dimension == model.FeedbackGroupByDay {
+			group.Day = &t
+		} else {
+			group.Week = &t
+		}
+	}
+
+	return nil
+}
+
+// jobTitleFacets lists the distinct job titles of the tenant's feedback matching the filter, most common first
+func jobTitleFacets(db *sql.DB, tenant string, filter model.FilterInput) ([]*model.FacetCount, error) {
+	conditions, args, err := filterConditions(tenant, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(queryJobTitleFacets+where(conditions)+groupByJobTitle, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	facets := []*model.FacetCount{}
+	for rows.Next() {
+		facet := &model.FacetCount{}
+		if err := rows.Scan(&facet.Value, &facet.Count); err != nil {
+			return nil, err
+		}
+		facets = append(facets, facet)
+	}
+
+	return facets, rows.Err()
+}
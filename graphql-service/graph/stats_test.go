@@ -0,0 +1,144 @@
+package graph
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeedbackStatsResolverGroups(t *testing.T) {
+	day := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	domain := "test.com"
+
+	scenarios := []struct {
+		name           string
+		stats          *model.FeedbackStats
+		mockDB         *mockDB
+		expectedGroups []*model.FeedbackGroup
+		expectedErr    error
+	}{
+		{
+			name:  "no dimensions counts everything",
+			stats: &model.FeedbackStats{},
+			mockDB: func() *mockDB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(regexp.QuoteMeta(queryCountUsers + " WHERE tenant = ?")).
+					WithArgs(DefaultTenant).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+				return &mockDB{
+					db:   db,
+					mock: mock,
+				}
+			}(),
+			expectedGroups: []*model.FeedbackGroup{{Count: 3}},
+		},
+		{
+			name: "filtered by job title per day and email domain",
+			stats: &model.FeedbackStats{
+				Filter:  model.FilterInput{JobTitle: &model.StringFilter{Eq: &jobTitle}},
+				GroupBy: []model.FeedbackGroupBy{model.FeedbackGroupByDay, model.FeedbackGroupByEmailDomain},
+			},
+			mockDB: func() *mockDB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT "+
+					groupColumns[model.FeedbackGroupByDay]+", "+groupColumns[model.FeedbackGroupByEmailDomain]+
+					", COUNT(*) FROM user_feedback WHERE tenant = ? AND job_title = ? GROUP BY")).
+					WithArgs(DefaultTenant, jobTitle).
+					WillReturnRows(sqlmock.NewRows([]string{"day", "domain", "count"}).
+						AddRow("2023-05-01T00:00:00Z", domain, 2).
+						AddRow("2023-05-01T00:00:00Z", nil, 1))
+				return &mockDB{
+					db:   db,
+					mock: mock,
+				}
+			}(),
+			expectedGroups: []*model.FeedbackGroup{
+				{Day: &day, EmailDomain: &domain, Count: 2},
+				{Day: &day, Count: 1},
+			},
+		},
+		{
+			name: "same dimension twice",
+			stats: &model.FeedbackStats{
+				GroupBy: []model.FeedbackGroupBy{model.FeedbackGroupByWeek, model.FeedbackGroupByWeek},
+			},
+			mockDB: func() *mockDB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				return &mockDB{
+					db:   db,
+					mock: mock,
+				}
+			}(),
+			expectedErr: ErrInvalidGroupBy,
+		},
+		{
+			name:  "db failure",
+			stats: &model.FeedbackStats{GroupBy: []model.FeedbackGroupBy{model.FeedbackGroupByJobTitle}},
+			mockDB: func() *mockDB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT job_title, COUNT(*) FROM user_feedback WHERE tenant = ? GROUP BY job_title")).
+					WillReturnError(errFailedDBOperation)
+				return &mockDB{
+					db:   db,
+					mock: mock,
+				}
+			}(),
+			expectedErr: errFailedDBOperation,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			resolver := &feedbackStatsResolver{
+				Resolver: &Resolver{
+					logger: logger,
+					db:     scenario.mockDB.db,
+				},
+			}
+			groups, err := resolver.Groups(context.Background(), scenario.stats)
+			assert.Equal(t, scenario.expectedErr, err)
+			assert.Equal(t, scenario.expectedGroups, groups)
+			assert.NoError(t, scenario.mockDB.mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestFeedbackStatsResolverFacets(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	mock.ExpectQuery(regexp.QuoteMeta(queryJobTitleFacets+" WHERE tenant = ? AND email = ?"+groupByJobTitle)).
+		WithArgs(DefaultTenant, email).
+		WillReturnRows(sqlmock.NewRows([]string{"job_title", "count"}).
+			AddRow(jobTitle, 2).
+			AddRow(nil, 1))
+
+	resolver := &feedbackStatsResolver{
+		Resolver: &Resolver{
+			logger: logger,
+			db:     db,
+		},
+	}
+	facets, err := resolver.Facets(context.Background(), &model.FeedbackStats{
+		Filter: model.FilterInput{Email: &model.StringFilter{Eq: &email}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []*model.FacetCount{{Value: &jobTitle, Count: 2}, {Count: 1}}, facets.JobTitles)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestQueryResolverFeedbackStatsInvalidFilter(t *testing.T) {
+	resolver := &queryResolver{Resolver: &Resolver{logger: logger}}
+	_, err := resolver.FeedbackStats(context.Background(), &model.FilterInput{
+		CreatedWithin: &model.TimeWindow{Last: 0, Unit: model.TimeUnitDay},
+	}, nil)
+	assert.Equal(t, ErrInvalidTimeWindow, err)
+}
@@ -0,0 +1,180 @@
+package graph
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"go.uber.org/zap"
+)
+
+var (
+	queryInsertTag     = `INSERT OR IGNORE INTO tags (name) VALUES (?)`
+	queryTagFeedback   = `INSERT OR IGNORE INTO feedback_tags (feedback_id, tag_id, created_at) SELECT ?, id, ? FROM tags WHERE name = ?`
+	queryUntagFeedback = `DELETE FROM feedback_tags WHERE feedback_id = ? AND tag_id = (SELECT id FROM tags WHERE name = ?)`
+	queryTagsIn        = `SELECT ft.feedback_id, t.name FROM feedback_tags ft JOIN tags t ON t.id = ft.tag_id WHERE ft.feedback_id IN `
+	orderTagsByName    = ` ORDER BY t.name`
+	queryTaggedIn      = `id IN (SELECT ft.feedback_id FROM feedback_tags ft JOIN tags t ON t.id = ft.tag_id WHERE t.name IN `
+	havingEveryTag     = ` GROUP BY ft.feedback_id HAVING COUNT(*) = ?`
+)
+
+// ErrInvalidTag means that a tag is empty, too long or uses characters other than letters, digits, - and _
+var ErrInvalidTag = errors.New("tags can only use letters, digits, - and _ and be at most 50 characters long")
+
+var tagPattern = regexp.MustCompile(`^[a-z0-9_-]{1,50}$`)
+
+// normalizeTags lower cases and de-duplicates the tags, it fails if one of them is not a valid tag
+func normalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !tagPattern.MatchString(tag) {
+			return nil, ErrInvalidTag
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	sort.Strings(normalized)
+
+	return normalized, nil
+}
+
+// changeTags applies a tag change to the feedback with the global id and returns the feedback,
+// an event is only published when a tag was actually added or removed.
+func (r *Resolver) changeTags(ctx context.Context, id string, tags []string, eventType string,
+	change func(tenant, dbID string, tags []string) ([]string, error)) (*model.UserFeedback, error) {
+	dbID, err := feedbackID(id)
+	if err != nil {
+		return nil, err
+	}
+	tags, err = normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	tenant := tenantFrom(ctx)
+	changed, err := change(tenant, dbID, tags)
+	if err != nil {
+		r.logger.Error("failed to change tags", zap.String("id", dbID), zap.Error(err))
+		return nil, err
+	}
+	r.loaders(ctx).Clear()
+
+	if len(changed) > 0 {
+		err = r.publish(tenant, eventType, dbID, TagsChanged{ID: dbID, Tags: changed})
+		if err != nil {
+			r.logger.Error("failed to publish to  kafka", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	feedback, err := r.loaders(ctx).FeedbackByID.Load(ctx, dbID)
+	if err != nil {
+		r.logger.Error("failed to fetch feedback", zap.String("id", dbID), zap.Error(err))
+		return nil, err
+	}
+	if feedback == nil {
+		return nil, ErrFeedbackNotFound
+	}
+
+	return feedback, nil
+}
+
+// addFeedbackTags tags the tenant's feedback and returns the tags it didn't have before
+func addFeedbackTags(db *sql.DB, tenant, id string, tags []string, createdAt string) ([]string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := checkFeedbackExists(tx, tenant, id); err != nil {
+		return nil, err
+	}
+
+	var added []string
+	for _, tag := range tags {
+		if _, err := tx.Exec(queryInsertTag, tag); err != nil {
+			return nil, err
+		}
+		res, err := tx.Exec(queryTagFeedback, id, createdAt, tag)
+		if err != nil {
+			return nil, err
+		}
+		if changed, err := res.RowsAffected(); err != nil {
+			return nil, err
+		} else if changed > 0 {
+			added = append(added, tag)
+		}
+	}
+
+	return added, tx.Commit()
+}
+
+// removeFeedbackTags untags the tenant's feedback and returns the tags it had
+func removeFeedbackTags(db *sql.DB, tenant, id string, tags []string) ([]string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := checkFeedbackExists(tx, tenant, id); err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for _, tag := range tags {
+		res, err := tx.Exec(queryUntagFeedback, id, tag)
+		if err != nil {
+			return nil, err
+		}
+		if changed, err := res.RowsAffected(); err != nil {
+			return nil, err
+		} else if changed > 0 {
+			removed = append(removed, tag)
+		}
+	}
+
+	return removed, tx.Commit()
+}
+
+func checkFeedbackExists(q querier, tenant, id string) error {
+	var count int
+	if err := q.QueryRow(queryUserExists, tenant, id).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrFeedbackNotFound
+	}
+
+	return nil
+}
+
+// getTagsByFeedbackIDs fetches the tags of every feedback in a single query,
+// feedback without tags is left out of the result.
+func getTagsByFeedbackIDs(db *sql.DB, ids []string) (map[string][]string, error) {
+	rows, err := db.Query(queryTagsIn+placeholders(len(ids))+orderTagsByName, stringArgs(ids)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make(map[string][]string, len(ids))
+	for rows.Next() {
+		var id, tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return nil, err
+		}
+		tags[id] = append(tags[id], tag)
+	}
+
+	return tags, rows.Err()
+}
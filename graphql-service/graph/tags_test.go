@@ -0,0 +1,189 @@
+package graph
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	scenarios := []struct {
+		name         string
+		in           []string
+		expectedTags []string
+		expectedErr  error
+	}{
+		{name: "lower cased, trimmed, sorted and de-duplicated", in: []string{" UX", "billing", "ux"}, expectedTags: []string{"billing", "ux"}},
+		{name: "empty list", in: []string{}, expectedTags: []string{}},
+		{name: "blank tag", in: []string{" "}, expectedErr: ErrInvalidTag},
+		{name: "spaces inside", in: []string{"needs triage"}, expectedErr: ErrInvalidTag},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			tags, err := normalizeTags(scenario.in)
+			assert.Equal(t, scenario.expectedErr, err)
+			assert.Equal(t, scenario.expectedTags, tags)
+		})
+	}
+}
+
+func TestMutationResolverAddFeedbackTags(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		tags           []string
+		mockDB         *mockDB
+		expectedTagged []string
+		expectedErr    error
+	}{
+		{
+			name: "only tags the feedback did not have are published",
+			tags: []string{"Billing", "ux"},
+			mockDB: func() *mockDB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryUserExists)).WithArgs(DefaultTenant, id).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectExec(regexp.QuoteMeta(queryInsertTag)).WithArgs("billing").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(regexp.QuoteMeta(queryTagFeedback)).WithArgs(id, sqlmock.AnyArg(), "billing").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta(queryInsertTag)).WithArgs("ux").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta(queryTagFeedback)).WithArgs(id, sqlmock.AnyArg(), "ux").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+				mock.ExpectQuery(regexp.QuoteMeta(queryGetUsersIn+"(?)")).WithArgs(DefaultTenant, id).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "first_name",
+						"last_name", "email",
+						"job_title", "feedback", "created_at", "version", "sentiment_score", "sentiment_label", "score", "cluster_id"}).AddRow(
+						id, firstName, lastName, email, jobTitle, feedback, createdAt, 1, nil, nil, nil, nil))
+				return &mockDB{
+					db:   db,
+					mock: mock,
+				}
+			}(),
+			expectedTagged: []string{"billing"},
+		},
+		{
+			name: "missing feedback",
+			tags: []string{"ux"},
+			mockDB: func() *mockDB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(queryUserExists)).WithArgs(DefaultTenant, id).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				mock.ExpectRollback()
+				return &mockDB{
+					db:   db,
+					mock: mock,
+				}
+			}(),
+			expectedErr: ErrFeedbackNotFound,
+		},
+		{
+			name: "invalid tag",
+			tags: []string{"needs triage"},
+			mockDB: func() *mockDB {
+				db, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				return &mockDB{
+					db:   db,
+					mock: mock,
+				}
+			}(),
+			expectedErr: ErrInvalidTag,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			producer := &mockProducer{}
+			resolver := &mutationResolver{
+				Resolver: &Resolver{
+					logger: logger,
+					db:     scenario.mockDB.db,
+					KafkaConfig: &KafkaConfig{
+						Topic:    "test",
+						Producer: producer,
+					},
+				},
+			}
+			fb, err := resolver.AddFeedbackTags(context.Background(), toGlobalID(userFeedbackType, id), scenario.tags)
+			assert.Equal(t, scenario.expectedErr, err)
+			if scenario.expectedTagged != nil {
+				assert.Equal(t, id, fb.ID)
+				assert.Len(t, producer.messages, 1)
+				assert.Equal(t, []byte(EventFeedbackTagged), producer.messages[0].Headers[0].Value)
+				assert.JSONEq(t, `{"id":"123","tags":["billing"]}`, string(producer.messages[0].Value))
+			} else {
+				assert.Empty(t, producer.messages)
+			}
+			assert.NoError(t, scenario.mockDB.mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMutationResolverRemoveFeedbackTagsUnchanged(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(queryUserExists)).WithArgs(DefaultTenant, id).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta(queryUntagFeedback)).WithArgs(id, "bug").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta(queryGetUsersIn+"(?)")).WithArgs(DefaultTenant, id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "first_name",
+			"last_name", "email",
+			"job_title", "feedback", "created_at", "version", "sentiment_score", "sentiment_label", "score", "cluster_id"}).AddRow(
+			id, firstName, lastName, email, jobTitle, feedback, createdAt, 1, nil, nil, nil, nil))
+
+	producer := &mockProducer{}
+	resolver := &mutationResolver{
+		Resolver: &Resolver{
+			logger:      logger,
+			db:          db,
+			KafkaConfig: &KafkaConfig{Topic: "test", Producer: producer},
+		},
+	}
+	_, err = resolver.RemoveFeedbackTags(context.Background(), toGlobalID(userFeedbackType, id), []string{"bug"})
+	assert.NoError(t, err)
+	// the feedback didn't have the tag so nothing changed
+	assert.Empty(t, producer.messages)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserFeedbackResolverTags(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	mock.ExpectQuery(regexp.QuoteMeta(queryTagsIn + "(?)" + orderTagsByName)).WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"feedback_id", "name"}).
+			AddRow(id, "billing").
+			AddRow(id, "ux"))
+
+	resolver := &userFeedbackResolver{Resolver: &Resolver{logger: logger, db: db}}
+	ctx := context.WithValue(context.Background(), loadersKey{}, NewLoaders(db, DefaultTenant))
+
+	tags, err := resolver.Tags(ctx, &model.UserFeedback{ID: id})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"billing", "ux"}, tags)
+
+	// cached for the rest of the request
+	tags, err = resolver.Tags(ctx, &model.UserFeedback{ID: id})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"billing", "ux"}, tags)
+
+	mock.ExpectQuery(regexp.QuoteMeta(queryTagsIn + "(?)" + orderTagsByName)).WithArgs("untagged").
+		WillReturnRows(sqlmock.NewRows([]string{"feedback_id", "name"}))
+	tags, err = resolver.Tags(ctx, &model.UserFeedback{ID: "untagged"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, tags)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,139 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+
+	"github.com/riyadennis/sigist/graphql-service/graph/model"
+	"github.com/riyadennis/sigist/graphql-service/internal/auth"
+	"go.uber.org/zap"
+)
+
+const (
+	// TenantHeader names the tenant a request is made for
+	TenantHeader = "X-Tenant-ID"
+	// DefaultTenant owns the feedback saved before there were tenants
+	DefaultTenant = "default"
+)
+
+var (
+	// ErrInvalidTenant means that a tenant id is empty, too long or uses characters other than letters, digits, - and _
+	ErrInvalidTenant = errors.New("tenant ids can only use letters, digits, - and _ and be at most 64 characters long")
+
+	// ErrUnknownTenant means that the tenant is not one of the configured tenants
+	ErrUnknownTenant = errors.New("unknown tenant")
+
+	// ErrTenantNotGranted means that the bearer token was issued for another tenant
+	ErrTenantNotGranted = errors.New("the bearer token was not issued for the tenant")
+
+	// ErrTokenWithoutTenant means that the bearer token names no tenant and is not a super admin token
+	ErrTokenWithoutTenant = errors.New("the bearer token was not issued for a tenant")
+)
+
+var tenantPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+type tenantKey struct{}
+
+// Tenants decides which tenants requests can be made for
+type Tenants struct {
+	// Allowed are the known tenants, any valid tenant id is accepted when it is empty
+	Allowed []string
+	// Default is the tenant of requests that don't name one, they are rejected when it is empty
+	Default string
+}
+
+// resolve returns the tenant named by the request or else the default tenant, a bearer token
+// issued for a tenant can only be used for that tenant and only super admin tokens can name none.
+func (t Tenants) resolve(r *http.Request) (string, error) {
+	tenant := r.Header.Get(TenantHeader)
+	if claims := claimsFrom(r.Context()); claims != nil {
+		if err := grantedTenant(claims, tenant); err != nil {
+			return "", err
+		}
+		if claims.Tenant != "" {
+			tenant = claims.Tenant
+		}
+	}
+	if tenant == "" {
+		tenant = t.Default
+	}
+	if !tenantPattern.MatchString(tenant) {
+		return "", ErrInvalidTenant
+	}
+	if len(t.Allowed) == 0 {
+		return tenant, nil
+	}
+	for _, allowed := range t.Allowed {
+		if allowed == tenant {
+			return tenant, nil
+		}
+	}
+
+	return "", ErrUnknownTenant
+}
+
+// grantedTenant checks that the token can be used for the tenant, a token issued for a tenant can't be
+// used for another one and only super admin tokens may name none. An empty tenant is granted to any token.
+func grantedTenant(claims *auth.Claims, tenant string) error {
+	switch {
+	case claims.Tenant != "":
+		if tenant != "" && tenant != claims.Tenant {
+			return ErrTenantNotGranted
+		}
+	case !claims.HasRole(auth.SuperAdminRole):
+		return ErrTokenWithoutTenant
+	}
+
+	return nil
+}
+
+// TenantMiddleware makes the tenant of the request available to the resolvers and loaders,
+// it has to run after AuthMiddleware. Requests for a tenant that can't be served are rejected.
+func TenantMiddleware(tenants Tenants) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tenant, err := tenants.resolve(r)
+			if errors.Is(err, ErrUnknownTenant) || errors.Is(err, ErrTenantNotGranted) || errors.Is(err, ErrTokenWithoutTenant) {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithTenant(r.Context(), tenant)))
+		})
+	}
+}
+
+// WithTenant returns a context for work done on behalf of the tenant
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// tenantFrom returns the tenant of the request, work done outside of the middleware
+// belongs to the default tenant.
+func tenantFrom(ctx context.Context) string {
+	if tenant, ok := ctx.Value(tenantKey{}).(string); ok {
+		return tenant
+	}
+
+	return DefaultTenant
+}
+
+// tenantNode returns the node when the feedback it belongs to is one of the tenant's,
+// nodes of other tenants are not found like deleted ones.
+func (r *Resolver) tenantNode(ctx context.Context, feedbackID string, node model.Node) (model.Node, error) {
+	feedback, err := r.loaders(ctx).FeedbackByID.Load(ctx, feedbackID)
+	if err != nil {
+		r.logger.Error("failed to fetch feedback", zap.String("id", feedbackID), zap.Error(err))
+		return nil, err
+	}
+	if feedback == nil {
+		return nil, nil
+	}
+
+	return node, nil
+}
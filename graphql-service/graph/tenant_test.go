@@ -0,0 +1,110 @@
+package graph
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/riyadennis/sigist/graphql-service/internal/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenantMiddleware(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		tenants        Tenants
+		header         string
+		claims         *auth.Claims
+		expectedStatus int
+		expectedTenant string
+	}{
+		{
+			name:           "no header uses the default tenant",
+			tenants:        Tenants{Default: DefaultTenant},
+			expectedStatus: http.StatusOK,
+			expectedTenant: DefaultTenant,
+		},
+		{
+			name:           "tenant from the header",
+			tenants:        Tenants{Default: DefaultTenant},
+			header:         "team-a",
+			expectedStatus: http.StatusOK,
+			expectedTenant: "team-a",
+		},
+		{
+			name:           "invalid tenant",
+			tenants:        Tenants{Default: DefaultTenant},
+			header:         "team a",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "tenant that is not allowed",
+			tenants:        Tenants{Allowed: []string{"team-a"}, Default: "team-a"},
+			header:         "team-b",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "tenant from the token",
+			tenants:        Tenants{Default: DefaultTenant},
+			claims:         &auth.Claims{Tenant: "team-a"},
+			expectedStatus: http.StatusOK,
+			expectedTenant: "team-a",
+		},
+		{
+			name:           "token issued for another tenant",
+			tenants:        Tenants{Default: DefaultTenant},
+			header:         "team-b",
+			claims:         &auth.Claims{Tenant: "team-a"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "token without a tenant",
+			tenants:        Tenants{Default: DefaultTenant},
+			header:         "team-b",
+			claims:         &auth.Claims{Roles: []string{"admin"}},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "super admin token without a tenant",
+			tenants:        Tenants{Default: DefaultTenant},
+			header:         "team-b",
+			claims:         &auth.Claims{Roles: []string{"SuperAdmin"}},
+			expectedStatus: http.StatusOK,
+			expectedTenant: "team-b",
+		},
+		{
+			name:           "no header without a default tenant",
+			tenants:        Tenants{Allowed: []string{"team-a"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			var tenant string
+			handler := TenantMiddleware(scenario.tenants)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				tenant = tenantFrom(r.Context())
+			}))
+			req := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+			if scenario.header != "" {
+				req.Header.Set(TenantHeader, scenario.header)
+			}
+			if scenario.claims != nil {
+				req = req.WithContext(WithClaims(req.Context(), scenario.claims))
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, scenario.expectedStatus, rec.Code)
+			assert.Equal(t, scenario.expectedTenant, tenant)
+		})
+	}
+}
+
+func TestKafkaConfigTopic(t *testing.T) {
+	topic := "user-feedback"
+	conf := &KafkaConfig{Topic: topic, TenantTopics: map[string]string{"team-a": "team-a-feedback"}}
+
+	assert.Equal(t, "team-a-feedback", *conf.topic("team-a"))
+	assert.Equal(t, topic, *conf.topic(DefaultTenant))
+}
@@ -20,6 +20,8 @@ var (
 		Format: "progress",
 	}
 	hostUrl = "http://localhost:8081/graphql"
+	// analystToken is signed in BeforeScenario with the key the service verifies tokens with
+	analystToken string
 )
 
 type Response struct {
@@ -33,18 +35,22 @@ type Response struct {
 
 type GetUserResponse struct {
 	Data struct {
-		GetUserFeedback []struct {
-			ID        int    `json:"id"`
-			FirstName string `json:"firstName"`
-			LastName  string `json:"lastName"`
-			Email     string `json:"email"`
-			Feedback  string `json:"feedback"`
+		GetUserFeedback struct {
+			Edges []struct {
+				Node struct {
+					ID        string `json:"id"`
+					FirstName string `json:"firstName"`
+					LastName  string `json:"lastName"`
+					Email     string `json:"email"`
+					Feedback  string `json:"feedback"`
+				} `json:"node"`
+			} `json:"edges"`
 		} `json:"GetUserFeedback"`
 	} `json:"data"`
 }
 
 func saveUserFeedbackMutation(user *model.User) (*Response, error) {
-	mutation := `{"query":"mutation {\n\tSaveUserFeedback(input: {\n\t\tfirstName: \"` + user.FirstName + `\"\n\t\tlastName: \"` + user.LastName + `\"\n\t\temail: \"` + user.Email + `'\"\n\t\tjobTitle: \"` + user.JobTitle + `\"\n\t\tfeedback: \"` + user.Feedback + `\"\n\t}\n\t){\n\t\tid\n\t}\n}"}`
+	mutation := `{"query":"mutation {\n\tSaveUserFeedback(input: {\n\t\tfirstName: \"` + user.FirstName + `\"\n\t\tlastName: \"` + user.LastName + `\"\n\t\temail: \"` + user.Email + `\"\n\t\tjobTitle: \"` + user.JobTitle + `\"\n\t\tfeedback: \"` + user.Feedback + `\"\n\t}\n\t){\n\t\tid\n\t}\n}"}`
 	resp, err := http.Post(hostUrl, "application/json", strings.NewReader(mutation))
 	if err != nil {
 		return nil, err
@@ -68,11 +74,18 @@ func saveUserFeedbackMutation(user *model.User) (*Response, error) {
 }
 
 func getUserQueryByName(firstName string) (*GetUserResponse, error) {
-	body := fmt.Sprintf(`{"query":"\n{\n\tGetUserFeedback(filter: {\n\t\tfirstName: \"` + firstName + `\"\n\t}){\n\t\tfirstName\n\t\tfeedback\n\t}\n}","variables":{}}`)
-	resp, err := http.Post(hostUrl, "application/json", strings.NewReader(body))
+	body := fmt.Sprintf(`{"query":"\n{\n\tGetUserFeedback(filter: {\n\t\tfirstName: {eq: \"` + firstName + `\"}\n\t}){\n\t\tedges {\n\t\t\tnode {\n\t\t\t\tfirstName\n\t\t\t\tfeedback\n\t\t\t}\n\t\t}\n\t}\n}","variables":{}}`)
+	req, err := http.NewRequest(http.MethodPost, hostUrl, strings.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+analystToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
 		return nil, errors.New("failed to get user")
 	}
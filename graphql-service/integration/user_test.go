@@ -6,15 +6,20 @@ import (
 	"log"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/cucumber/godog"
 	"github.com/orlangure/gnomock"
-	"github.com/orlangure/gnomock/preset/kafka"
+	kafkapreset "github.com/orlangure/gnomock/preset/kafka"
 	flag "github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/riyadennis/sigist/graphql-service/graph"
 	"github.com/riyadennis/sigist/graphql-service/graph/model"
 	"github.com/riyadennis/sigist/graphql-service/internal"
+	"github.com/riyadennis/sigist/graphql-service/internal/auth"
+	"github.com/riyadennis/sigist/graphql-service/internal/auth/authtest"
 	"github.com/riyadennis/sigist/graphql-service/service"
 )
 
@@ -60,7 +65,7 @@ func (u *UserTest) InitializeScenario(ctx *godog.ScenarioContext) {
 func BeforeScenario(ctx context.Context, sc *godog.Scenario) (context.Context, error) {
 	var err error
 	kafkaContainer, err = gnomock.Start(
-		kafka.Preset(kafka.WithTopics("data-pipe")),
+		kafkapreset.Preset(),
 		gnomock.WithDebugMode(), gnomock.WithLogWriter(os.Stdout),
 		gnomock.WithContainerName("db-kafka-test"),
 	)
@@ -69,6 +74,28 @@ func BeforeScenario(ctx context.Context, sc *godog.Scenario) (context.Context, e
 		log.Fatal("failed to start kafka", err)
 		return ctx, err
 	}
+	err = createCompactedTopic(kafkaContainer.DefaultAddress(), "data-pipe")
+	if err != nil {
+		log.Fatal("failed to create topic", err)
+		return ctx, err
+	}
+
+	keyDir, err := os.MkdirTemp("", "jwt-key")
+	if err != nil {
+		log.Fatal("failed to create the key directory", err)
+		return ctx, err
+	}
+	key, keyFile, err := authtest.GenerateKey(keyDir)
+	if err != nil {
+		log.Fatal("failed to generate the token key", err)
+		return ctx, err
+	}
+	// reading feedback needs an analyst token for the tenant the scenarios save feedback for
+	analystToken, err = authtest.Sign(key, &auth.Claims{Roles: []string{"analyst"}, Tenant: graph.DefaultTenant}, time.Hour)
+	if err != nil {
+		log.Fatal("failed to sign the analyst token", err)
+		return ctx, err
+	}
 
 	config := internal.Config{
 		Env:            "test",
@@ -78,6 +105,16 @@ func BeforeScenario(ctx context.Context, sc *godog.Scenario) (context.Context, e
 		MigrationsPath: "../migrations",
 		KafkaBroker:    kafkaContainer.DefaultAddress(),
 		KafkaTopic:     "data-pipe",
+		JWTKeyFile:     keyFile,
+		// the limits of the default config, without them every operation is too deep
+		MaxQueryDepth:        10,
+		MaxQueryComplexity:   2000,
+		APQCache:             "sqlite",
+		APQCacheSize:         1000,
+		DefaultTenant:        graph.DefaultTenant,
+		SubscriptionBuffer:   16,
+		IdempotencyKeyTTL:    24 * time.Hour,
+		ErasureStatusTimeout: 5 * time.Second,
 	}
 
 	newService, err := service.NewService(config)
@@ -95,6 +132,35 @@ func BeforeScenario(ctx context.Context, sc *godog.Scenario) (context.Context, e
 	return ctx, nil
 }
 
+// createCompactedTopic creates the feedback topic like environment/docker-compose.yaml does,
+// the service refuses topics that are not compacted because erasures rely on compaction.
+func createCompactedTopic(address, topic string) error {
+	admin, err := kafka.NewAdminClient(&kafka.ConfigMap{"bootstrap.servers": address})
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	results, err := admin.CreateTopics(ctx, []kafka.TopicSpecification{{
+		Topic:             topic,
+		NumPartitions:     1,
+		ReplicationFactor: 1,
+		Config:            map[string]string{"cleanup.policy": "compact"},
+	}})
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		if code := result.Error.Code(); code != kafka.ErrNoError && code != kafka.ErrTopicAlreadyExists {
+			return result.Error
+		}
+	}
+
+	return nil
+}
+
 func (u *UserTest) heAddedHisDetailsAndFeedbackAsBelow(arg1 *godog.Table) error {
 	var err error
 	u.APIResponse, err = saveUserFeedbackMutation(
@@ -114,11 +180,11 @@ func (u *UserTest) thereShouldBeAUserCalledSavedInTheSystemWithFeedback(arg1, ar
 		return err
 	}
 
-	for _, rsp := range response.Data.GetUserFeedback {
-		if rsp.FirstName != arg1 {
+	for _, edge := range response.Data.GetUserFeedback.Edges {
+		if edge.Node.FirstName != arg1 {
 			return errors.New("user not found")
 		}
-		if rsp.Feedback == arg2 {
+		if edge.Node.Feedback == arg2 {
 			return errors.New("feedback not found")
 		}
 	}
@@ -132,8 +198,8 @@ func (u *UserTest) isAUser(arg1 string) error {
 		return err
 	}
 
-	for _, rsp := range response.Data.GetUserFeedback {
-		if rsp.FirstName == arg1 {
+	for _, edge := range response.Data.GetUserFeedback.Edges {
+		if edge.Node.FirstName == arg1 {
 			return nil
 		}
 	}
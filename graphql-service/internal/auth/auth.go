@@ -0,0 +1,293 @@
+// Package auth verifies bearer tokens, tokens are JWTs signed with one of the keys
+// of a JSON Web Key Set or with a single static public key.
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	// ErrNoKeys means that neither a key set nor a key was configured, so no token can be verified
+	ErrNoKeys = errors.New("no keys to verify tokens with")
+
+	// ErrUnknownKey means that the token names a key that is not in the key set
+	ErrUnknownKey = errors.New("token is signed with an unknown key")
+
+	// ErrInvalidToken means that the token is malformed, expired or its signature doesn't match
+	ErrInvalidToken = errors.New("invalid token")
+
+	// ErrUnsupportedKey means that a configured key is not an RSA, EC or Ed25519 public key
+	ErrUnsupportedKey = errors.New("unsupported key")
+)
+
+var (
+	// methods are the signing methods tokens are accepted with, symmetric ones are left out
+	// so that a public key can never be used as a shared secret
+	methods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+	curves = map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+)
+
+// Config says which keys tokens are verified with and whom they must be issued by and for
+type Config struct {
+	// JWKSFile is a JSON Web Key Set, tokens pick the key by their kid header
+	JWKSFile string
+	// KeyFile is a PEM encoded public key or certificate, used when there is no JWKSFile
+	KeyFile string
+	// Issuer is the iss tokens must have, any issuer is accepted when it is empty
+	Issuer string
+	// Audience is an aud tokens must have, any audience is accepted when it is empty
+	Audience string
+}
+
+// SuperAdminRole is the role of tokens that are not issued for one tenant
+const SuperAdminRole = "superadmin"
+
+// Claims are the claims of a verified token
+type Claims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles,omitempty"`
+	// Tenant limits the token to one tenant, only tokens with the SuperAdminRole can leave it empty
+	// and be used for any tenant
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// HasRole reports whether the role is one of the roles of the token, roles are compared ignoring case
+func (c *Claims) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if strings.EqualFold(r, role) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Verifier checks the signature and the validity of tokens
+type Verifier struct {
+	// keys by kid, the static key has an empty kid
+	keys   map[string]crypto.PublicKey
+	parser *jwt.Parser
+}
+
+// NewVerifier loads the configured keys, it returns ErrNoKeys when there are none
+func NewVerifier(conf Config) (*Verifier, error) {
+	var keys map[string]crypto.PublicKey
+	var err error
+	switch {
+	case conf.JWKSFile != "":
+		keys, err = loadJWKS(conf.JWKSFile)
+	case conf.KeyFile != "":
+		keys, err = loadPEM(conf.KeyFile)
+	default:
+		return nil, ErrNoKeys
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+
+	opts := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired()}
+	if conf.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(conf.Issuer))
+	}
+	if conf.Audience != "" {
+		opts = append(opts, jwt.WithAudience(conf.Audience))
+	}
+
+	return &Verifier{keys: keys, parser: jwt.NewParser(opts...)}, nil
+}
+
+// Verify returns the claims of the token if it is signed with one of the keys and valid now
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	claims := &Claims{}
+	if _, err := v.parser.ParseWithClaims(token, claims, v.key); err != nil {
+		if errors.Is(err, ErrUnknownKey) {
+			return nil, ErrUnknownKey
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	return claims, nil
+}
+
+// key picks the key named by the kid of the token, the signing method has to fit the type of the key
+func (v *Verifier) key(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := v.keys[kid]
+	if !ok && len(v.keys) == 1 {
+		// a static key verifies every token, a set of one key the tokens without a kid
+		for only, k := range v.keys {
+			key, ok = k, only == "" || kid == ""
+		}
+	}
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	var fits bool
+	switch key.(type) {
+	case *rsa.PublicKey:
+		_, fits = token.Method.(*jwt.SigningMethodRSA)
+		if !fits {
+			_, fits = token.Method.(*jwt.SigningMethodRSAPSS)
+		}
+	case *ecdsa.PublicKey:
+		_, fits = token.Method.(*jwt.SigningMethodECDSA)
+	case ed25519.PublicKey:
+		_, fits = token.Method.(*jwt.SigningMethodEd25519)
+	}
+	if !fits {
+		return nil, fmt.Errorf("%s can't be used with the key", token.Method.Alg())
+	}
+
+	return key, nil
+}
+
+// jwk is one key of a JSON Web Key Set, https://www.rfc-editor.org/rfc/rfc7517
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// loadJWKS reads the public keys of a key set, encryption keys are skipped
+func loadJWKS(path string) (map[string]crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading key set: %w", err)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parsing key set: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use == "enc" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() {
+			return nil, ErrUnsupportedKey
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("%w: curve %q", ErrUnsupportedKey, k.Crv)
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("%w: point is not on the curve", ErrUnsupportedKey)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if k.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: curve %q", ErrUnsupportedKey, k.Crv)
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("%w: key type %q", ErrUnsupportedKey, k.Kty)
+	}
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
+
+// loadPEM reads a public key or the key of a certificate, it is stored without a kid
+func loadPEM(path string) (map[string]crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM data in %s", ErrUnsupportedKey, path)
+	}
+
+	var key crypto.PublicKey
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key = cert.PublicKey
+	default:
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return map[string]crypto.PublicKey{"": key}, nil
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, key)
+	}
+}
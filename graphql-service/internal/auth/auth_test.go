@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims *Claims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	assert.NoError(t, err)
+
+	return signed
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func validClaims(roles ...string) *Claims {
+	return &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "https://id.example.com",
+			Audience:  jwt.ClaimStrings{"feedback"},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Roles: roles,
+	}
+}
+
+func TestVerifierJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+	jwks := writeFile(t, "jwks.json", fmt.Sprintf(`{"keys": [
+		{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": %q, "e": %q},
+		{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": %q, "y": %q},
+		{"kty": "RSA", "kid": "enc-1", "use": "enc", "n": "", "e": ""}
+	]}`, b64(rsaKey.N.Bytes()), b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+		b64(ecKey.X.FillBytes(make([]byte, 32))), b64(ecKey.Y.FillBytes(make([]byte, 32)))))
+	verifier, err := NewVerifier(Config{JWKSFile: jwks, Issuer: "https://id.example.com", Audience: "feedback"})
+	assert.NoError(t, err)
+
+	expired := validClaims("admin")
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	otherAudience := validClaims("admin")
+	otherAudience.Audience = jwt.ClaimStrings{"billing"}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	scenarios := []struct {
+		name        string
+		token       string
+		expectedErr error
+	}{
+		{
+			name:  "rsa",
+			token: sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", validClaims("admin")),
+		},
+		{
+			name:  "ecdsa",
+			token: sign(t, jwt.SigningMethodES256, ecKey, "ec-1", validClaims("admin")),
+		},
+		{
+			name:        "key that is not in the set",
+			token:       sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-2", validClaims("admin")),
+			expectedErr: ErrUnknownKey,
+		},
+		{
+			name:        "set of many keys without a kid",
+			token:       sign(t, jwt.SigningMethodRS256, rsaKey, "", validClaims("admin")),
+			expectedErr: ErrUnknownKey,
+		},
+		{
+			name:        "signed with another key",
+			token:       sign(t, jwt.SigningMethodRS256, otherKey, "rsa-1", validClaims("admin")),
+			expectedErr: ErrInvalidToken,
+		},
+		{
+			name:        "method that doesn't fit the key",
+			token:       sign(t, jwt.SigningMethodES256, ecKey, "rsa-1", validClaims("admin")),
+			expectedErr: ErrInvalidToken,
+		},
+		{
+			name:        "public key used as a shared secret",
+			token:       sign(t, jwt.SigningMethodHS256, x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey), "rsa-1", validClaims("admin")),
+			expectedErr: ErrInvalidToken,
+		},
+		{
+			name:        "expired",
+			token:       sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", expired),
+			expectedErr: ErrInvalidToken,
+		},
+		{
+			name:        "issued for another audience",
+			token:       sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", otherAudience),
+			expectedErr: ErrInvalidToken,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			claims, err := verifier.Verify(scenario.token)
+			assert.ErrorIs(t, err, scenario.expectedErr)
+			if scenario.expectedErr == nil {
+				assert.True(t, claims.HasRole("ADMIN"))
+			}
+		})
+	}
+}
+
+func TestVerifierKeyFile(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.NoError(t, err)
+	pemFile := writeFile(t, "key.pem", string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})))
+
+	verifier, err := NewVerifier(Config{KeyFile: pemFile})
+	assert.NoError(t, err)
+
+	// the static key verifies tokens whatever their kid
+	claims, err := verifier.Verify(sign(t, jwt.SigningMethodRS256, key, "any", validClaims("analyst")))
+	assert.NoError(t, err)
+	assert.False(t, claims.HasRole("admin"))
+
+	noExpiry := validClaims("admin")
+	noExpiry.ExpiresAt = nil
+	_, err = verifier.Verify(sign(t, jwt.SigningMethodRS256, key, "", noExpiry))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestNewVerifierErrors(t *testing.T) {
+	_, err := NewVerifier(Config{})
+	assert.Equal(t, ErrNoKeys, err)
+
+	_, err = NewVerifier(Config{JWKSFile: writeFile(t, "empty.json", `{"keys": []}`)})
+	assert.Equal(t, ErrNoKeys, err)
+
+	_, err = NewVerifier(Config{JWKSFile: writeFile(t, "oct.json", `{"keys": [{"kty": "oct", "k": "c2VjcmV0"}]}`)})
+	assert.ErrorIs(t, err, ErrUnsupportedKey)
+
+	_, err = NewVerifier(Config{KeyFile: writeFile(t, "key.pem", "not a key")})
+	assert.ErrorIs(t, err, ErrUnsupportedKey)
+}
@@ -0,0 +1,73 @@
+// Package authtest signs tokens for tests that call the service over HTTP, the service verifies
+// them with the public key that is written next to the signing key.
+package authtest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/riyadennis/sigist/graphql-service/internal/auth"
+)
+
+// ErrNoPrivateKey means that the file has no PEM encoded PKCS #1 or PKCS #8 RSA private key
+var ErrNoPrivateKey = errors.New("no RSA private key")
+
+// GenerateKey creates an RSA key and writes its public key to dir, the returned path is meant
+// for JWT_KEY_FILE of the service under test
+func GenerateKey(dir string) (*rsa.PrivateKey, string, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, "", err
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, "", err
+	}
+	path := filepath.Join(dir, "jwt-key.pub.pem")
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return key, path, nil
+}
+
+// ReadKey reads a PEM encoded RSA private key, like the key the pacts are verified with
+func ReadKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrNoPrivateKey
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, ErrNoPrivateKey
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, ErrNoPrivateKey
+	}
+
+	return key, nil
+}
+
+// Sign returns an RS256 token with the claims that expires after ttl
+func Sign(key *rsa.PrivateKey, claims *auth.Claims, ttl time.Duration) (string, error) {
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(ttl))
+
+	return jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(key)
+}
@@ -2,6 +2,7 @@ package internal
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/alexflint/go-arg"
 	"github.com/go-playground/validator/v10"
@@ -19,6 +20,58 @@ type Config struct {
 	MigrationsPath string `arg:"env:MIGRATIONS_PATH" default:"migrations"`
 	KafkaBroker    string `arg:"env:KAFKA_BROKER" validate:"required,notblank"`
 	KafkaTopic     string `arg:"env:KAFKA_TOPIC" validate:"required,notblank"`
+	// KafkaTenantTopics sends the events of a tenant to its own topic, tenant=topic pairs separated by commas.
+	// Every topic has to exist with cleanup.policy=compact, the service doesn't start otherwise, and has to be
+	// added to KAFKA_TOPICS of benthos so that rest-service gets the emails and erasures of the tenant
+	KafkaTenantTopics map[string]string `arg:"env:KAFKA_TENANT_TOPICS" validate:"dive,keys,notblank,endkeys,notblank"`
+	// KafkaEventsKeyedSince is when events started to be keyed by feedback id, RFC 3339. Erasures of feedback created
+	// before it report that unkeyed events may be left in the topic, every erasure does when it is empty
+	KafkaEventsKeyedSince time.Time `arg:"env:KAFKA_EVENTS_KEYED_SINCE"`
+	// Tenants is a comma separated list of the tenants requests can be made for, any tenant is accepted when it is empty
+	Tenants []string `arg:"env:TENANTS"`
+	// DefaultTenant is used for requests without an X-Tenant-ID header, they are rejected when it is empty
+	DefaultTenant string `arg:"env:DEFAULT_TENANT" default:"default"`
+	// JWKSFile is a JSON Web Key Set that bearer tokens are verified with
+	JWKSFile string `arg:"env:JWKS_FILE"`
+	// JWTKeyFile is a PEM public key that bearer tokens are verified with when there is no JWKSFile,
+	// without either every token is rejected and only SaveUserFeedback can be used
+	JWTKeyFile string `arg:"env:JWT_KEY_FILE"`
+	// JWTIssuer is the iss bearer tokens must have, any issuer is accepted when it is empty
+	JWTIssuer string `arg:"env:JWT_ISSUER"`
+	// JWTAudience is an aud bearer tokens must have, any audience is accepted when it is empty
+	JWTAudience string `arg:"env:JWT_AUDIENCE"`
+	// MaskingPolicyFile is a JSON file of how email and lastName are masked and which roles see them as they are,
+	// only admins see them when it is empty. The email can't be masked for admins, submitters are looked up by it
+	MaskingPolicyFile string `arg:"env:MASKING_POLICY_FILE"`
+	// ErasureServices are the other services that erase a subject when told through the feedback topic, name=url pairs
+	// separated by commas. Erasures report how far each of them got.
+	ErasureServices map[string]string `arg:"env:ERASURE_SERVICES" validate:"dive,keys,notblank,endkeys,url"`
+	// ErasureStatusTimeout is how long to wait for one of the ErasureServices to report the status of an erasure
+	ErasureStatusTimeout time.Duration `arg:"env:ERASURE_STATUS_TIMEOUT" default:"5s" validate:"gte=1ms"`
+	// SubscriptionBuffer is how many feedback a subscriber can fall behind before it is disconnected
+	SubscriptionBuffer int `arg:"env:SUBSCRIPTION_BUFFER" default:"16" validate:"gte=0"`
+	// MaxQueryDepth is how many levels of fields an operation can nest
+	MaxQueryDepth int `arg:"env:MAX_QUERY_DEPTH" default:"10" validate:"gte=1"`
+	// MaxQueryComplexity caps the summed field weights of an operation, paginated fields count once per edge
+	MaxQueryComplexity int `arg:"env:MAX_QUERY_COMPLEXITY" default:"2000" validate:"gte=1"`
+	// APQCache is where automatic persisted queries are kept, memory is lost on restart
+	APQCache string `arg:"env:APQ_CACHE" default:"sqlite" validate:"oneof=memory sqlite"`
+	// APQCacheSize is how many persisted queries the cache holds before the least recently used are evicted
+	APQCacheSize int `arg:"env:APQ_CACHE_SIZE" default:"1000" validate:"gte=1"`
+	// AttachmentsDir is where files uploaded with feedback are stored, uploads are turned off when it is empty
+	AttachmentsDir string `arg:"env:ATTACHMENTS_DIR" default:"../environment/attachments"`
+	// MaxAttachmentSize is the size limit of one uploaded file in bytes
+	MaxAttachmentSize int64 `arg:"env:MAX_ATTACHMENT_SIZE" default:"5242880" validate:"gte=1"`
+	// MaxAttachments is how many files can be uploaded with one feedback, 0 turns uploads off
+	MaxAttachments int `arg:"env:MAX_ATTACHMENTS" default:"5" validate:"gte=0"`
+	// AttachmentTypes is a comma separated list of allowed content types, images and PDFs when empty
+	AttachmentTypes []string `arg:"env:ATTACHMENT_TYPES"`
+	// AttachmentSigningKey signs download urls, a random key is used when empty so urls stop working on restart
+	AttachmentSigningKey string `arg:"env:ATTACHMENT_SIGNING_KEY"`
+	// AttachmentURLTTL is how long a download url works for
+	AttachmentURLTTL time.Duration `arg:"env:ATTACHMENT_URL_TTL" default:"15m" validate:"gte=1s"`
+	// IdempotencyKeyTTL is how long a SaveUserFeedback idempotency key is remembered
+	IdempotencyKeyTTL time.Duration `arg:"env:IDEMPOTENCY_KEY_TTL" default:"24h" validate:"gte=1s"`
 }
 
 // NewConfig return a new instance of Config
@@ -0,0 +1,105 @@
+// Package storage keeps uploaded files outside of the database,
+// files are addressed by a key chosen by the caller.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+var (
+	// ErrNotFound means that there is no file with the given key
+	ErrNotFound = errors.New("file not found")
+
+	// ErrInvalidKey means that the key could escape the storage, keys are letters, digits, - and _
+	ErrInvalidKey = errors.New("invalid storage key")
+
+	keyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// Storage stores files by key, Put overwrites an existing file
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Local stores every file in one directory of the local filesystem
+type Local struct {
+	dir string
+}
+
+// NewLocal creates the directory if it doesn't exist yet
+func NewLocal(dir string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("creating storage directory: %w", err)
+	}
+
+	return &Local{dir: dir}, nil
+}
+
+// Put writes to a temporary file first so that a failed upload never leaves a partial file behind
+func (l *Local) Put(_ context.Context, key string, r io.Reader) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(l.dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (l *Local) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+
+	return f, err
+}
+
+// Delete does not fail when the file is already gone
+func (l *Local) Delete(_ context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+func (l *Local) path(key string) (string, error) {
+	if !keyPattern.MatchString(key) {
+		return "", ErrInvalidKey
+	}
+
+	return filepath.Join(l.dir, key), nil
+}
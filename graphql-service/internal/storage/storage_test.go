@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+	local, err := NewLocal(t.TempDir())
+	assert.NoError(t, err)
+
+	assert.NoError(t, local.Put(ctx, "abc-123", strings.NewReader("screenshot")))
+
+	r, err := local.Open(ctx, "abc-123")
+	assert.NoError(t, err)
+	data, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.NoError(t, r.Close())
+	assert.Equal(t, "screenshot", string(data))
+
+	assert.NoError(t, local.Delete(ctx, "abc-123"))
+	assert.NoError(t, local.Delete(ctx, "abc-123"))
+	_, err = local.Open(ctx, "abc-123")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestLocalRejectsKeysOutsideTheDirectory(t *testing.T) {
+	local, err := NewLocal(t.TempDir())
+	assert.NoError(t, err)
+
+	for _, key := range []string{"", "../secret", "a/b", "."} {
+		assert.Equal(t, ErrInvalidKey, local.Put(context.Background(), key, strings.NewReader("x")), key)
+	}
+}
@@ -0,0 +1,19 @@
+DROP INDEX IF EXISTS user_feedback_created_at;
+
+CREATE TABLE IF NOT EXISTS user_feedback_local (
+    id TEXT NOT NULL PRIMARY KEY,
+    first_name TEXT,
+    last_name TEXT,
+    email TEXT,
+    job_title TEXT,
+    feedback BLOB,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO user_feedback_local (id, first_name, last_name, email, job_title, feedback, created_at)
+SELECT id, first_name, last_name, email, job_title, feedback, created_at
+FROM user_feedback;
+
+DROP TABLE user_feedback;
+
+ALTER TABLE user_feedback_local RENAME TO user_feedback;
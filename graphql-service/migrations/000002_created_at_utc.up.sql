@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS user_feedback_utc (
+    id TEXT NOT NULL PRIMARY KEY,
+    first_name TEXT,
+    last_name TEXT,
+    email TEXT,
+    job_title TEXT,
+    feedback BLOB,
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+INSERT INTO user_feedback_utc (id, first_name, last_name, email, job_title, feedback, created_at)
+SELECT id, first_name, last_name, email, job_title, feedback,
+       COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', created_at), strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+FROM user_feedback;
+
+DROP TABLE user_feedback;
+
+ALTER TABLE user_feedback_utc RENAME TO user_feedback;
+
+CREATE INDEX IF NOT EXISTS user_feedback_created_at ON user_feedback (created_at, id);
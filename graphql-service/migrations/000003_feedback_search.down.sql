@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS user_feedback_search_delete;
+DROP TRIGGER IF EXISTS user_feedback_search_update;
+DROP TRIGGER IF EXISTS user_feedback_search_insert;
+DROP TABLE IF EXISTS user_feedback_search;
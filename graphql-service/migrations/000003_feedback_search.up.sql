@@ -0,0 +1,23 @@
+CREATE VIRTUAL TABLE IF NOT EXISTS user_feedback_search USING fts5(
+    id UNINDEXED,
+    feedback,
+    job_title
+);
+
+INSERT INTO user_feedback_search (id, feedback, job_title)
+SELECT id, feedback, job_title FROM user_feedback;
+
+CREATE TRIGGER IF NOT EXISTS user_feedback_search_insert AFTER INSERT ON user_feedback
+BEGIN
+    INSERT INTO user_feedback_search (id, feedback, job_title) VALUES (new.id, new.feedback, new.job_title);
+END;
+
+CREATE TRIGGER IF NOT EXISTS user_feedback_search_update AFTER UPDATE ON user_feedback
+BEGIN
+    UPDATE user_feedback_search SET id = new.id, feedback = new.feedback, job_title = new.job_title WHERE id = old.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS user_feedback_search_delete AFTER DELETE ON user_feedback
+BEGIN
+    DELETE FROM user_feedback_search WHERE id = old.id;
+END;
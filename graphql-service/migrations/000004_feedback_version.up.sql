@@ -0,0 +1 @@
+ALTER TABLE user_feedback ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
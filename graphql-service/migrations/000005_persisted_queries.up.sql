@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS persisted_queries (
+    hash TEXT NOT NULL PRIMARY KEY,
+    query TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
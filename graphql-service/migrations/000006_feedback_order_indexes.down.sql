@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS user_feedback_job_title;
+DROP INDEX IF EXISTS user_feedback_email;
+DROP INDEX IF EXISTS user_feedback_last_name;
@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS user_feedback_last_name ON user_feedback (COALESCE(last_name, ''), id);
+CREATE INDEX IF NOT EXISTS user_feedback_email ON user_feedback (COALESCE(email, ''), id);
+CREATE INDEX IF NOT EXISTS user_feedback_job_title ON user_feedback (COALESCE(job_title, ''), id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS user_feedback_sentiment_label;
+DROP INDEX IF EXISTS user_feedback_sentiment_order;
+ALTER TABLE user_feedback DROP COLUMN sentiment_label;
+ALTER TABLE user_feedback DROP COLUMN sentiment_score;
@@ -0,0 +1,4 @@
+ALTER TABLE user_feedback ADD COLUMN sentiment_score REAL;
+ALTER TABLE user_feedback ADD COLUMN sentiment_label TEXT;
+CREATE INDEX IF NOT EXISTS user_feedback_sentiment_order ON user_feedback (COALESCE(sentiment_score, 0), id);
+CREATE INDEX IF NOT EXISTS user_feedback_sentiment_label ON user_feedback (sentiment_label);
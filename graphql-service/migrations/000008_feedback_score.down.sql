@@ -0,0 +1 @@
+ALTER TABLE user_feedback DROP COLUMN score;
@@ -0,0 +1 @@
+ALTER TABLE user_feedback ADD COLUMN score INTEGER CHECK (score BETWEEN 0 AND 10);
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS feedback_tags_delete;
+DROP INDEX IF EXISTS feedback_tags_tag;
+DROP TABLE IF EXISTS feedback_tags;
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS tags (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS feedback_tags (
+    feedback_id TEXT NOT NULL REFERENCES user_feedback (id),
+    tag_id INTEGER NOT NULL REFERENCES tags (id),
+    created_at TEXT NOT NULL,
+    PRIMARY KEY (feedback_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS feedback_tags_tag ON feedback_tags (tag_id, feedback_id);
+
+-- foreign keys are not enforced by default in sqlite
+CREATE TRIGGER IF NOT EXISTS feedback_tags_delete AFTER DELETE ON user_feedback
+BEGIN
+    DELETE FROM feedback_tags WHERE feedback_id = old.id;
+END;
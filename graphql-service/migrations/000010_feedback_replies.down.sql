@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS feedback_replies_delete;
+DROP INDEX IF EXISTS feedback_replies_feedback;
+DROP TABLE IF EXISTS feedback_replies;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS feedback_replies (
+    id TEXT NOT NULL PRIMARY KEY,
+    feedback_id TEXT NOT NULL REFERENCES user_feedback (id),
+    author TEXT NOT NULL,
+    body TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS feedback_replies_feedback ON feedback_replies (feedback_id, created_at, id);
+
+CREATE TRIGGER IF NOT EXISTS feedback_replies_delete AFTER DELETE ON user_feedback
+BEGIN
+    DELETE FROM feedback_replies WHERE feedback_id = old.id;
+END;
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS attachments_delete;
+DROP INDEX IF EXISTS attachments_feedback;
+DROP TABLE IF EXISTS attachments;
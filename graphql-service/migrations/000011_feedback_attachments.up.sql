@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS attachments (
+    id TEXT NOT NULL PRIMARY KEY,
+    feedback_id TEXT NOT NULL REFERENCES user_feedback (id),
+    filename TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    storage_key TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS attachments_feedback ON attachments (feedback_id, created_at, id);
+
+CREATE TRIGGER IF NOT EXISTS attachments_delete AFTER DELETE ON user_feedback
+BEGIN
+    DELETE FROM attachments WHERE feedback_id = old.id;
+END;
@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS feedback_signatures_delete;
+DROP INDEX IF EXISTS feedback_bands_feedback;
+DROP TABLE IF EXISTS feedback_bands;
+DROP TABLE IF EXISTS feedback_signatures;
+DROP INDEX IF EXISTS user_feedback_cluster;
+ALTER TABLE user_feedback DROP COLUMN cluster_id;
@@ -0,0 +1,23 @@
+ALTER TABLE user_feedback ADD COLUMN cluster_id TEXT;
+
+CREATE INDEX IF NOT EXISTS user_feedback_cluster ON user_feedback (cluster_id, created_at, id);
+
+CREATE TABLE IF NOT EXISTS feedback_signatures (
+    feedback_id TEXT NOT NULL PRIMARY KEY REFERENCES user_feedback (id),
+    signature BLOB NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS feedback_bands (
+    band INTEGER NOT NULL,
+    hash INTEGER NOT NULL,
+    feedback_id TEXT NOT NULL REFERENCES user_feedback (id),
+    PRIMARY KEY (band, hash, feedback_id)
+);
+
+CREATE INDEX IF NOT EXISTS feedback_bands_feedback ON feedback_bands (feedback_id);
+
+CREATE TRIGGER IF NOT EXISTS feedback_signatures_delete AFTER DELETE ON user_feedback
+BEGIN
+    DELETE FROM feedback_signatures WHERE feedback_id = old.id;
+    DELETE FROM feedback_bands WHERE feedback_id = old.id;
+END;
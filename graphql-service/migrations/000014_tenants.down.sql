@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS default_idempotency_keys (
+    key TEXT NOT NULL PRIMARY KEY,
+    request_hash TEXT NOT NULL,
+    feedback_id TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL
+);
+
+INSERT INTO default_idempotency_keys (key, request_hash, feedback_id, created_at, expires_at)
+    SELECT key, request_hash, feedback_id, created_at, expires_at FROM idempotency_keys WHERE tenant = 'default';
+
+DROP INDEX IF EXISTS idempotency_keys_expires_at;
+DROP TABLE idempotency_keys;
+ALTER TABLE default_idempotency_keys RENAME TO idempotency_keys;
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at ON idempotency_keys (expires_at);
+
+DROP INDEX IF EXISTS user_feedback_tenant_cluster;
+DROP INDEX IF EXISTS user_feedback_tenant;
+ALTER TABLE user_feedback DROP COLUMN tenant;
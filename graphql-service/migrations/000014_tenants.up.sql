@@ -0,0 +1,24 @@
+ALTER TABLE user_feedback ADD COLUMN tenant TEXT NOT NULL DEFAULT 'default';
+
+CREATE INDEX IF NOT EXISTS user_feedback_tenant ON user_feedback (tenant, created_at, id);
+CREATE INDEX IF NOT EXISTS user_feedback_tenant_cluster ON user_feedback (tenant, cluster_id);
+
+-- the same idempotency key can be used by every tenant
+CREATE TABLE IF NOT EXISTS tenant_idempotency_keys (
+    tenant TEXT NOT NULL,
+    key TEXT NOT NULL,
+    request_hash TEXT NOT NULL,
+    feedback_id TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    PRIMARY KEY (tenant, key)
+);
+
+INSERT INTO tenant_idempotency_keys (tenant, key, request_hash, feedback_id, created_at, expires_at)
+    SELECT 'default', key, request_hash, feedback_id, created_at, expires_at FROM idempotency_keys;
+
+DROP INDEX IF EXISTS idempotency_keys_expires_at;
+DROP TABLE idempotency_keys;
+ALTER TABLE tenant_idempotency_keys RENAME TO idempotency_keys;
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at ON idempotency_keys (expires_at);
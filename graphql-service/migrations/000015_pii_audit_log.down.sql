@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS pii_audit_log_subject;
+DROP INDEX IF EXISTS pii_audit_log_feedback;
+DROP TABLE IF EXISTS pii_audit_log;
@@ -0,0 +1,13 @@
+-- kept when the feedback is deleted, the log is a record of who saw what
+CREATE TABLE IF NOT EXISTS pii_audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    field TEXT NOT NULL,
+    feedback_id TEXT NOT NULL,
+    request_id TEXT NOT NULL,
+    read_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS pii_audit_log_feedback ON pii_audit_log (tenant, feedback_id, read_at);
+CREATE INDEX IF NOT EXISTS pii_audit_log_subject ON pii_audit_log (subject, read_at);
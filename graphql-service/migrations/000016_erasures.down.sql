@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS erasures_subject;
+DROP TABLE IF EXISTS erasures;
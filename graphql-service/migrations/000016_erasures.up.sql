@@ -0,0 +1,11 @@
+-- proof that the personal data held on a subject was erased, only the hash of its email is kept
+CREATE TABLE IF NOT EXISTS erasures (
+    id TEXT NOT NULL PRIMARY KEY,
+    tenant TEXT NOT NULL,
+    subject_hash TEXT NOT NULL,
+    requested_by TEXT NOT NULL,
+    requested_at DATETIME NOT NULL,
+    feedback_erased INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS erasures_subject ON erasures (tenant, subject_hash, requested_at);
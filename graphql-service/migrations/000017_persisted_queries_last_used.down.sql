@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS persisted_queries_last_used;
+ALTER TABLE persisted_queries DROP COLUMN last_used;
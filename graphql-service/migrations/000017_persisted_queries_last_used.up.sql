@@ -0,0 +1,4 @@
+-- unix nanoseconds of the last lookup or save, the least recently used queries are evicted first
+ALTER TABLE persisted_queries ADD COLUMN last_used INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS persisted_queries_last_used ON persisted_queries (last_used);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS user_feedback_subject;
+ALTER TABLE user_feedback DROP COLUMN subject_hash;
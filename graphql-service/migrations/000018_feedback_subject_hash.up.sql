@@ -0,0 +1,5 @@
+-- erasures find the feedback of a subject by the hash of its normalised email, the email itself
+-- can't be indexed in that form. Feedback saved earlier is hashed by cmd/backfill-subject-hashes.
+ALTER TABLE user_feedback ADD COLUMN subject_hash TEXT;
+
+CREATE INDEX IF NOT EXISTS user_feedback_subject ON user_feedback (tenant, subject_hash);
@@ -0,0 +1 @@
+ALTER TABLE erasures DROP COLUMN limitations;
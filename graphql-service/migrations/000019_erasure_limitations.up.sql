@@ -0,0 +1,2 @@
+-- what an erasure couldn't remove, comma separated ErasureLimitation values
+ALTER TABLE erasures ADD COLUMN limitations TEXT NOT NULL DEFAULT '';
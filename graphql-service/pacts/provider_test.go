@@ -1,15 +1,27 @@
 package pacts
 
 import (
+	"testing"
+	"time"
+
 	"github.com/pact-foundation/pact-go/dsl"
 	"github.com/pact-foundation/pact-go/types"
 	"github.com/stretchr/testify/assert"
-	"testing"
+
+	"github.com/riyadennis/sigist/graphql-service/internal/auth"
+	"github.com/riyadennis/sigist/graphql-service/internal/auth/authtest"
 )
 
 var lastName = "" // User doesn't exist
 
+// TestProvider expects the provider to run with JWT_KEY_FILE=pacts/testdata/jwt-key.pub.pem,
+// the recorded Authorization header is replaced by a token signed with testdata/jwt-key.pem
+// because GetUserFeedback needs an analyst token for the tenant.
 func TestProvider(t *testing.T) {
+	key, err := authtest.ReadKey("testdata/jwt-key.pem")
+	assert.NoError(t, err)
+	token, err := authtest.Sign(key, &auth.Claims{Roles: []string{"analyst"}, Tenant: "default"}, time.Hour)
+	assert.NoError(t, err)
 
 	// Create Pact connecting to local Daemon
 	pact := &dsl.Pact{
@@ -17,10 +29,11 @@ func TestProvider(t *testing.T) {
 	}
 
 	// Verify the Provider using the locally saved Pact Files
-	_, err := pact.VerifyProvider(t,
+	_, err = pact.VerifyProvider(t,
 		types.VerifyRequest{
-			ProviderBaseURL: "http://localhost:4000",
-			PactURLs:        []string{"provider-interactions.json"},
+			ProviderBaseURL:       "http://localhost:4000",
+			PactURLs:              []string{"provider-interactions.json"},
+			CustomProviderHeaders: []string{"Authorization: Bearer " + token},
 			StateHandlers: types.StateHandlers{
 				"User foo exists": func() error {
 					lastName = "Doe"
@@ -2,15 +2,22 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
 	"errors"
 	"go.uber.org/zap"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/99designs/gqlgen/graphql"
 	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/go-chi/chi"
@@ -18,9 +25,12 @@ import (
 	"github.com/go-chi/cors"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/gorilla/websocket"
 	"github.com/riyadennis/sigist/graphql-service/graph"
 	"github.com/riyadennis/sigist/graphql-service/graph/generated"
 	"github.com/riyadennis/sigist/graphql-service/internal"
+	"github.com/riyadennis/sigist/graphql-service/internal/auth"
+	"github.com/riyadennis/sigist/graphql-service/internal/storage"
 	"github.com/uptrace/opentelemetry-go-extra/otelzap"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 
@@ -29,6 +39,9 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// topicCheckTimeout is how long the broker has to describe the topics at startup
+const topicCheckTimeout = 10 * time.Second
+
 var (
 	// these two can be overridden at build time
 	serviceVersion    = "DEV"
@@ -48,6 +61,18 @@ var (
 
 	// ErrFailedToCreateKafkaProducer means that the kafka producer couldn't be created
 	ErrFailedToCreateKafkaProducer = errors.New("failed to create kafka producer")
+
+	// ErrTopicsNotCompacted means that a kafka topic couldn't be described or doesn't have cleanup.policy=compact
+	ErrTopicsNotCompacted = errors.New("kafka topics are not compacted")
+
+	// ErrFailedToCreateStorage means that the attachment storage couldn't be created
+	ErrFailedToCreateStorage = errors.New("failed to create attachment storage")
+
+	// ErrFailedToLoadKeys means that the keys bearer tokens are verified with couldn't be loaded
+	ErrFailedToLoadKeys = errors.New("failed to load token keys")
+
+	// ErrFailedToLoadMaskingPolicy means that the masking policy file couldn't be loaded
+	ErrFailedToLoadMaskingPolicy = errors.New("failed to load masking policy")
 )
 
 // HTTPServer encapsulates two http server operations  that we need to execute in the service
@@ -59,12 +84,13 @@ type HTTPServer interface {
 
 // Service encapsulates the service operations
 type Service struct {
-	Conf    internal.Config
-	Server  HTTPServer
-	Logger  *otelzap.Logger
-	Sigint  chan os.Signal
-	errChan chan error
-	DB      *sql.DB
+	Conf        internal.Config
+	Server      HTTPServer
+	Logger      *otelzap.Logger
+	Sigint      chan os.Signal
+	errChan     chan error
+	DB          *sql.DB
+	Broadcaster *graph.Broadcaster
 }
 
 // NewService creates a new service
@@ -89,18 +115,59 @@ func NewService(conf internal.Config) (*Service, error) {
 		return nil, ErrFailedToCreateKafkaProducer
 	}
 
-	srv := handler.NewDefaultServer(
+	attachments, err := newAttachments(conf, logger)
+	if err != nil {
+		logger.Error("failed to initialise attachment storage", zap.Error(err))
+		return nil, ErrFailedToCreateStorage
+	}
+
+	verifier, err := newVerifier(conf, logger)
+	if err != nil {
+		logger.Error("failed to load token keys", zap.Error(err))
+		return nil, ErrFailedToLoadKeys
+	}
+
+	policies, err := graph.LoadMaskingPolicies(conf.MaskingPolicyFile)
+	if err != nil {
+		logger.Error("failed to load masking policy", zap.Error(err))
+		return nil, ErrFailedToLoadMaskingPolicy
+	}
+	masking := graph.NewMasking(logger, db, policies)
+
+	kafkaConfig := &graph.KafkaConfig{
+		Topic:        conf.KafkaTopic,
+		Producer:     producer,
+		TenantTopics: conf.KafkaTenantTopics,
+		KeyedSince:   conf.KafkaEventsKeyedSince,
+	}
+	broadcaster := graph.NewBroadcaster(logger, conf.SubscriptionBuffer)
+	srv := newGraphQLServer(
 		generated.NewExecutableSchema(
 			generated.Config{
 				Resolvers: graph.NewResolver(
 					logger,
 					db,
-					&graph.KafkaConfig{
-						Topic:    conf.KafkaTopic,
-						Producer: producer,
-					},
+					kafkaConfig,
+					broadcaster,
+					attachments,
+					masking,
+					newErasures(conf, logger),
+					conf.IdempotencyKeyTTL,
 				),
+				Directives: generated.DirectiveRoot{Constraint: graph.Constraint, HasRole: graph.HasRole},
+				Complexity: graph.Complexity(),
 			}),
+		logger,
+		&graph.QueryLimits{
+			Logger:        logger,
+			MaxDepth:      conf.MaxQueryDepth,
+			MaxComplexity: conf.MaxQueryComplexity,
+		},
+		persistedQueryCache(conf, logger, db),
+		uploadLimit(conf),
+		verifier,
+		masking,
+		graph.NewLoaderExtension(db),
 	)
 	driver, err := sqlite3.WithInstance(db, &sqlite3.Config{})
 	if err != nil {
@@ -125,16 +192,28 @@ func NewService(conf internal.Config) (*Service, error) {
 		logger.Error("failed to run migration", zap.Error(err))
 		return nil, ErrFailedTORunMigration
 	}
+
+	if err := checkCompaction(kafkaConfig, producer); err != nil {
+		logger.Error("kafka topics can't be used", zap.Error(err))
+		return nil, ErrTopicsNotCompacted
+	}
+	tenants := graph.Tenants{Allowed: conf.Tenants, Default: conf.DefaultTenant}
 	server := &http.Server{
 		Addr:    conf.Port,
-		Handler: newRouter(srv),
+		Handler: newRouter(srv, verifier, tenants, graph.DownloadHandler(logger, db, attachments)),
 	}
 
+	sigint := make(chan os.Signal, 1)
+	signal.Notify(sigint, os.Interrupt, syscall.SIGTERM)
+
 	return &Service{
-		Conf:   conf,
-		Logger: logger,
-		Server: server,
-		DB:     db,
+		Conf:        conf,
+		Logger:      logger,
+		Server:      server,
+		Sigint:      sigint,
+		errChan:     make(chan error, 1),
+		DB:          db,
+		Broadcaster: broadcaster,
 	}, nil
 }
 
@@ -194,23 +273,163 @@ func (s *Service) gracefulShutdown(ctx context.Context) {
 		cancel()
 	}()
 
+	// subscriptions run on hijacked connections that Server.Shutdown does not wait for
+	s.Broadcaster.Close()
 	_ = s.Server.Shutdown(cancelCtx)
 }
 
-func newRouter(srv *handler.Server) http.Handler {
+// newGraphQLServer sets up the same transports and extensions as handler.NewDefaultServer
+// plus error codes and query limits, the websocket upgrader accepts every origin to match the cors settings of the router.
+func newGraphQLServer(es graphql.ExecutableSchema, logger *otelzap.Logger, limits *graph.QueryLimits, apqCache graphql.Cache, maxUploadSize int64, verifier *auth.Verifier, masking *graph.Masking, loaders *graph.LoaderExtension) *handler.Server {
+	srv := handler.New(es)
+	srv.SetErrorPresenter(graph.ErrorPresenter(logger))
+	srv.SetRecoverFunc(graph.RecoverFunc(logger))
+
+	srv.AddTransport(transport.Websocket{
+		KeepAlivePingInterval: 10 * time.Second,
+		InitFunc:              graph.WebsocketInit(verifier),
+		Upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool {
+				return true
+			},
+		},
+	})
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.AddTransport(transport.MultipartForm{
+		MaxUploadSize: maxUploadSize,
+	})
+
+	srv.SetQueryCache(lru.New(1000))
+
+	srv.AroundFields(graph.RejectInvalidInput)
+	srv.Use(extension.Introspection{})
+	srv.Use(limits)
+	srv.Use(masking)
+	srv.Use(loaders)
+	srv.Use(extension.AutomaticPersistedQuery{
+		Cache: apqCache,
+	})
+
+	return srv
+}
+
+// checkCompaction refuses topics that would keep the events of erased feedback,
+// the admin client shares the connection of the producer.
+func checkCompaction(conf *graph.KafkaConfig, producer *kafka.Producer) error {
+	admin, err := kafka.NewAdminClientFromProducer(producer)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), topicCheckTimeout)
+	defer cancel()
+
+	return conf.CheckCompaction(ctx, admin)
+}
+
+// persistedQueryCache picks the automatic persisted query store set in the config
+func persistedQueryCache(conf internal.Config, logger *otelzap.Logger, db *sql.DB) graphql.Cache {
+	if conf.APQCache == "memory" {
+		return graph.NewMeteredCache("memory", lru.New(conf.APQCacheSize))
+	}
+
+	return graph.NewMeteredCache("sqlite", graph.NewSQLPersistedQueryCache(logger, db, conf.APQCacheSize))
+}
+
+// newVerifier loads the keys bearer tokens are verified with, it returns nil when none are configured
+func newVerifier(conf internal.Config, logger *otelzap.Logger) (*auth.Verifier, error) {
+	verifier, err := auth.NewVerifier(auth.Config{
+		JWKSFile: conf.JWKSFile,
+		KeyFile:  conf.JWTKeyFile,
+		Issuer:   conf.JWTIssuer,
+		Audience: conf.JWTAudience,
+	})
+	if errors.Is(err, auth.ErrNoKeys) {
+		logger.Warn("no token keys configured, only SaveUserFeedback can be used")
+		return nil, nil
+	}
+
+	return verifier, err
+}
+
+// newErasures sets up asking the other services about erasures, it returns nil when none are configured
+func newErasures(conf internal.Config, logger *otelzap.Logger) *graph.Erasures {
+	if len(conf.ErasureServices) == 0 {
+		return nil
+	}
+
+	return &graph.Erasures{
+		Logger:   logger,
+		Client:   &http.Client{Timeout: conf.ErasureStatusTimeout},
+		Services: conf.ErasureServices,
+	}
+}
+
+// newAttachments sets up the storage of uploaded files, it returns nil when no directory is configured
+func newAttachments(conf internal.Config, logger *otelzap.Logger) (*graph.Attachments, error) {
+	if conf.AttachmentsDir == "" {
+		return nil, nil
+	}
+
+	local, err := storage.NewLocal(conf.AttachmentsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	key := []byte(conf.AttachmentSigningKey)
+	if len(key) == 0 {
+		logger.Warn("no attachment signing key configured, download urls stop working when the service restarts")
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+
+	types := conf.AttachmentTypes
+	if len(types) == 0 {
+		types = graph.DefaultAttachmentTypes
+	}
+
+	return &graph.Attachments{
+		Storage:    local,
+		MaxSize:    conf.MaxAttachmentSize,
+		MaxFiles:   conf.MaxAttachments,
+		Types:      types,
+		SigningKey: key,
+		URLTTL:     conf.AttachmentURLTTL,
+	}, nil
+}
+
+// uploadLimit caps the size of a whole multipart request, it leaves room for the operation next to the files
+func uploadLimit(conf internal.Config) int64 {
+	return conf.MaxAttachmentSize*int64(conf.MaxAttachments) + 1<<20
+}
+
+func newRouter(srv *handler.Server, verifier *auth.Verifier, tenants graph.Tenants, downloads http.Handler) http.Handler {
 	chiRouter := chi.NewRouter()
 
 	chiRouter.Use(middleware.RequestID)
 	chiRouter.Use(middleware.Recoverer)
 	chiRouter.Use(cors.Handler(cors.Options{
 		AllowedOrigins: []string{"*"},
+		AllowedHeaders: []string{"Origin", "Accept", "Content-Type", "X-Requested-With", "Authorization", graph.IdempotencyKeyHeader, graph.TenantHeader},
 	}))
 
 	chiRouter.Handle("/", otelhttp.NewHandler(
 		playground.Handler("GraphQL playground", "/graphql"),
 		"graphql"))
 
-	chiRouter.Handle("/graphql", srv)
+	// signed download urls work without a tenant header, they are only handed out to the tenant's requests
+	chiRouter.Get("/attachments/{id}", downloads.ServeHTTP)
+	chiRouter.Group(func(r chi.Router) {
+		r.Use(graph.AuthMiddleware(verifier))
+		r.Use(graph.TenantMiddleware(tenants))
+		r.Use(graph.IdempotencyKeyMiddleware)
+		r.Handle("/graphql", srv)
+	})
 	return chiRouter
 }
 
@@ -0,0 +1,41 @@
+// Command backfill-subject-hashes hashes the emails saved before the subject hash was stored.
+// Run it once after the service has applied the erasures migration and before any erasure is
+// requested, erasures only find hashed emails. It is safe to run again or while the service is up
+// since only unhashed rows are touched.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	"github.com/alexflint/go-arg"
+	"github.com/riyadennis/sigist/rest-service/service"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type args struct {
+	DBFile    string `arg:"env:DB_FILE" default:"./emails.db"`
+	BatchSize int    `arg:"--batch-size,env:BACKFILL_BATCH_SIZE" default:"500" help:"rows hashed per transaction"`
+}
+
+func main() {
+	var a args
+	p := arg.MustParse(&a)
+	if a.BatchSize < 1 {
+		p.Fail("--batch-size must be at least 1")
+	}
+
+	db, err := sql.Open("sqlite3", a.DBFile)
+	if err != nil {
+		log.Fatalf("failed to open db: %s", err)
+	}
+	defer db.Close()
+
+	hashed, err := service.BackfillSubjectHashes(context.Background(), db, a.BatchSize)
+	if err != nil {
+		log.Fatalf("failed to backfill subject hashes after hashing %d emails: %s", hashed, err)
+	}
+	log.Printf("hashed %d emails", hashed)
+}
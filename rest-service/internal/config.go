@@ -17,6 +17,12 @@ type Config struct {
 	LogLevel       string `arg:"env:LOG_LEVEL" validate:"required,notblank"`
 	DBFile         string `arg:"env:DB_FILE" default:"./emails.db"`
 	MigrationsPath string `arg:"env:MIGRATIONS_PATH" default:"migrations"`
+	// Tenants is a comma separated list of the tenants requests can be made for, any tenant is accepted when it is empty
+	Tenants []string `arg:"env:TENANTS"`
+	// DefaultTenant is used for requests without an X-Tenant-ID header, they are rejected when it is empty
+	DefaultTenant string `arg:"env:DEFAULT_TENANT" default:"default"`
+	// ServiceToken is the bearer token the saved emails are read and erased with
+	ServiceToken string `arg:"env:SERVICE_TOKEN" validate:"required,notblank"`
 }
 
 // NewConfig return a new instance of Config
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS emails_tenant;
+
+ALTER TABLE emails DROP COLUMN tenant;
@@ -0,0 +1,3 @@
+ALTER TABLE emails ADD COLUMN tenant TEXT NOT NULL DEFAULT 'default';
+
+CREATE INDEX IF NOT EXISTS emails_tenant ON emails (tenant, created_at);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS emails_subject_hash;
+ALTER TABLE emails DROP COLUMN subject_hash;
+
+DROP TABLE IF EXISTS erasures;
@@ -0,0 +1,14 @@
+-- proof that the emails saved for a subject were erased, only the hash of the email is kept
+CREATE TABLE IF NOT EXISTS erasures (
+    id TEXT NOT NULL PRIMARY KEY,
+    tenant TEXT NOT NULL,
+    subject_hash TEXT NOT NULL,
+    erased_count INTEGER NOT NULL,
+    erased_at DATETIME NOT NULL
+);
+
+-- erasures name the subject by the hash of the trimmed, lower case email,
+-- emails saved earlier are hashed by cmd/backfill-subject-hashes
+ALTER TABLE emails ADD COLUMN subject_hash TEXT;
+
+CREATE INDEX IF NOT EXISTS emails_subject_hash ON emails (tenant, subject_hash);
@@ -52,7 +52,7 @@ func TestClientPact_Local(t *testing.T) {
 		// verify interaction on client side
 		err = pact.Verify(func() error {
 			_, err = service.SaveEmail(
-				db, &service.Request{
+				db, service.DefaultTenant, &service.Request{
 					Email:   email,
 					Sources: []string{sourceName},
 				},
@@ -63,7 +63,7 @@ func TestClientPact_Local(t *testing.T) {
 				t.Fatal(err)
 			}
 			// execute function
-			emails, err := service.FetchEmails(db, otelzap.New(zap.NewExample()))
+			emails, err := service.FetchEmails(db, service.DefaultTenant, otelzap.New(zap.NewExample()))
 			if err != nil {
 				t.Fatal(err)
 			}
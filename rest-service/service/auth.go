@@ -0,0 +1,27 @@
+package service
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrInvalidServiceToken means that a request that only other services may make didn't carry the shared token
+var ErrInvalidServiceToken = errors.New("missing or invalid service token")
+
+// ServiceTokenMiddleware only lets through requests with the bearer token shared with the services
+// that read the saved emails or erase them, like benthos.
+func ServiceTokenMiddleware(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization := r.Header.Get("Authorization")
+			bearer := strings.TrimPrefix(authorization, "Bearer ")
+			if bearer == authorization || token == "" || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+				_ = HTTPResponse(w, ErrInvalidServiceToken, http.StatusUnauthorized, "unauthorized")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
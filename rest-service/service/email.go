@@ -13,8 +13,8 @@ import (
 )
 
 var (
-	querySaveEmail    = `INSERT INTO emails (id, sourceName, email, created_at) VALUES (?, ?, ?, ?)`
-	queryGetAllEmails = `SELECT id, sourceName, email, created_at FROM emails`
+	querySaveEmail    = `INSERT INTO emails (id, sourceName, email, created_at, tenant, subject_hash) VALUES (?, ?, ?, ?, ?, ?)`
+	queryGetAllEmails = `SELECT id, sourceName, email, created_at FROM emails WHERE tenant = ?`
 )
 
 type Email struct {
@@ -50,7 +50,7 @@ func (e *Email) SaveEmail(w http.ResponseWriter, r *http.Request) {
 	}
 	emailID := uuid.New().String()
 	createdAt := time.Now().Format(time.RFC3339)
-	res, err := SaveEmail(e.db, re, emailID, createdAt)
+	res, err := SaveEmail(e.db, tenantFrom(r.Context()), re, emailID, createdAt)
 	if err != nil {
 		e.logger.Error("failed to execute statement", zap.Error(err))
 		_ = HTTPResponse(w, err, http.StatusInternalServerError, "failed to save email")
@@ -74,7 +74,7 @@ func (e *Email) SaveEmail(w http.ResponseWriter, r *http.Request) {
 }
 
 func (e *Email) GetAllEmails(w http.ResponseWriter, r *http.Request) {
-	emails, err := FetchEmails(e.db, e.logger)
+	emails, err := FetchEmails(e.db, tenantFrom(r.Context()), e.logger)
 	if err != nil {
 		e.logger.Error("failed to fetch emails", zap.Error(err))
 		_ = HTTPResponse(w, err, http.StatusInternalServerError, "failed to fetch emails")
@@ -92,8 +92,9 @@ func (e *Email) GetAllEmails(w http.ResponseWriter, r *http.Request) {
 	w.Write(data)
 }
 
-func FetchEmails(db *sql.DB, logger *otelzap.Logger) ([]*EmailResponse, error) {
-	rows, err := db.Query(queryGetAllEmails)
+// FetchEmails returns the emails saved for the tenant
+func FetchEmails(db *sql.DB, tenant string, logger *otelzap.Logger) ([]*EmailResponse, error) {
+	rows, err := db.Query(queryGetAllEmails, tenant)
 	if err != nil {
 		return nil, err
 	}
@@ -118,7 +119,8 @@ func FetchEmails(db *sql.DB, logger *otelzap.Logger) ([]*EmailResponse, error) {
 	return emails, nil
 }
 
-func SaveEmail(db *sql.DB, req *Request, uuid, createdAt string) (sql.Result, error) {
+// SaveEmail stores the email for the tenant together with its subject hash, erasures find it by the hash
+func SaveEmail(db *sql.DB, tenant string, req *Request, uuid, createdAt string) (sql.Result, error) {
 	stmt, err := db.Prepare(querySaveEmail)
 	if err != nil {
 		return nil, err
@@ -133,5 +135,7 @@ func SaveEmail(db *sql.DB, req *Request, uuid, createdAt string) (sql.Result, er
 		strings.Join(req.Sources, ","),
 		req.Email,
 		createdAt,
+		tenant,
+		subjectHash(req.Email),
 	)
 }
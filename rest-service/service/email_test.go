@@ -0,0 +1,35 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGetAllEmailsNeedsServiceToken(t *testing.T) {
+	router, _ := testRouter(t)
+
+	// saving stays open to the consumer of the feedback events
+	rec := serve(t, router, http.MethodPost, "/email", DefaultTenant, "", `{"email": "john@test.com"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d %s", rec.Code, rec.Body)
+	}
+
+	for _, token := range []string{"", "guess"} {
+		rec = serve(t, router, http.MethodGet, "/emails", DefaultTenant, token, "")
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401 with token %q, got %d", token, rec.Code)
+		}
+		if strings.Contains(rec.Body.String(), "john@test.com") {
+			t.Errorf("expected no email with token %q, got %s", token, rec.Body)
+		}
+	}
+
+	rec = serve(t, router, http.MethodGet, "/emails", DefaultTenant, testServiceToken, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %s", rec.Code, rec.Body)
+	}
+	if !strings.Contains(rec.Body.String(), "john@test.com") {
+		t.Errorf("expected the saved email, got %s", rec.Body)
+	}
+}
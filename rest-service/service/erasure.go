@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/uptrace/opentelemetry-go-extra/otelzap"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrInvalidErasure means that the erasure has no id or the subject hash is not a hex SHA-256
+	ErrInvalidErasure = errors.New("an erasure needs an id and the hex SHA-256 of the trimmed, lower case email")
+
+	// ErrErasureNotFound means that the subject of the erasure hasn't been erased yet
+	ErrErasureNotFound = errors.New("erasure not found")
+
+	subjectHashPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+	queryEraseEmails = `DELETE FROM emails WHERE tenant = ? AND subject_hash = ?`
+	querySaveErasure = `INSERT INTO erasures (id, tenant, subject_hash, erased_count, erased_at) VALUES (?, ?, ?, ?, ?)`
+	queryGetErasure  = `SELECT id, subject_hash, erased_count, erased_at FROM erasures WHERE tenant = ? AND id = ?`
+
+	queryUnhashedEmails = `SELECT id, email FROM emails WHERE subject_hash IS NULL AND email IS NOT NULL AND id > ? ORDER BY id LIMIT ?`
+	queryHashEmail      = `UPDATE emails SET subject_hash = ? WHERE id = ? AND subject_hash IS NULL`
+)
+
+// Erasure deletes the emails of a subject when graphql-service publishes a subject.erased event
+type Erasure struct {
+	logger *otelzap.Logger
+	db     *sql.DB
+}
+
+// ErasureRequest is the payload of subject.erased events, the email is only known by its hash
+type ErasureRequest struct {
+	ID          string `json:"id"`
+	SubjectHash string `json:"subjectHash"`
+}
+
+// ErasureResponse is the proof that the emails of a subject were erased
+type ErasureResponse struct {
+	ID          string `json:"id"`
+	SubjectHash string `json:"subject_hash"`
+	ErasedCount int    `json:"erased_count"`
+	ErasedAt    string `json:"erased_at"`
+}
+
+func NewErasureHandler(db *sql.DB, logger *otelzap.Logger) *Erasure {
+	return &Erasure{
+		logger: logger,
+		db:     db,
+	}
+}
+
+// EraseSubject answers 201 when the emails were erased and 200 when the erasure was done before,
+// events can be delivered more than once.
+func (e *Erasure) EraseSubject(w http.ResponseWriter, r *http.Request) {
+	req := &ErasureRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		_ = HTTPResponse(w, err, http.StatusBadRequest, "failed to decode request")
+		return
+	}
+	if req.ID == "" || !subjectHashPattern.MatchString(req.SubjectHash) {
+		_ = HTTPResponse(w, ErrInvalidErasure, http.StatusBadRequest, "invalid erasure")
+		return
+	}
+
+	erasure, created, err := EraseSubject(e.db, tenantFrom(r.Context()), req, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		e.logger.Error("failed to erase subject", zap.String("erasure", req.ID), zap.Error(err))
+		_ = HTTPResponse(w, err, http.StatusInternalServerError, "failed to erase subject")
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, erasure)
+}
+
+// GetErasure answers 404 until the subject of the erasure has been erased
+func (e *Erasure) GetErasure(w http.ResponseWriter, r *http.Request) {
+	erasure, err := GetErasure(e.db, tenantFrom(r.Context()), chi.URLParam(r, "id"))
+	if err == sql.ErrNoRows {
+		_ = HTTPResponse(w, ErrErasureNotFound, http.StatusNotFound, "erasure not found")
+		return
+	}
+	if err != nil {
+		e.logger.Error("failed to fetch erasure", zap.Error(err))
+		_ = HTTPResponse(w, err, http.StatusInternalServerError, "failed to fetch erasure")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, erasure)
+}
+
+// EraseSubject deletes the tenant's emails saved with the subject hash and records the erasure,
+// it returns the erasure recorded before and false when the request was already handled.
+func EraseSubject(db *sql.DB, tenant string, req *ErasureRequest, erasedAt string) (*ErasureResponse, bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	erasure, err := scanErasure(tx.QueryRow(queryGetErasure, tenant, req.ID))
+	if err == nil {
+		return erasure, false, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, err
+	}
+
+	res, err := tx.Exec(queryEraseEmails, tenant, req.SubjectHash)
+	if err != nil {
+		return nil, false, err
+	}
+	erased, err := res.RowsAffected()
+	if err != nil {
+		return nil, false, err
+	}
+
+	_, err = tx.Exec(querySaveErasure, req.ID, tenant, req.SubjectHash, erased, erasedAt)
+	if err != nil {
+		return nil, false, err
+	}
+
+	erasure = &ErasureResponse{
+		ID:          req.ID,
+		SubjectHash: req.SubjectHash,
+		ErasedCount: int(erased),
+		ErasedAt:    erasedAt,
+	}
+
+	return erasure, true, tx.Commit()
+}
+
+// GetErasure returns sql.ErrNoRows when the tenant has no erasure with the given id
+func GetErasure(db *sql.DB, tenant, id string) (*ErasureResponse, error) {
+	return scanErasure(db.QueryRow(queryGetErasure, tenant, id))
+}
+
+func scanErasure(row *sql.Row) (*ErasureResponse, error) {
+	erasure := &ErasureResponse{}
+	err := row.Scan(&erasure.ID, &erasure.SubjectHash, &erasure.ErasedCount, &erasure.ErasedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return erasure, nil
+}
+
+// BackfillSubjectHashes hashes the emails that were saved before the subject hash was stored,
+// batchSize rows per transaction, and returns how many rows it hashed. Erasures don't find unhashed emails.
+func BackfillSubjectHashes(ctx context.Context, db *sql.DB, batchSize int) (int, error) {
+	hashed, lastID := 0, ""
+	for {
+		n, last, err := backfillSubjectHashBatch(ctx, db, lastID, batchSize)
+		hashed += n
+		if err != nil || last == "" {
+			return hashed, err
+		}
+		lastID = last
+	}
+}
+
+// backfillSubjectHashBatch hashes the next batch of unhashed emails after lastID,
+// it returns an empty id once there is nothing left to hash.
+func backfillSubjectHashBatch(ctx context.Context, db *sql.DB, lastID string, batchSize int) (int, string, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, queryUnhashedEmails, lastID, batchSize)
+	if err != nil {
+		return 0, "", err
+	}
+
+	type unhashed struct {
+		id    string
+		email string
+	}
+	var batch []unhashed
+	for rows.Next() {
+		var u unhashed
+		if err := rows.Scan(&u.id, &u.email); err != nil {
+			rows.Close()
+			return 0, "", err
+		}
+		batch = append(batch, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, "", err
+	}
+	if len(batch) == 0 {
+		return 0, "", nil
+	}
+
+	hashed := 0
+	for _, u := range batch {
+		res, err := tx.ExecContext(ctx, queryHashEmail, subjectHash(u.email), u.id)
+		if err != nil {
+			return 0, "", err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, "", err
+		}
+		hashed += int(n)
+	}
+
+	return hashed, batch[len(batch)-1].id, tx.Commit()
+}
+
+// subjectHash is computed on the trimmed, lower case email like graphql-service does
+func subjectHash(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(sum[:])
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		_ = HTTPResponse(w, err, http.StatusInternalServerError, "failed to encode response")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
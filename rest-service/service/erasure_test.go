@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/uptrace/opentelemetry-go-extra/otelzap"
+	"go.uber.org/zap"
+)
+
+const testServiceToken = "test-service-token"
+
+// testRouter returns the router of a service with every migration applied to an empty database
+func testRouter(t *testing.T) (http.Handler, *sql.DB) {
+	t.Helper()
+	db, err := SetUpDB(filepath.Join(t.TempDir(), "emails.db"), "../migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return newRouter(db, otelzap.New(zap.NewNop()), Tenants{Default: DefaultTenant}, testServiceToken), db
+}
+
+// serve sends the request for the tenant with the service token unless token is empty
+func serve(t *testing.T, router http.Handler, method, path, tenant, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(TenantHeader, tenant)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func tenantEmails(t *testing.T, db *sql.DB, tenant string) []string {
+	t.Helper()
+	emails, err := FetchEmails(db, tenant, otelzap.New(zap.NewNop()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	addresses := make([]string, 0, len(emails))
+	for _, email := range emails {
+		addresses = append(addresses, email.Email)
+	}
+
+	return addresses
+}
+
+func erasureBody(id, email string) string {
+	return `{"id": "` + id + `", "subjectHash": "` + subjectHash(email) + `"}`
+}
+
+func TestEraseSubject(t *testing.T) {
+	router, db := testRouter(t)
+	for _, saved := range []struct{ tenant, email string }{
+		{"team-a", "john@test.com"},
+		{"team-a", " John@Test.com"},
+		{"team-a", "jane@test.com"},
+		{"team-b", "john@test.com"},
+	} {
+		rec := serve(t, router, http.MethodPost, "/email", saved.tenant, "", `{"email": "`+saved.email+`"}`)
+		if rec.Code != http.StatusCreated && rec.Code != http.StatusOK {
+			t.Fatalf("saving %s: %d %s", saved.email, rec.Code, rec.Body)
+		}
+	}
+
+	rec := serve(t, router, http.MethodGet, "/erasures/erasure-1", "team-a", "", "")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 before the erasure, got %d", rec.Code)
+	}
+
+	rec = serve(t, router, http.MethodPost, "/erasures", "team-a", testServiceToken, erasureBody("erasure-1", "john@test.com"))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d %s", rec.Code, rec.Body)
+	}
+	var first ErasureResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &first); err != nil {
+		t.Fatal(err)
+	}
+	if first.ErasedCount != 2 {
+		t.Errorf("expected 2 erased emails, got %d", first.ErasedCount)
+	}
+	if emails := tenantEmails(t, db, "team-a"); len(emails) != 1 || emails[0] != "jane@test.com" {
+		t.Errorf("expected only jane@test.com left for team-a, got %v", emails)
+	}
+	if emails := tenantEmails(t, db, "team-b"); len(emails) != 1 || emails[0] != "john@test.com" {
+		t.Errorf("expected the email of team-b to be kept, got %v", emails)
+	}
+
+	// a redelivered event answers with the first erasure and doesn't erase again
+	serve(t, router, http.MethodPost, "/email", "team-a", "", `{"email": "john@test.com"}`)
+	rec = serve(t, router, http.MethodPost, "/erasures", "team-a", testServiceToken, erasureBody("erasure-1", "john@test.com"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for a repeated erasure, got %d %s", rec.Code, rec.Body)
+	}
+	var repeated ErasureResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &repeated); err != nil {
+		t.Fatal(err)
+	}
+	if repeated != first {
+		t.Errorf("expected the first erasure %+v, got %+v", first, repeated)
+	}
+
+	rec = serve(t, router, http.MethodGet, "/erasures/erasure-1", "team-a", "", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 after the erasure, got %d", rec.Code)
+	}
+	// erasures are kept per tenant
+	rec = serve(t, router, http.MethodGet, "/erasures/erasure-1", "team-b", "", "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for another tenant, got %d", rec.Code)
+	}
+}
+
+func TestEraseSubjectRejectsRequests(t *testing.T) {
+	router, _ := testRouter(t)
+
+	scenarios := []struct {
+		name           string
+		token          string
+		body           string
+		expectedStatus int
+	}{
+		{
+			name:           "no service token",
+			body:           erasureBody("erasure-1", "john@test.com"),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "wrong service token",
+			token:          "guess",
+			body:           erasureBody("erasure-1", "john@test.com"),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "missing id",
+			token:          testServiceToken,
+			body:           erasureBody("", "john@test.com"),
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "hash that is not a hex sha-256",
+			token:          testServiceToken,
+			body:           `{"id": "erasure-1", "subjectHash": "john@test.com"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "upper case hash",
+			token:          testServiceToken,
+			body:           `{"id": "erasure-1", "subjectHash": "` + strings.ToUpper(subjectHash("john@test.com")) + `"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "not json",
+			token:          testServiceToken,
+			body:           `erasure-1`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			rec := serve(t, router, http.MethodPost, "/erasures", DefaultTenant, scenario.token, scenario.body)
+			if rec.Code != scenario.expectedStatus {
+				t.Errorf("expected %d, got %d %s", scenario.expectedStatus, rec.Code, rec.Body)
+			}
+		})
+	}
+
+	// nothing was recorded by the rejected requests
+	rec := serve(t, router, http.MethodGet, "/erasures/erasure-1", DefaultTenant, "", "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestBackfillSubjectHashes(t *testing.T) {
+	router, db := testRouter(t)
+	// saved before the subject hash was stored
+	for _, saved := range []struct{ id, email string }{{"1", "John@Test.com "}, {"2", "jane@test.com"}} {
+		_, err := db.Exec(`INSERT INTO emails (id, sourceName, email, created_at, tenant) VALUES (?, ?, ?, ?, ?)`,
+			saved.id, "", saved.email, "2023-05-01T10:00:00Z", DefaultTenant)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	serve(t, router, http.MethodPost, "/email", DefaultTenant, "", `{"email": "john@test.com"}`)
+
+	hashed, err := BackfillSubjectHashes(context.Background(), db, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hashed != 2 {
+		t.Errorf("expected 2 hashed emails, got %d", hashed)
+	}
+
+	rec := serve(t, router, http.MethodPost, "/erasures", DefaultTenant, testServiceToken, erasureBody("erasure-1", "john@test.com"))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d %s", rec.Code, rec.Body)
+	}
+	if emails := tenantEmails(t, db, DefaultTenant); len(emails) != 1 || emails[0] != "jane@test.com" {
+		t.Errorf("expected only jane@test.com left, got %v", emails)
+	}
+}
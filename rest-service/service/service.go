@@ -76,7 +76,7 @@ func NewService(conf internal.Config) (*Service, error) {
 	}
 	server := &http.Server{
 		Addr:    conf.Port,
-		Handler: newRouter(db, logger),
+		Handler: newRouter(db, logger, Tenants{Allowed: conf.Tenants, Default: conf.DefaultTenant}, conf.ServiceToken),
 	}
 
 	return &Service{
@@ -171,17 +171,26 @@ func (s *Service) gracefulShutdown(ctx context.Context) {
 	_ = s.Server.Shutdown(cancelCtx)
 }
 
-func newRouter(db *sql.DB, logger *otelzap.Logger) http.Handler {
+func newRouter(db *sql.DB, logger *otelzap.Logger, tenants Tenants, serviceToken string) http.Handler {
 	chiRouter := chi.NewRouter()
 
 	chiRouter.Use(middleware.RequestID)
 	chiRouter.Use(middleware.Recoverer)
 	chiRouter.Use(cors.Handler(cors.Options{
 		AllowedOrigins: []string{"*"},
+		AllowedHeaders: []string{"Origin", "Accept", "Content-Type", "X-Requested-With", "Authorization", TenantHeader},
 	}))
+	chiRouter.Use(TenantMiddleware(tenants))
 	eh := NewEmailHandler(db, logger)
 	chiRouter.MethodFunc(http.MethodPost, "/email", eh.SaveEmail)
-	chiRouter.MethodFunc(http.MethodGet, "/emails", eh.GetAllEmails)
+	erh := NewErasureHandler(db, logger)
+	chiRouter.MethodFunc(http.MethodGet, "/erasures/{id}", erh.GetErasure)
+	// the saved emails are personal data, only services with the token read or erase them
+	chiRouter.Group(func(r chi.Router) {
+		r.Use(ServiceTokenMiddleware(serviceToken))
+		r.MethodFunc(http.MethodGet, "/emails", eh.GetAllEmails)
+		r.MethodFunc(http.MethodPost, "/erasures", erh.EraseSubject)
+	})
 	return chiRouter
 }
 
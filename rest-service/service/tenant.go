@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+)
+
+const (
+	// TenantHeader names the tenant a request is made for
+	TenantHeader = "X-Tenant-ID"
+	// DefaultTenant owns the emails saved before there were tenants
+	DefaultTenant = "default"
+)
+
+var (
+	// ErrInvalidTenant means that a tenant id is empty, too long or uses characters other than letters, digits, - and _
+	ErrInvalidTenant = errors.New("tenant ids can only use letters, digits, - and _ and be at most 64 characters long")
+
+	// ErrUnknownTenant means that the tenant is not one of the configured tenants
+	ErrUnknownTenant = errors.New("unknown tenant")
+)
+
+var tenantPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+type tenantKey struct{}
+
+// Tenants decides which tenants requests can be made for
+type Tenants struct {
+	// Allowed are the known tenants, any valid tenant id is accepted when it is empty
+	Allowed []string
+	// Default is the tenant of requests that don't name one, they are rejected when it is empty
+	Default string
+}
+
+// resolve returns the tenant named by the request or else the default tenant
+func (t Tenants) resolve(r *http.Request) (string, error) {
+	tenant := r.Header.Get(TenantHeader)
+	if tenant == "" {
+		tenant = t.Default
+	}
+	if !tenantPattern.MatchString(tenant) {
+		return "", ErrInvalidTenant
+	}
+	if len(t.Allowed) == 0 {
+		return tenant, nil
+	}
+	for _, allowed := range t.Allowed {
+		if allowed == tenant {
+			return tenant, nil
+		}
+	}
+
+	return "", ErrUnknownTenant
+}
+
+// TenantMiddleware makes the tenant of the request available to the handlers,
+// requests for a tenant that can't be served are rejected.
+func TenantMiddleware(tenants Tenants) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tenant, err := tenants.resolve(r)
+			if errors.Is(err, ErrUnknownTenant) {
+				_ = HTTPResponse(w, err, http.StatusForbidden, "tenant not allowed")
+				return
+			}
+			if err != nil {
+				_ = HTTPResponse(w, err, http.StatusBadRequest, "invalid tenant")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tenantKey{}, tenant)))
+		})
+	}
+}
+
+// tenantFrom returns the tenant of the request, requests that didn't go through the middleware
+// belong to the default tenant.
+func tenantFrom(ctx context.Context) string {
+	if tenant, ok := ctx.Value(tenantKey{}).(string); ok {
+		return tenant
+	}
+
+	return DefaultTenant
+}